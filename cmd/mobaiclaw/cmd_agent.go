@@ -15,6 +15,7 @@ import (
 	"github.com/chzyer/readline"
 	"github.com/zhaopengme/mobaiclaw/pkg/agent"
 	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 )
@@ -23,6 +24,7 @@ func agentCmd() {
 	message := ""
 	sessionKey := "cli:default"
 	modelOverride := ""
+	var cassette *config.CassetteConfig
 
 	args := os.Args[2:]
 	for i := 0; i < len(args); i++ {
@@ -45,6 +47,11 @@ func agentCmd() {
 				modelOverride = args[i+1]
 				i++
 			}
+		case "--record", "--replay":
+			if i+1 < len(args) {
+				cassette = &config.CassetteConfig{Mode: strings.TrimPrefix(args[i], "--"), Path: args[i+1]}
+				i++
+			}
 		}
 	}
 
@@ -57,6 +64,13 @@ func agentCmd() {
 	if modelOverride != "" {
 		cfg.Agents.Defaults.Model = modelOverride
 	}
+	if cassette != nil {
+		cfg.Cassette = cassette
+	}
+	if err := useCassette(cfg); err != nil {
+		fmt.Printf("Error opening cassette: %v\n", err)
+		os.Exit(1)
+	}
 
 	provider, modelID, err := providers.CreateProvider(cfg)
 	if err != nil {
@@ -94,6 +108,22 @@ func agentCmd() {
 	}
 }
 
+// useCassette makes all providers record to or replay from the cassette
+// configured in cfg, if any.
+func useCassette(cfg *config.Config) error {
+	if cfg.Cassette == nil || cfg.Cassette.Path == "" {
+		return nil
+	}
+	cassette, err := providers.OpenCassette(cfg.Cassette.Mode, cfg.Cassette.Path)
+	if err != nil {
+		return err
+	}
+	providers.UseCassette(cassette)
+	logger.InfoCF("agent", "Using LLM cassette",
+		map[string]interface{}{"mode": cfg.Cassette.Mode, "path": cfg.Cassette.Path})
+	return nil
+}
+
 func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	prompt := fmt.Sprintf("%s You: ", logo)
 
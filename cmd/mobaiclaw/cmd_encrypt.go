@@ -0,0 +1,255 @@
+// MobaiClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/agent"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
+	"github.com/zhaopengme/mobaiclaw/pkg/routing"
+	"github.com/zhaopengme/mobaiclaw/pkg/session"
+)
+
+// defaultKeyFile is where --generate-key writes the key when no key file is
+// configured.
+const defaultKeyFile = "~/.mobaiclaw/encryption.key"
+
+func encryptWorkspaceHelp() {
+	fmt.Println("\nUsage: mobaiclaw encrypt-workspace [options]")
+	fmt.Println()
+	fmt.Println("Encrypts existing sessions, memory and cron jobs of every agent workspace.")
+	fmt.Println("Stop the gateway first. The key comes from " + crypt.KeyEnv + " or encryption.key_file.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --generate-key      Create a new key file (encryption.key_file, else " + defaultKeyFile + ")")
+	fmt.Println("  --decrypt           Turn encrypted files back into plaintext")
+	fmt.Println("  --dry-run           List the files that would be rewritten")
+}
+
+func encryptWorkspaceCmd() {
+	generate, decrypt, dryRun := false, false, false
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--generate-key":
+			generate = true
+		case "--decrypt":
+			decrypt = true
+		case "--dry-run":
+			dryRun = true
+		case "-h", "--help", "help":
+			encryptWorkspaceHelp()
+			return
+		default:
+			fmt.Printf("Unknown option: %s\n", arg)
+			encryptWorkspaceHelp()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if generate {
+		if decrypt {
+			fmt.Println("Error: --generate-key cannot be combined with --decrypt")
+			os.Exit(1)
+		}
+		if crypt.Enabled() {
+			fmt.Println("Error: an encryption key is already configured")
+			os.Exit(1)
+		}
+		generateEncryptionKey(cfg, dryRun)
+	}
+	if !crypt.Enabled() && !dryRun {
+		fmt.Printf("Error: no encryption key. Set %s, set encryption.key_file in the config, or run with --generate-key.\n", crypt.KeyEnv)
+		os.Exit(1)
+	}
+
+	verb := "Encrypted"
+	if decrypt {
+		verb = "Decrypted"
+	}
+	total, failed := 0, false
+	for _, workspace := range agentWorkspaces(cfg) {
+		for _, path := range workspaceDataFiles(workspace) {
+			changed, err := convertFile(path, decrypt, dryRun)
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", path, err)
+				failed = true
+				continue
+			}
+			if changed {
+				total++
+				if dryRun {
+					fmt.Printf("  %s\n", path)
+				}
+			}
+		}
+	}
+
+	switch {
+	case dryRun:
+		fmt.Printf("\n%d file(s) would be rewritten\n", total)
+	default:
+		fmt.Printf("✓ %s %d file(s)\n", verb, total)
+		if decrypt {
+			fmt.Printf("  Remove encryption.key_file and %s so new files are written in plaintext.\n", crypt.KeyEnv)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// generateEncryptionKey creates a key file and enables encryption with it,
+// recording the file in the config when none was configured.
+func generateEncryptionKey(cfg *config.Config, dryRun bool) {
+	configured := cfg.Encryption.KeyFile != ""
+	if !configured {
+		cfg.Encryption.KeyFile = defaultKeyFile
+	}
+	path := cfg.EncryptionKeyFile()
+	if dryRun {
+		fmt.Printf("Would create encryption key %s\n", path)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		fmt.Printf("Error creating key directory: %v\n", err)
+		os.Exit(1)
+	}
+	key, err := crypt.GenerateKeyFile(path)
+	if err != nil {
+		fmt.Printf("Error creating key file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := crypt.Enable(key); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Created encryption key %s\n", path)
+	fmt.Println("  Back it up: encrypted files cannot be read without it.")
+
+	if !configured {
+		if err := config.SaveConfig(getConfigPath(), cfg); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Set encryption.key_file in %s\n", getConfigPath())
+	}
+}
+
+// agentWorkspaces returns the workspace of every configured agent, each
+// once.
+func agentWorkspaces(cfg *config.Config) []string {
+	ids := []string{routing.DefaultAgentID}
+	for _, ac := range cfg.Agents.List {
+		ids = append(ids, ac.ID)
+	}
+	seen := make(map[string]bool)
+	var workspaces []string
+	for _, id := range ids {
+		workspace := filepath.Dir(agent.SessionsDir(cfg, id))
+		if !seen[workspace] {
+			seen[workspace] = true
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	return workspaces
+}
+
+// workspaceDataFiles lists the files of a workspace that are encrypted at
+// rest.
+func workspaceDataFiles(workspace string) []string {
+	sessions := filepath.Join(workspace, "sessions")
+	patterns := []string{
+		filepath.Join(sessions, "*.json"),
+		filepath.Join(sessions, "*.jsonl"),
+		filepath.Join(sessions, session.ArchiveDir, "*.json"),
+		filepath.Join(sessions, session.IndexDir, "*.jsonl"),
+		filepath.Join(workspace, "memory", "profile.json"),
+		filepath.Join(workspace, "memory", "*", "*.md"),
+		filepath.Join(workspace, "cron", "jobs.json"),
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// convertFile encrypts or decrypts a file in place and reports whether it
+// needed to change. Logs are converted line by line so they stay
+// appendable.
+func convertFile(path string, decrypt, dryRun bool) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	// A file, or a line of a log, needs converting when its state differs
+	// from the one asked for.
+	pending := func(chunk []byte) bool { return len(chunk) > 0 && crypt.IsSealed(chunk) == decrypt }
+
+	isLog := strings.HasSuffix(path, ".jsonl")
+	var lines [][]byte
+	changed := false
+	if isLog {
+		lines = bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+		for _, line := range lines {
+			changed = changed || pending(line)
+		}
+	} else {
+		changed = pending(data)
+	}
+	if !changed || dryRun {
+		return changed, nil
+	}
+
+	var out []byte
+	if isLog {
+		var buf bytes.Buffer
+		for _, line := range lines {
+			if pending(line) {
+				plain, err := crypt.OpenLine(line)
+				if err != nil {
+					return false, err
+				}
+				if line = plain; !decrypt {
+					line = crypt.SealLine(plain)
+				}
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		out = buf.Bytes()
+	} else {
+		plain, err := crypt.Open(data)
+		if err != nil {
+			return false, err
+		}
+		if out = plain; !decrypt {
+			out = crypt.Seal(plain)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0600); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, nil
+}
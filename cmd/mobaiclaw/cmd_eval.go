@@ -0,0 +1,114 @@
+// MobaiClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/agent"
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/eval"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+func evalCmd() {
+	var paths []string
+	modelOverride := ""
+	keepSessions := false
+	var cassette *config.CassetteConfig
+
+	logger.SetLevel(logger.WARN)
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--debug", "-d":
+			logger.SetLevel(logger.DEBUG)
+		case "--model", "-model":
+			if i+1 < len(args) {
+				modelOverride = args[i+1]
+				i++
+			}
+		case "--record", "--replay":
+			if i+1 < len(args) {
+				cassette = &config.CassetteConfig{Mode: strings.TrimPrefix(args[i], "--"), Path: args[i+1]}
+				i++
+			}
+		case "--keep-sessions":
+			keepSessions = true
+		case "-h", "--help":
+			evalHelp()
+			return
+		default:
+			paths = append(paths, args[i])
+		}
+	}
+	if len(paths) == 0 {
+		evalHelp()
+		os.Exit(1)
+	}
+
+	scenarios, err := eval.LoadScenarios(paths...)
+	if err != nil {
+		fmt.Printf("Error loading scenarios: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if modelOverride != "" {
+		cfg.Agents.Defaults.Model = modelOverride
+	}
+	if cassette != nil {
+		cfg.Cassette = cassette
+	}
+	if err := useCassette(cfg); err != nil {
+		fmt.Printf("Error opening cassette: %v\n", err)
+		os.Exit(1)
+	}
+
+	provider, modelID, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+	if modelID != "" {
+		cfg.Agents.Defaults.Model = modelID
+	}
+
+	agentLoop := agent.NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	runner := eval.NewRunner(agentLoop)
+	runner.KeepSessions = keepSessions
+
+	fmt.Printf("%s Running %d scenarios\n\n", logo, len(scenarios))
+	ctx := context.Background()
+	results := make([]eval.Result, 0, len(scenarios))
+	for _, s := range scenarios {
+		results = append(results, runner.Run(ctx, s))
+	}
+
+	if failed := eval.WriteReport(os.Stdout, results); failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func evalHelp() {
+	fmt.Println("Usage: mobaiclaw eval <file|dir>... [options]")
+	fmt.Println()
+	fmt.Println("Runs YAML or JSON conversation scenarios and reports which pass.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --model <name>     Model to evaluate")
+	fmt.Println("  --replay <file>    Answer from a recorded cassette instead of the provider")
+	fmt.Println("  --record <file>    Record the provider's answers to a cassette")
+	fmt.Println("  --keep-sessions    Keep the eval sessions for inspection")
+	fmt.Println("  -d, --debug        Show debug logs")
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/zhaopengme/mobaiclaw/pkg/gateway"
 	"github.com/zhaopengme/mobaiclaw/pkg/health"
 	"github.com/zhaopengme/mobaiclaw/pkg/heartbeat"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 	"github.com/zhaopengme/mobaiclaw/pkg/state"
@@ -46,6 +47,11 @@ func gatewayCmd() {
 		os.Exit(1)
 	}
 
+	if err := useCassette(cfg); err != nil {
+		fmt.Printf("Error opening cassette: %v\n", err)
+		os.Exit(1)
+	}
+
 	provider, modelID, err := providers.CreateProvider(cfg)
 	if err != nil {
 		fmt.Printf("Error creating provider: %v\n", err)
@@ -111,6 +117,7 @@ func gatewayCmd() {
 		fmt.Printf("Error creating channel manager: %v\n", err)
 		os.Exit(1)
 	}
+	agentLoop.SetStreamingChannels(channelManager.StreamingChannels())
 
 	var transcriber *voice.GroqTranscriber
 	if cfg.Providers.Groq.APIKey != "" {
@@ -168,12 +175,18 @@ func gatewayCmd() {
 		MonitorUSB: cfg.Devices.MonitorUSB,
 	}, stateManager)
 	deviceService.SetBus(mainBus)
+	deviceService.SetLocaleFunc(func(channel, chatID string) string {
+		return agentLoop.LocaleFor(bus.InboundMessage{Channel: channel, ChatID: chatID})
+	})
 	if err := deviceService.Start(ctx); err != nil {
 		fmt.Printf("Error starting device service: %v\n", err)
 	} else if cfg.Devices.Enabled {
 		fmt.Println("✓ Device event service started")
 	}
 
+	channelManager.SetLocaleFunc(func(channel, chatID string) string {
+		return agentLoop.LocaleFor(bus.InboundMessage{Channel: channel, ChatID: chatID})
+	})
 	if err := channelManager.StartAll(ctx); err != nil {
 		fmt.Printf("Error starting channels: %v\n", err)
 	}
@@ -188,6 +201,7 @@ func gatewayCmd() {
 
 	reloadCallback := createReloadCallback(agentLoop, mainBus)
 	gw := gateway.NewCommandGateway(mainBus, agentBus, channelManager, agentLoop.GetRegistry(), reloadCallback)
+	gw.SetAgentLoop(agentLoop)
 	go gw.Run(ctx)
 
 	go agentLoop.Run(ctx)
@@ -211,16 +225,18 @@ func gatewayCmd() {
 // It reloads config from disk, recreates the provider, and updates the agent registry.
 func createReloadCallback(agentLoop *agent.AgentLoop, msgBus bus.Broker) func(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	return func(ctx context.Context, msg bus.InboundMessage) (string, error) {
+		locale := agentLoop.LocaleFor(msg)
+
 		// Load new config
 		newCfg, err := loadConfig()
 		if err != nil {
-			return fmt.Sprintf("Failed to load config: %v", err), nil
+			return i18n.T(locale, "cmd.reload.load_failed", err), nil
 		}
 
 		// Create new provider instance
 		newProvider, modelID, err := providers.CreateProvider(newCfg)
 		if err != nil {
-			return fmt.Sprintf("Failed to create provider: %v", err), nil
+			return i18n.T(locale, "cmd.reload.provider_failed", err), nil
 		}
 		// Use the resolved model ID from provider creation
 		if modelID != "" {
@@ -230,7 +246,7 @@ func createReloadCallback(agentLoop *agent.AgentLoop, msgBus bus.Broker) func(ct
 		// Trigger registry reload with new provider and setup function
 		registry := agentLoop.GetRegistry()
 		if err := registry.Reload(newCfg, newProvider, msgBus, agentSetupFunc); err != nil {
-			return fmt.Sprintf("Failed to reload registry: %v", err), nil
+			return i18n.T(locale, "cmd.reload.registry_failed", err), nil
 		}
 
 		// Update agentLoop's config reference
@@ -240,7 +256,7 @@ func createReloadCallback(agentLoop *agent.AgentLoop, msgBus bus.Broker) func(ct
 		// Get new agent count
 		agentIDs := registry.ListAgentIDs()
 
-		return fmt.Sprintf("Config reloaded successfully. Provider: %s, %d agent(s) available: %s",
+		return i18n.T(agentLoop.LocaleFor(msg), "cmd.reload.done",
 			modelID, len(agentIDs), strings.Join(agentIDs, ", ")), nil
 	}
 }
@@ -0,0 +1,441 @@
+// MobaiClaw - Ultra-lightweight personal AI agent
+// License: MIT
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/agent"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/routing"
+	"github.com/zhaopengme/mobaiclaw/pkg/session"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+func sessionCmd() {
+	if len(os.Args) < 3 {
+		sessionHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "list", "ls":
+		sessionListCmd(os.Args[3:])
+	case "show":
+		sessionShowCmd(os.Args[3:])
+	case "delete", "rm":
+		sessionDeleteCmd(os.Args[3:])
+	case "prune":
+		sessionPruneCmd(os.Args[3:])
+	case "export":
+		sessionExportCmd(os.Args[3:])
+	default:
+		fmt.Printf("Unknown session command: %s\n", os.Args[2])
+		sessionHelp()
+	}
+}
+
+func sessionHelp() {
+	fmt.Println("\nSession commands:")
+	fmt.Println("  list                List sessions, most recently active first")
+	fmt.Println("  show <key>          Show a session and its latest messages")
+	fmt.Println("  delete <key>...     Delete sessions")
+	fmt.Println("  prune               Delete sessions inactive for --older-than")
+	fmt.Println("  export <key>        Export a conversation transcript")
+	fmt.Println()
+	fmt.Println("List and prune options:")
+	fmt.Println("  --agent             Only sessions of this agent (default: all agents)")
+	fmt.Println("  --channel           Only sessions of this channel")
+	fmt.Println("  --older-than        Only sessions inactive for this long (e.g. 30d, 12h)")
+	fmt.Println("  --newer-than        Only sessions active within this long")
+	fmt.Println("  --dry-run           prune: list what would be deleted")
+	fmt.Println()
+	fmt.Println("Show options:")
+	fmt.Println("  -n, --messages      Number of latest messages to print (default: 10)")
+	fmt.Println()
+	fmt.Println("Export options:")
+	fmt.Println("  -f, --format        md (default), html or json")
+	fmt.Println("  -o, --output        Write to a file instead of stdout")
+	fmt.Println("  --agent             Agent owning the session (default: from the key, else main)")
+	fmt.Println("  --redact            Replace tool outputs with a placeholder")
+}
+
+// sessionArgs holds the options shared by the session commands.
+type sessionArgs struct {
+	agentID string
+	filter  session.ListFilter
+	dryRun  bool
+	count   int
+	keys    []string
+}
+
+func parseSessionArgs(args []string) sessionArgs {
+	parsed := sessionArgs{count: 10}
+	age := func(flag, value string) time.Duration {
+		d, err := session.ParseAge(value)
+		if err != nil || d <= 0 {
+			fmt.Printf("Invalid %s value %q (use e.g. 30d, 12h or 90m)\n", flag, value)
+			os.Exit(1)
+		}
+		return d
+	}
+	for i := 0; i < len(args); i++ {
+		value := ""
+		if i+1 < len(args) {
+			value = args[i+1]
+		}
+		switch args[i] {
+		case "--agent":
+			parsed.agentID = value
+			i++
+		case "--channel":
+			parsed.filter.Channel = value
+			i++
+		case "--older-than":
+			parsed.filter.OlderThan = age(args[i], value)
+			i++
+		case "--newer-than":
+			parsed.filter.NewerThan = age(args[i], value)
+			i++
+		case "-n", "--messages":
+			fmt.Sscanf(value, "%d", &parsed.count)
+			i++
+		case "--dry-run":
+			parsed.dryRun = true
+		default:
+			parsed.keys = append(parsed.keys, args[i])
+		}
+	}
+	return parsed
+}
+
+// agentSessions is the session manager of one agent.
+type agentSessions struct {
+	agentID  string
+	sessions *session.SessionManager
+}
+
+// openAgentSessions opens the sessions of agentID, or of every configured
+// agent when it is empty. Agents sharing a workspace are opened once.
+func openAgentSessions(cfg *config.Config, agentID string) []agentSessions {
+	var ids []string
+	switch {
+	case agentID != "":
+		ids = []string{agentID}
+	case len(cfg.Agents.List) == 0:
+		ids = []string{routing.DefaultAgentID}
+	default:
+		for _, ac := range cfg.Agents.List {
+			ids = append(ids, ac.ID)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var opened []agentSessions
+	for _, id := range ids {
+		id = routing.NormalizeAgentID(id)
+		dir := agent.SessionsDir(cfg, id)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		opened = append(opened, agentSessions{agentID: id, sessions: agent.OpenSessionManager(cfg, id)})
+	}
+	return opened
+}
+
+// sessionAgentID returns the agent owning key: agentID when given, else the
+// agent named in the key, else the default agent.
+func sessionAgentID(key, agentID string) string {
+	if agentID != "" {
+		return agentID
+	}
+	if parsed := routing.ParseAgentSessionKey(key); parsed != nil {
+		return parsed.AgentID
+	}
+	return routing.DefaultAgentID
+}
+
+// listSessions returns the sessions of each opened agent that match filter.
+func listSessions(opened []agentSessions, filter session.ListFilter) map[string][]session.SessionInfo {
+	now := time.Now()
+	matched := make(map[string][]session.SessionInfo)
+	for _, a := range opened {
+		infos, err := a.sessions.List()
+		if err != nil {
+			fmt.Printf("Error listing sessions of agent %s: %v\n", a.agentID, err)
+			continue
+		}
+		for _, info := range infos {
+			if filter.Match(info, now) {
+				matched[a.agentID] = append(matched[a.agentID], info)
+			}
+		}
+	}
+	return matched
+}
+
+func sessionListCmd(args []string) {
+	parsed := parseSessionArgs(args)
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	opened := openAgentSessions(cfg, parsed.agentID)
+	matched := listSessions(opened, parsed.filter)
+	total := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENT\tKEY\tCHANNEL\tMESSAGES\tSUMMARY\tLAST ACTIVITY\tSIZE")
+	for _, a := range opened {
+		for _, info := range matched[a.agentID] {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				a.agentID, info.Key, orDash(info.Channel), info.Messages,
+				yesNo(info.HasSummary), formatActivity(info.Updated), utils.FormatBytes(info.Size))
+			total++
+		}
+	}
+	w.Flush()
+	fmt.Printf("\n%d session(s)\n", total)
+}
+
+func sessionShowCmd(args []string) {
+	parsed := parseSessionArgs(args)
+	if len(parsed.keys) != 1 {
+		fmt.Println("Usage: mobaiclaw sessions show <key> [--agent id] [-n count]")
+		os.Exit(1)
+	}
+	key := parsed.keys[0]
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	agentID := sessionAgentID(key, parsed.agentID)
+	store, err := agent.OpenSessionStore(cfg, agentID)
+	if err != nil {
+		fmt.Printf("Error opening sessions: %v\n", err)
+		os.Exit(1)
+	}
+	sess, err := store.Load(key)
+	if err != nil {
+		fmt.Printf("Error loading session: %v\n", err)
+		os.Exit(1)
+	}
+	if sess == nil {
+		fmt.Printf("Session %s not found for agent %s\n", key, agentID)
+		os.Exit(1)
+	}
+	size, _ := store.Size(key)
+
+	fmt.Printf("Session:       %s\n", sess.Key)
+	fmt.Printf("Agent:         %s\n", agentID)
+	if sess.Channel != "" {
+		fmt.Printf("Chat:          %s:%s\n", sess.Channel, sess.ChatID)
+	}
+	fmt.Printf("Messages:      %d\n", len(sess.Messages))
+	fmt.Printf("Started:       %s\n", formatActivity(sess.Created))
+	fmt.Printf("Last activity: %s\n", formatActivity(sess.Updated))
+	fmt.Printf("Size:          %s\n", utils.FormatBytes(size))
+	if sess.Model != "" {
+		fmt.Printf("Model:         %s\n", sess.Model)
+	}
+	if sess.Summary != "" {
+		fmt.Printf("\nSummary:\n%s\n", sess.Summary)
+	}
+
+	messages := sess.Messages
+	if parsed.count >= 0 && len(messages) > parsed.count {
+		messages = messages[len(messages)-parsed.count:]
+	}
+	if len(messages) > 0 {
+		fmt.Printf("\nLast %d message(s):\n", len(messages))
+	}
+	for _, msg := range messages {
+		content := msg.Content
+		if content == "" && len(msg.ToolCalls) > 0 {
+			names := make([]string, 0, len(msg.ToolCalls))
+			for _, tc := range msg.ToolCalls {
+				name := tc.Name
+				if name == "" && tc.Function != nil {
+					name = tc.Function.Name
+				}
+				names = append(names, name)
+			}
+			content = "[calls " + strings.Join(names, ", ") + "]"
+		}
+		fmt.Printf("  %-9s %s\n", msg.Role+":", truncateLine(content, 200))
+	}
+}
+
+func sessionDeleteCmd(args []string) {
+	parsed := parseSessionArgs(args)
+	if len(parsed.keys) == 0 {
+		fmt.Println("Usage: mobaiclaw sessions delete <key>... [--agent id]")
+		os.Exit(1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, key := range parsed.keys {
+		sessions := agent.OpenSessionManager(cfg, sessionAgentID(key, parsed.agentID))
+		if err := sessions.Delete(key); err != nil {
+			fmt.Printf("✗ %s: %v\n", key, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Deleted %s\n", key)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func sessionPruneCmd(args []string) {
+	parsed := parseSessionArgs(args)
+	if parsed.filter.OlderThan == 0 {
+		fmt.Println("Usage: mobaiclaw sessions prune --older-than <age> [--agent id] [--channel name] [--dry-run]")
+		os.Exit(1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	opened := openAgentSessions(cfg, parsed.agentID)
+	matched := listSessions(opened, parsed.filter)
+	pruned, freed := 0, int64(0)
+	for _, a := range opened {
+		for _, info := range matched[a.agentID] {
+			if parsed.dryRun {
+				fmt.Printf("  would delete %s (%s, last active %s)\n", info.Key, a.agentID, formatActivity(info.Updated))
+			} else if err := a.sessions.Delete(info.Key); err != nil {
+				fmt.Printf("✗ %s: %v\n", info.Key, err)
+				continue
+			}
+			pruned++
+			freed += info.Size
+		}
+	}
+	if parsed.dryRun {
+		fmt.Printf("\n%d session(s) would be deleted, freeing %s\n", pruned, utils.FormatBytes(freed))
+		return
+	}
+	fmt.Printf("✓ Deleted %d session(s), freeing %s\n", pruned, utils.FormatBytes(freed))
+}
+
+func formatActivity(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// truncateLine puts s on one line of at most n runes.
+func truncateLine(s string, n int) string {
+	return utils.Truncate(strings.Join(strings.Fields(s), " "), n)
+}
+
+func sessionExportCmd(args []string) {
+	key, format, output, agentID := "", "", "", ""
+	var opts session.ExportOptions
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-f", "--format":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		case "--agent":
+			if i+1 < len(args) {
+				agentID = args[i+1]
+				i++
+			}
+		case "--redact":
+			opts.RedactToolOutput = true
+		default:
+			key = args[i]
+		}
+	}
+	if key == "" {
+		fmt.Println("Usage: mobaiclaw sessions export <key> [--format md|html|json] [-o file] [--redact]")
+		os.Exit(1)
+	}
+	format, err := session.ParseExportFormat(format)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	agentID = sessionAgentID(key, agentID)
+
+	store, err := agent.OpenSessionStore(cfg, agentID)
+	if err != nil {
+		fmt.Printf("Error opening sessions: %v\n", err)
+		os.Exit(1)
+	}
+	sess, err := store.Load(key)
+	if err != nil {
+		fmt.Printf("Error loading session: %v\n", err)
+		os.Exit(1)
+	}
+	if sess == nil {
+		fmt.Printf("Session %s not found for agent %s\n", key, agentID)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" && output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Printf("Error creating %s: %v\n", output, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := session.Export(w, sess, format, opts); err != nil {
+		fmt.Printf("Error exporting session: %v\n", err)
+		os.Exit(1)
+	}
+	if output != "" && output != "-" {
+		fmt.Printf("✓ Exported %s to %s\n", key, output)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"runtime"
 
 	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
 	"github.com/zhaopengme/mobaiclaw/pkg/skills"
 )
 
@@ -115,6 +116,12 @@ func main() {
 		authCmd()
 	case "cron":
 		cronCmd()
+	case "eval":
+		evalCmd()
+	case "session", "sessions":
+		sessionCmd()
+	case "encrypt-workspace":
+		encryptWorkspaceCmd()
 	case "skills":
 		if len(os.Args) < 3 {
 			skillsHelp()
@@ -184,6 +191,9 @@ func printHelp() {
 	fmt.Println("  gateway     Start mobaiclaw gateway")
 	fmt.Println("  status      Show mobaiclaw status")
 	fmt.Println("  cron        Manage scheduled tasks")
+	fmt.Println("  eval        Run conversation regression scenarios")
+	fmt.Println("  sessions    List, inspect, prune and export sessions")
+	fmt.Println("  encrypt-workspace  Encrypt sessions, memory and cron jobs at rest")
 	fmt.Println("  migrate     Migrate from OpenClaw to MobaiClaw")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
 	fmt.Println("  version     Show version information")
@@ -195,5 +205,16 @@ func getConfigPath() string {
 }
 
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfig(getConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypt.LoadKey(cfg.EncryptionKeyFile())
+	if err != nil {
+		return nil, err
+	}
+	if err := crypt.Enable(key); err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	return cfg, nil
 }
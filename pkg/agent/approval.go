@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/constants"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+const defaultApprovalTimeout = 5 * time.Minute
+
+// pendingApproval is a tool call waiting for the user's decision.
+type pendingApproval struct {
+	channel  string
+	chatID   string
+	senderID string // sender of the message that led to the call, if known
+	decision chan bool
+}
+
+// awaitApproval asks the originating chat to approve a tool call and blocks
+// until the user answers, the approval times out or the turn is cancelled.
+// It returns "" when the call may run, or the reason it was not approved.
+func (al *AgentLoop) awaitApproval(ctx context.Context, agent *AgentInstance, tc providers.ToolCall, opts processOptions) string {
+	if constants.IsInternalChannel(opts.Channel) || opts.Channel == "" || opts.ChatID == "" {
+		return fmt.Sprintf("Tool call %s requires user approval, which is not available on this channel. It was not executed.", tc.Name)
+	}
+
+	id := newApprovalID()
+	pending := &pendingApproval{
+		channel:  opts.Channel,
+		chatID:   opts.ChatID,
+		senderID: opts.SenderID,
+		decision: make(chan bool, 1),
+	}
+	al.approvals.Store(id, pending)
+	defer al.approvals.Delete(id)
+
+	timeout := al.approvalTimeout()
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: opts.Channel,
+		ChatID:  opts.ChatID,
+		Content: i18n.T(opts.Locale, "agent.approval_request",
+			id, tc.Name, utils.Truncate(string(argsJSON), 1000), id, id, timeout),
+		Metadata: map[string]string{"approval_id": id, "locale": opts.Locale},
+	})
+
+	logger.InfoCF("agent", "Waiting for tool approval",
+		map[string]interface{}{
+			"agent_id":    agent.ID,
+			"tool":        tc.Name,
+			"approval_id": id,
+			"session_key": opts.SessionKey,
+		})
+
+	// Waiting on the user must not hold back the other chats
+	defer yieldSlot(ctx)()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case approved := <-pending.decision:
+		if approved {
+			return ""
+		}
+		return fmt.Sprintf("The user denied the %s call. It was not executed.", tc.Name)
+	case <-timer.C:
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Content: i18n.T(opts.Locale, "agent.approval_expired", id, tc.Name),
+		})
+		return fmt.Sprintf("Approval for the %s call timed out after %s. It was not executed.", tc.Name, timeout)
+	case <-ctx.Done():
+		return "Turn cancelled while waiting for approval."
+	}
+}
+
+// ResolveApproval delivers the user's decision for a pending approval. The
+// decision must come from the chat that was asked, by the user whose message
+// led to the call or by a gateway admin. It returns false when no such
+// approval is pending.
+func (al *AgentLoop) ResolveApproval(channel, chatID, senderID, id string, approved bool) bool {
+	value, ok := al.approvals.Load(id)
+	if !ok {
+		return false
+	}
+	pending := value.(*pendingApproval)
+	if pending.channel != channel || pending.chatID != chatID {
+		return false
+	}
+	if pending.senderID != "" && pending.senderID != senderID && !al.isAdmin(channel, senderID) {
+		return false
+	}
+	al.approvals.Delete(id)
+	select {
+	case pending.decision <- approved:
+	default:
+	}
+	return true
+}
+
+func (al *AgentLoop) approvalTimeout() time.Duration {
+	if cfg := al.GetConfig(); cfg != nil && cfg.Tools.Approval.TimeoutSeconds > 0 {
+		return time.Duration(cfg.Tools.Approval.TimeoutSeconds) * time.Second
+	}
+	return defaultApprovalTimeout
+}
+
+func (al *AgentLoop) isAdmin(channel, senderID string) bool {
+	cfg := al.GetConfig()
+	return cfg != nil && cfg.Gateway.IsAdmin(channel, senderID)
+}
+
+func newApprovalID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
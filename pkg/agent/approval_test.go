@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// singleToolCallProvider requests one call to a tool, then records the tool result.
+type singleToolCallProvider struct {
+	tool   string
+	calls  int
+	result string
+}
+
+func (p *singleToolCallProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	if p.calls == 1 {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+			ID:        "call_1",
+			Type:      "function",
+			Name:      p.tool,
+			Arguments: map[string]interface{}{"label": "ran"},
+		}}}, nil
+	}
+	p.result = messages[len(messages)-1].Content
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (p *singleToolCallProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func newApprovalTestLoop(t *testing.T, timeoutSeconds int) (*AgentLoop, *bus.MessageBus, *singleToolCallProvider, *sleepTool) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{
+			Approval: config.ApprovalConfig{
+				Enabled:        true,
+				TimeoutSeconds: timeoutSeconds,
+				Rules:          []config.ApprovalRule{{Tool: "danger"}},
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	provider := &singleToolCallProvider{tool: "danger"}
+	al := NewAgentLoop(cfg, msgBus, provider)
+	tool := &sleepTool{name: "danger"}
+	al.RegisterTool(tool)
+	return al, msgBus, provider, tool
+}
+
+// nextApprovalID waits for the approval request published on the bus.
+func nextApprovalID(t *testing.T, msgBus *bus.MessageBus) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), responseTimeout)
+	defer cancel()
+	for {
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok {
+			t.Fatal("no approval request was sent")
+		}
+		if id := msg.Metadata["approval_id"]; id != "" {
+			if !strings.Contains(msg.Content, "/approve "+id) {
+				t.Errorf("approval request lacks the /approve hint: %q", msg.Content)
+			}
+			return id
+		}
+	}
+}
+
+func TestAgentLoop_ApprovedToolCallRuns(t *testing.T) {
+	al, msgBus, provider, tool := newApprovalTestLoop(t, 0)
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "do it"}
+
+	done := make(chan struct{})
+	go func() {
+		testHelper{al: al}.executeAndGetResponse(t, context.Background(), msg)
+		close(done)
+	}()
+
+	id := nextApprovalID(t, msgBus)
+	if len(id) < 16 {
+		t.Errorf("approval ID %q is too short to be unguessable", id)
+	}
+	if al.ResolveApproval("telegram", "other-chat", "user1", id, true) {
+		t.Error("approval from another chat must be rejected")
+	}
+	if al.ResolveApproval("telegram", "chat1", "user2", id, true) {
+		t.Error("approval from another member of the chat must be rejected")
+	}
+	if !al.ResolveApproval("telegram", "chat1", "user1", id, true) {
+		t.Fatal("ResolveApproval returned false for a pending approval")
+	}
+	<-done
+
+	if tool.maxRunning.Load() != 1 || provider.result != "ran" {
+		t.Errorf("tool did not run after approval (result %q)", provider.result)
+	}
+	if al.ResolveApproval("telegram", "chat1", "user1", id, true) {
+		t.Error("approval must not be resolvable twice")
+	}
+}
+
+func TestAgentLoop_DeniedToolCallIsSkipped(t *testing.T) {
+	al, msgBus, provider, tool := newApprovalTestLoop(t, 0)
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "do it"}
+
+	done := make(chan struct{})
+	go func() {
+		testHelper{al: al}.executeAndGetResponse(t, context.Background(), msg)
+		close(done)
+	}()
+
+	al.ResolveApproval("telegram", "chat1", "user1", nextApprovalID(t, msgBus), false)
+	<-done
+
+	if tool.maxRunning.Load() != 0 {
+		t.Error("denied tool call must not run")
+	}
+	if !strings.Contains(provider.result, "denied") {
+		t.Errorf("tool result = %q, want a denial", provider.result)
+	}
+}
+
+func TestAgentLoop_ApprovalTimesOut(t *testing.T) {
+	al, _, provider, tool := newApprovalTestLoop(t, 1)
+	start := time.Now()
+	testHelper{al: al}.executeAndGetResponse(t, context.Background(),
+		bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "do it"})
+
+	if time.Since(start) < time.Second {
+		t.Error("turn finished before the approval timeout")
+	}
+	if tool.maxRunning.Load() != 0 || !strings.Contains(provider.result, "timed out") {
+		t.Errorf("expected a timeout result, got %q", provider.result)
+	}
+}
+
+func TestAgentLoop_ApprovalUnavailableOnInternalChannels(t *testing.T) {
+	al, _, provider, tool := newApprovalTestLoop(t, 0)
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "do it", "agent:main:cli", "cli", "direct"); err != nil {
+		t.Fatal(err)
+	}
+	if tool.maxRunning.Load() != 0 || !strings.Contains(provider.result, "not available") {
+		t.Errorf("expected the call to be refused, got %q", provider.result)
+	}
+}
+
+func TestAgentLoop_AdminMayAnswerApprovals(t *testing.T) {
+	al, msgBus, _, tool := newApprovalTestLoop(t, 0)
+	al.GetConfig().Gateway.Admins = config.FlexibleStringSlice{"telegram:admin1"}
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "do it"}
+
+	done := make(chan struct{})
+	go func() {
+		testHelper{al: al}.executeAndGetResponse(t, context.Background(), msg)
+		close(done)
+	}()
+
+	if !al.ResolveApproval("telegram", "chat1", "admin1|alice", nextApprovalID(t, msgBus), true) {
+		t.Fatal("an admin should be able to answer the approval")
+	}
+	<-done
+	if tool.maxRunning.Load() != 1 {
+		t.Error("tool did not run after the admin approved it")
+	}
+}
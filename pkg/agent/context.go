@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 	"github.com/zhaopengme/mobaiclaw/pkg/skills"
@@ -201,10 +202,12 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 
 	messages = append(messages, history...)
 
-	if strings.TrimSpace(currentMessage) != "" {
+	contentParts := buildContentParts(currentMessage, media)
+	if strings.TrimSpace(currentMessage) != "" || len(contentParts) > 0 {
 		messages = append(messages, providers.Message{
-			Role:    "user",
-			Content: currentMessage,
+			Role:         "user",
+			Content:      currentMessage,
+			ContentParts: contentParts,
 		})
 	}
 
@@ -337,6 +340,23 @@ func (cb *ContextBuilder) AddAssistantMessage(messages []providers.Message, cont
 	return messages
 }
 
+// withAmbientContext prefixes a user message with the group messages sent
+// since the bot was last addressed, each attributed to its sender. The result
+// is only sent to the model, never stored in the session.
+func withAmbientContext(content string, ambient []bus.AmbientMessage) string {
+	if len(ambient) == 0 {
+		return content
+	}
+	var sb strings.Builder
+	sb.WriteString("[Recent messages in this chat, not addressed to you]\n")
+	for _, m := range ambient {
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", m.Time.Local().Format("15:04"), m.Sender, m.Content))
+	}
+	sb.WriteString("\n[Message addressed to you]\n")
+	sb.WriteString(content)
+	return sb.String()
+}
+
 func (cb *ContextBuilder) loadSkills() string {
 	allSkills := cb.skillsLoader.ListSkills()
 	if len(allSkills) == 0 {
@@ -2,10 +2,15 @@
 package agent
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
 )
 
 func TestLoadBootstrapFiles_NoUserMD(t *testing.T) {
@@ -24,3 +29,49 @@ func TestLoadBootstrapFiles_NoUserMD(t *testing.T) {
 		t.Errorf("LoadBootstrapFiles should load AGENTS.md")
 	}
 }
+
+func TestAgentLoop_AmbientContextSentButNotStored(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace: t.TempDir(),
+				Model:     "test-model",
+				MaxTokens: 4096,
+			},
+		},
+	}
+	provider := &promptRecorder{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	const key = "agent:main:ambient"
+
+	at := time.Now().Add(-5 * time.Minute)
+	_, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel:    "discord",
+		SenderID:   "42",
+		ChatID:     "room",
+		Content:    "what do you think?",
+		SessionKey: key,
+		Ambient: []bus.AmbientMessage{
+			{Sender: "alice", Content: "shall we meet on friday?", Time: at},
+			{Sender: "bob", Content: "saturday works better", Time: at.Add(time.Minute)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+
+	prompt := provider.messages[len(provider.messages)-1].Content
+	for _, want := range []string{"alice: shall we meet on friday?", "bob: saturday works better", "what do you think?"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Index(prompt, "saturday") > strings.Index(prompt, "what do you think?") {
+		t.Errorf("ambient messages should precede the addressed message:\n%s", prompt)
+	}
+
+	history := al.registry.GetDefaultAgent().Sessions.GetHistory(key)
+	if len(history) != 2 || history[0].Role != "user" || history[0].Content != "what do you think?" {
+		t.Fatalf("history = %+v, want only the user's own message and the reply", history)
+	}
+}
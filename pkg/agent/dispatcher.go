@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+)
+
+// defaultMaxConcurrentSessions is used when agents.defaults.max_concurrent_sessions is unset.
+const defaultMaxConcurrentSessions = 4
+
+// sessionDispatcher runs inbound messages on a worker per session key.
+// Messages for the same session are processed strictly in arrival order,
+// while different sessions run in parallel up to the concurrency limit.
+type sessionDispatcher struct {
+	handle func(ctx context.Context, msg bus.InboundMessage)
+	slots  chan struct{}
+	queues map[string][]bus.InboundMessage
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+}
+
+func newSessionDispatcher(limit int, handle func(ctx context.Context, msg bus.InboundMessage)) *sessionDispatcher {
+	if limit <= 0 {
+		limit = defaultMaxConcurrentSessions
+	}
+	return &sessionDispatcher{
+		handle: handle,
+		slots:  make(chan struct{}, limit),
+		queues: make(map[string][]bus.InboundMessage),
+	}
+}
+
+// Dispatch enqueues msg for the given session key. It never blocks on
+// processing; a worker is started if the session has none.
+func (d *sessionDispatcher) Dispatch(ctx context.Context, key string, msg bus.InboundMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if queue, active := d.queues[key]; active {
+		d.queues[key] = append(queue, msg)
+		return
+	}
+
+	d.queues[key] = []bus.InboundMessage{msg}
+	d.wg.Add(1)
+	go d.work(ctx, key)
+}
+
+// work drains the queue of one session, holding a concurrency slot per message.
+func (d *sessionDispatcher) work(ctx context.Context, key string) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		msg := queue[0]
+		d.queues[key] = queue[1:]
+		d.mu.Unlock()
+
+		select {
+		case d.slots <- struct{}{}:
+		case <-ctx.Done():
+			d.mu.Lock()
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		slot := &dispatchSlot{slots: d.slots}
+		d.handle(context.WithValue(ctx, dispatchSlotKey{}, slot), msg)
+		<-d.slots
+	}
+}
+
+type dispatchSlotKey struct{}
+
+// dispatchSlot is the concurrency slot held by one turn. The turn gives it
+// up while it waits on the user, so that idle turns do not hold back other
+// sessions.
+type dispatchSlot struct {
+	slots   chan struct{}
+	mu      sync.Mutex
+	waiting int // waits in progress, possibly from parallel tool calls
+}
+
+// yieldSlot gives up the concurrency slot of the turn running in ctx for a
+// wait that needs no processing. It returns a function that takes the slot
+// back, blocking until one is free, which must be called once the wait ends.
+func yieldSlot(ctx context.Context) func() {
+	slot, ok := ctx.Value(dispatchSlotKey{}).(*dispatchSlot)
+	if !ok {
+		return func() {}
+	}
+	slot.mu.Lock()
+	slot.waiting++
+	if slot.waiting == 1 {
+		<-slot.slots
+	}
+	slot.mu.Unlock()
+
+	return func() {
+		slot.mu.Lock()
+		defer slot.mu.Unlock()
+		slot.waiting--
+		if slot.waiting == 0 {
+			slot.slots <- struct{}{}
+		}
+	}
+}
+
+// Busy reports whether the session has a worker, i.e. a message of it is
+// being processed or waiting in its queue.
+func (d *sessionDispatcher) Busy(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, active := d.queues[key]
+	return active
+}
+
+// Wait blocks until all session workers have exited.
+func (d *sessionDispatcher) Wait() {
+	d.wg.Wait()
+}
@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+)
+
+func TestSessionDispatcher_SameSessionOrdered(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+
+	d := newSessionDispatcher(4, func(ctx context.Context, msg bus.InboundMessage) {
+		// Earlier messages sleep longer; ordering must still hold.
+		if msg.Content == "1" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		mu.Lock()
+		order = append(order, msg.Content)
+		mu.Unlock()
+	})
+
+	ctx := context.Background()
+	for _, c := range []string{"1", "2", "3"} {
+		d.Dispatch(ctx, "session-a", bus.InboundMessage{Content: c})
+	}
+	d.Wait()
+
+	if len(order) != 3 || order[0] != "1" || order[1] != "2" || order[2] != "3" {
+		t.Errorf("Expected messages in order [1 2 3], got %v", order)
+	}
+}
+
+func TestSessionDispatcher_DifferentSessionsParallel(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 2)
+
+	d := newSessionDispatcher(2, func(ctx context.Context, msg bus.InboundMessage) {
+		started <- msg.Content
+		<-release
+	})
+
+	ctx := context.Background()
+	d.Dispatch(ctx, "session-a", bus.InboundMessage{Content: "a"})
+	d.Dispatch(ctx, "session-b", bus.InboundMessage{Content: "b"})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("Expected both sessions to start while the other is still running")
+		}
+	}
+	close(release)
+	d.Wait()
+}
+
+func TestSessionDispatcher_RespectsLimit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 3)
+
+	d := newSessionDispatcher(1, func(ctx context.Context, msg bus.InboundMessage) {
+		started <- msg.Content
+		<-release
+	})
+
+	ctx := context.Background()
+	d.Dispatch(ctx, "session-a", bus.InboundMessage{Content: "a"})
+	d.Dispatch(ctx, "session-b", bus.InboundMessage{Content: "b"})
+
+	<-started
+	select {
+	case c := <-started:
+		t.Fatalf("Expected second session to wait for a free slot, but %q started", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected second session to start after the first finished")
+	}
+	close(release)
+	d.Wait()
+}
+
+func TestSessionDispatcher_YieldedSlotLetsOthersRun(t *testing.T) {
+	waiting := make(chan struct{})
+	answer := make(chan struct{})
+	done := make(chan string, 2)
+
+	d := newSessionDispatcher(1, func(ctx context.Context, msg bus.InboundMessage) {
+		if msg.Content == "a" {
+			reacquire := yieldSlot(ctx)
+			close(waiting)
+			<-answer
+			reacquire()
+		}
+		done <- msg.Content
+	})
+
+	ctx := context.Background()
+	d.Dispatch(ctx, "session-a", bus.InboundMessage{Content: "a"})
+	<-waiting
+	d.Dispatch(ctx, "session-b", bus.InboundMessage{Content: "b"})
+
+	select {
+	case c := <-done:
+		if c != "b" {
+			t.Fatalf("expected session b to finish first, got %q", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected session b to run while session a waits")
+	}
+	close(answer)
+	d.Wait()
+	if len(d.slots) != 0 {
+		t.Errorf("%d slots still held after all sessions finished", len(d.slots))
+	}
+}
+
+func TestSessionDispatcher_QueuedSessionIsBusy(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string, 2)
+
+	d := newSessionDispatcher(1, func(ctx context.Context, msg bus.InboundMessage) {
+		started <- msg.Content
+		<-release
+	})
+	al := &AgentLoop{}
+	al.dispatcher.Store(d)
+
+	ctx := context.Background()
+	d.Dispatch(ctx, "session-a", bus.InboundMessage{Content: "a"})
+	<-started
+	// session-b waits for the only slot, so no turn is running for it yet
+	d.Dispatch(ctx, "session-b", bus.InboundMessage{Content: "b"})
+
+	if !al.SessionBusy("session-b") {
+		t.Error("a session with a queued message should be busy")
+	}
+	if al.SessionBusy("session-c") {
+		t.Error("a session without messages should not be busy")
+	}
+
+	close(release)
+	d.Wait()
+	if al.SessionBusy("session-a") || al.SessionBusy("session-b") {
+		t.Error("sessions should not be busy once their queues drain")
+	}
+}
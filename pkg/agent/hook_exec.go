@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+const defaultHookTimeout = 10 * time.Second
+
+// hookReply is what an external hook may print on stdout. Fields left out
+// keep the event unchanged.
+type hookReply struct {
+	Veto       bool                   `json:"veto"`
+	Reason     string                 `json:"reason"`
+	Messages   []providers.Message    `json:"messages"`
+	Response   *providers.LLMResponse `json:"response"`
+	ToolCall   *providers.ToolCall    `json:"tool_call"`
+	ToolResult *HookToolResult        `json:"tool_result"`
+}
+
+// externalHook wraps an executable configured in config.json as a hook.
+func externalHook(hc config.HookConfig) registeredHook {
+	name := hc.Name
+	if name == "" {
+		name = hc.Command
+	}
+	stages := make([]HookStage, 0, len(hc.Stages))
+	for _, s := range hc.Stages {
+		stages = append(stages, HookStage(s))
+	}
+	timeout := defaultHookTimeout
+	if hc.TimeoutSeconds > 0 {
+		timeout = time.Duration(hc.TimeoutSeconds) * time.Second
+	}
+
+	return registeredHook{
+		name:   name,
+		stages: stages,
+		tools:  hc.Tools,
+		run: func(ctx context.Context, event *HookEvent) error {
+			err := runExternalHook(ctx, hc, timeout, event)
+			if err != nil && hc.FailClosed {
+				if _, isVeto := err.(*HookVeto); !isVeto {
+					return &HookVeto{Hook: name, Reason: fmt.Sprintf("hook failed: %v", err)}
+				}
+			}
+			return err
+		},
+	}
+}
+
+func runExternalHook(ctx context.Context, hc config.HookConfig, timeout time.Duration, event *HookEvent) error {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hc.Command, hc.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "MOBAICLAW_HOOK_STAGE="+string(event.Stage))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, utils.Truncate(msg, 500))
+		}
+		return err
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return nil
+	}
+	var reply hookReply
+	if err := json.Unmarshal(output, &reply); err != nil {
+		return fmt.Errorf("invalid hook output: %w", err)
+	}
+	if reply.Veto {
+		return &HookVeto{Reason: reply.Reason}
+	}
+
+	switch event.Stage {
+	case HookBeforeLLM:
+		if reply.Messages != nil {
+			event.Messages = reply.Messages
+		}
+	case HookAfterLLM:
+		if reply.Response != nil {
+			event.Response = reply.Response
+		}
+	case HookBeforeTool:
+		if reply.ToolCall != nil && reply.ToolCall.Arguments != nil && event.ToolCall != nil {
+			event.ToolCall.Arguments = reply.ToolCall.Arguments
+		}
+	case HookAfterTool:
+		if reply.ToolResult != nil {
+			event.ToolResult = reply.ToolResult
+		}
+	}
+	return nil
+}
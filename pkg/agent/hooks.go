@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// HookStage identifies the point in the agent loop at which a hook runs.
+type HookStage string
+
+const (
+	// HookBeforeLLM runs before each LLM call. Hooks may rewrite the
+	// messages sent; the session history is not changed.
+	HookBeforeLLM HookStage = "before_llm"
+	// HookAfterLLM runs on each LLM response before it is used. Hooks may
+	// rewrite the content or tool calls. Replies are not streamed while any
+	// such hook is registered, so users only see what the hooks let through.
+	HookAfterLLM HookStage = "after_llm"
+	// HookBeforeTool runs before each tool call, ahead of the approval check.
+	// Hooks may rewrite the arguments.
+	HookBeforeTool HookStage = "before_tool"
+	// HookAfterTool runs on each tool result before the LLM sees it. Hooks
+	// may rewrite the result.
+	HookAfterTool HookStage = "after_tool"
+)
+
+// HookEvent is passed to hooks. Only the fields of its stage are set; hooks
+// change the outcome by modifying them in place.
+type HookEvent struct {
+	Stage      HookStage `json:"stage"`
+	AgentID    string    `json:"agent_id"`
+	SessionKey string    `json:"session_key"`
+	Channel    string    `json:"channel"`
+	ChatID     string    `json:"chat_id"`
+	Iteration  int       `json:"iteration"`
+	Model      string    `json:"model,omitempty"`
+
+	Messages   []providers.Message    `json:"messages,omitempty"`    // before_llm
+	Response   *providers.LLMResponse `json:"response,omitempty"`    // after_llm
+	ToolCall   *providers.ToolCall    `json:"tool_call,omitempty"`   // before_tool, after_tool
+	ToolResult *HookToolResult        `json:"tool_result,omitempty"` // after_tool
+}
+
+// HookToolResult is the tool output the LLM will see.
+type HookToolResult struct {
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// HookFunc handles a hook event. Returning a *HookVeto stops the LLM call or
+// tool call; any other error is logged and the event continues unchanged by
+// this hook. Tool hooks may run concurrently for parallel tool calls.
+type HookFunc func(ctx context.Context, event *HookEvent) error
+
+// HookVeto is returned by a hook to block what its event describes.
+type HookVeto struct {
+	Hook   string
+	Reason string
+}
+
+func (v *HookVeto) Error() string {
+	return fmt.Sprintf("blocked by hook %s: %s", v.Hook, v.Reason)
+}
+
+type registeredHook struct {
+	name   string
+	stages []HookStage
+	tools  []string // tool names for tool stages, empty for all
+	run    HookFunc
+}
+
+func (h registeredHook) handles(event *HookEvent) bool {
+	if len(h.stages) > 0 && !slices.Contains(h.stages, event.Stage) {
+		return false
+	}
+	if len(h.tools) > 0 && event.ToolCall != nil && !slices.Contains(h.tools, event.ToolCall.Name) {
+		return false
+	}
+	return true
+}
+
+// hookList holds the hooks registered in Go.
+type hookList struct {
+	mu    sync.RWMutex
+	hooks []registeredHook
+}
+
+// AddHook registers fn to run at the given stages, or at every stage when
+// none are given. Hooks run in registration order, before those configured
+// in config.json.
+func (al *AgentLoop) AddHook(name string, fn HookFunc, stages ...HookStage) {
+	al.hooks.mu.Lock()
+	defer al.hooks.mu.Unlock()
+	al.hooks.hooks = append(al.hooks.hooks, registeredHook{name: name, stages: stages, run: fn})
+}
+
+// allHooks returns the hooks registered in Go followed by those configured
+// in config.json.
+func (al *AgentLoop) allHooks() []registeredHook {
+	al.hooks.mu.RLock()
+	hooks := append([]registeredHook(nil), al.hooks.hooks...)
+	al.hooks.mu.RUnlock()
+	if cfg := al.GetConfig(); cfg != nil {
+		for _, hc := range cfg.Hooks {
+			hooks = append(hooks, externalHook(hc))
+		}
+	}
+	return hooks
+}
+
+// hasHooks reports whether any hook runs at stage.
+func (al *AgentLoop) hasHooks(stage HookStage) bool {
+	for _, h := range al.allHooks() {
+		if len(h.stages) == 0 || slices.Contains(h.stages, stage) {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks passes event through every hook registered for its stage. It
+// returns the first veto, or nil when the event may proceed.
+func (al *AgentLoop) runHooks(ctx context.Context, event *HookEvent) *HookVeto {
+	for _, h := range al.allHooks() {
+		if !h.handles(event) {
+			continue
+		}
+		err := h.run(ctx, event)
+		if err == nil {
+			continue
+		}
+		var veto *HookVeto
+		if errors.As(err, &veto) {
+			if veto.Hook == "" {
+				veto.Hook = h.name
+			}
+			logger.InfoCF("agent", "Hook vetoed",
+				map[string]interface{}{
+					"hook":        veto.Hook,
+					"stage":       string(event.Stage),
+					"reason":      veto.Reason,
+					"session_key": event.SessionKey,
+				})
+			return veto
+		}
+		logger.WarnCF("agent", "Hook failed",
+			map[string]interface{}{
+				"hook":  h.name,
+				"stage": string(event.Stage),
+				"error": err.Error(),
+			})
+	}
+	return nil
+}
+
+func newHookEvent(stage HookStage, agent *AgentInstance, iteration int, opts processOptions) *HookEvent {
+	return &HookEvent{
+		Stage:      stage,
+		AgentID:    agent.ID,
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		ChatID:     opts.ChatID,
+		Iteration:  iteration,
+	}
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+)
+
+func newHookTestLoop(t *testing.T, hooks ...config.HookConfig) (*AgentLoop, *singleToolCallProvider, *sleepTool) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Hooks: hooks,
+	}
+	provider := &singleToolCallProvider{tool: "probe"}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	tool := &sleepTool{name: "probe"}
+	al.RegisterTool(tool)
+	return al, provider, tool
+}
+
+func hookTestMessage() bus.InboundMessage {
+	return bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "go"}
+}
+
+func TestHooks_RewriteToolArgumentsAndResult(t *testing.T) {
+	al, provider, _ := newHookTestLoop(t)
+	al.AddHook("rewrite-args", func(ctx context.Context, event *HookEvent) error {
+		event.ToolCall.Arguments["label"] = "rewritten"
+		return nil
+	}, HookBeforeTool)
+	al.AddHook("annotate-result", func(ctx context.Context, event *HookEvent) error {
+		event.ToolResult.Content += " (checked)"
+		return nil
+	}, HookAfterTool)
+
+	testHelper{al: al}.executeAndGetResponse(t, context.Background(), hookTestMessage())
+
+	if provider.result != "rewritten (checked)" {
+		t.Errorf("tool result seen by the LLM = %q", provider.result)
+	}
+}
+
+func TestHooks_VetoToolCall(t *testing.T) {
+	al, provider, tool := newHookTestLoop(t)
+	al.AddHook("policy", func(ctx context.Context, event *HookEvent) error {
+		if event.ToolCall.Name == "probe" {
+			return &HookVeto{Reason: "probe is not allowed"}
+		}
+		return nil
+	}, HookBeforeTool)
+
+	testHelper{al: al}.executeAndGetResponse(t, context.Background(), hookTestMessage())
+
+	if tool.maxRunning.Load() != 0 {
+		t.Error("vetoed tool call must not run")
+	}
+	if !strings.Contains(provider.result, "probe is not allowed") {
+		t.Errorf("tool result seen by the LLM = %q, want the veto reason", provider.result)
+	}
+}
+
+func TestHooks_VetoLLMCall(t *testing.T) {
+	al, provider, _ := newHookTestLoop(t)
+	al.AddHook("failing", func(ctx context.Context, event *HookEvent) error {
+		return errors.New("ignored")
+	})
+	al.AddHook("gate", func(ctx context.Context, event *HookEvent) error {
+		return &HookVeto{Reason: "outside business hours"}
+	}, HookBeforeLLM)
+
+	response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), hookTestMessage())
+
+	if provider.calls != 0 {
+		t.Errorf("provider was called %d times despite the veto", provider.calls)
+	}
+	if !strings.Contains(response, "outside business hours") {
+		t.Errorf("response = %q, want the veto reason", response)
+	}
+}
+
+func TestHooks_ExternalExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script hook")
+	}
+	script := filepath.Join(t.TempDir(), "hook.sh")
+	body := "#!/bin/sh\ncat > /dev/null\necho '{\"tool_result\": {\"content\": \"redacted\"}}'\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	al, provider, _ := newHookTestLoop(t,
+		config.HookConfig{Name: "redact", Command: script, Stages: []string{"after_tool"}, Tools: []string{"probe"}},
+		config.HookConfig{Name: "missing", Command: filepath.Join(t.TempDir(), "missing"), Stages: []string{"before_llm"}},
+	)
+
+	testHelper{al: al}.executeAndGetResponse(t, context.Background(), hookTestMessage())
+
+	if provider.result != "redacted" {
+		t.Errorf("tool result seen by the LLM = %q, want the hook's replacement", provider.result)
+	}
+}
+
+func TestHooks_ExternalFailClosed(t *testing.T) {
+	al, provider, _ := newHookTestLoop(t, config.HookConfig{
+		Name:       "compliance",
+		Command:    filepath.Join(t.TempDir(), "missing"),
+		Stages:     []string{"before_llm"},
+		FailClosed: true,
+	})
+
+	response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), hookTestMessage())
+
+	if provider.calls != 0 || !strings.Contains(response, "blocked") {
+		t.Errorf("a failing fail_closed hook must block the call, got %q after %d calls", response, provider.calls)
+	}
+}
@@ -8,32 +8,52 @@ import (
 
 	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 	"github.com/zhaopengme/mobaiclaw/pkg/routing"
 	"github.com/zhaopengme/mobaiclaw/pkg/session"
 	"github.com/zhaopengme/mobaiclaw/pkg/tools"
+	"github.com/zhaopengme/mobaiclaw/pkg/usage"
 )
 
 // AgentInstance represents a fully configured agent with its own workspace,
 // session manager, context builder, and tool registry.
 type AgentInstance struct {
-	ID             string
-	Name           string
-	Model          string
-	Fallbacks      []string
-	Workspace      string
-	MaxIterations  int
-	MaxTokens      int
-	Temperature    float64
-	ContextWindow  int
-	SummaryModel   string
-	Provider       providers.LLMProvider
-	Sessions       *session.SessionManager
-	ContextBuilder *ContextBuilder
-	Tools          *tools.ToolRegistry
-	Subagents      *config.SubagentsConfig
-	SkillsFilter   []string
-	Candidates     []providers.FallbackCandidate
+	ID               string
+	Name             string
+	Model            string
+	Fallbacks        []string
+	Workspace        string
+	MaxIterations    int
+	MaxParallelTools int // tool calls of one iteration that may run at once
+	MaxTokens        int
+	Temperature      float64
+	ContextWindow    int
+	SummaryModel     string
+	ReasoningEffort  string // "low", "medium", "high" or "" for the model default
+	ThinkingBudget   int    // reasoning tokens, 0 when unset
+	Provider         providers.LLMProvider
+	Sessions         *session.SessionManager
+	ContextBuilder   *ContextBuilder
+	Memory           *MemoryStore
+	Tools            *tools.ToolRegistry
+	Subagents        *config.SubagentsConfig
+	SubagentMgr      *tools.SubagentManager // set by SetupAgentTools
+	SkillsFilter     []string
+	Candidates       []providers.FallbackCandidate
+	Usage            *usage.Store
+	Approvals        *tools.ApprovalPolicy // nil when approvals are disabled
+
+	// ImageCandidates routes requests carrying images to the configured
+	// image model and its fallbacks. Empty means the primary model is used.
+	ImageCandidates []providers.FallbackCandidate
+	imageProviders  map[string]providers.LLMProvider // ModelKey -> provider
+
+	// modelProviders caches the providers of the model_list entries chats
+	// switched to with /switch model. Entries are keyed by their whole config, so
+	// an edited entry gets a new provider.
+	modelMu        sync.Mutex
+	modelProviders map[config.ModelConfig]modelProvider
 }
 
 // NewAgentInstance creates an agent instance from config.
@@ -58,8 +78,7 @@ func NewAgentInstance(
 	toolsRegistry.Register(tools.NewEditFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewAppendFileTool(workspace, restrict))
 
-	sessionsDir := filepath.Join(workspace, "sessions")
-	sessionsManager := session.NewSessionManager(sessionsDir)
+	sessionsManager := newSessionManager(cfg, filepath.Join(workspace, "sessions"))
 
 	memoryStore := NewMemoryStore(workspace)
 	contextBuilder := NewContextBuilder(workspace, memoryStore)
@@ -69,6 +88,12 @@ func NewAgentInstance(
 	toolsRegistry.Register(tools.NewMemoryStoreTool(memoryStore))
 	toolsRegistry.Register(tools.NewMemoryDeleteTool(memoryStore))
 
+	var historyCfg config.HistoryToolConfig
+	if cfg != nil {
+		historyCfg = cfg.Tools.History
+	}
+	toolsRegistry.Register(tools.NewHistorySearchTool(sessionsManager.Index(), historyCfg.AllChats))
+
 	agentID := routing.DefaultAgentID
 	agentName := ""
 	var subagents *config.SubagentsConfig
@@ -86,6 +111,11 @@ func NewAgentInstance(
 		maxIter = 20
 	}
 
+	maxParallelTools := defaults.MaxParallelTools
+	if maxParallelTools == 0 {
+		maxParallelTools = 4
+	}
+
 	maxTokens := defaults.MaxTokens
 	if maxTokens == 0 {
 		maxTokens = 8192
@@ -101,6 +131,11 @@ func NewAgentInstance(
 		temperature = *defaults.Temperature
 	}
 
+	reasoningEffort, thinkingBudget := defaults.ReasoningEffort, defaults.ThinkingBudget
+	if agentCfg != nil && (agentCfg.ReasoningEffort != "" || agentCfg.ThinkingBudget > 0) {
+		reasoningEffort, thinkingBudget = agentCfg.ReasoningEffort, agentCfg.ThinkingBudget
+	}
+
 	// Resolve fallback candidates
 	modelCfg := providers.ModelConfig{
 		Primary:   model,
@@ -124,25 +159,140 @@ func NewAgentInstance(
 		}
 	}
 
+	imageCandidates, imageProviders := resolveImageCandidates(cfg, defaults, workspace, agentProvider)
+
 	return &AgentInstance{
-		ID:             agentID,
-		Name:           agentName,
-		Model:          model,
-		Fallbacks:      fallbacks,
-		Workspace:      workspace,
-		MaxIterations:  maxIter,
-		MaxTokens:      maxTokens,
-		Temperature:    temperature,
-		ContextWindow:  contextWindow,
-		SummaryModel:   defaults.SummaryModel,
-		Provider:       agentProvider,
-		Sessions:       sessionsManager,
-		ContextBuilder: contextBuilder,
-		Tools:          toolsRegistry,
-		Subagents:      subagents,
-		SkillsFilter:   skillsFilter,
-		Candidates:     candidates,
+		ID:               agentID,
+		Name:             agentName,
+		Model:            model,
+		Fallbacks:        fallbacks,
+		Workspace:        workspace,
+		MaxIterations:    maxIter,
+		MaxParallelTools: maxParallelTools,
+		MaxTokens:        maxTokens,
+		Temperature:      temperature,
+		ContextWindow:    contextWindow,
+		SummaryModel:     defaults.SummaryModel,
+		ReasoningEffort:  reasoningEffort,
+		ThinkingBudget:   thinkingBudget,
+		Provider:         agentProvider,
+		Sessions:         sessionsManager,
+		ContextBuilder:   contextBuilder,
+		Memory:           memoryStore,
+		Tools:            toolsRegistry,
+		Subagents:        subagents,
+		SkillsFilter:     skillsFilter,
+		Candidates:       candidates,
+		Usage:            usage.NewStore(workspace),
+		Approvals:        approvalPolicy(cfg, workspace),
+
+		ImageCandidates: imageCandidates,
+		imageProviders:  imageProviders,
+	}
+}
+
+// newSessionManager creates the session manager of an agent using the
+// configured storage backend, falling back to JSON files.
+func newSessionManager(cfg *config.Config, dir string) *session.SessionManager {
+	var sessionCfg config.SessionConfig
+	if cfg != nil {
+		sessionCfg = cfg.Session
+	}
+	store, err := session.NewStore(sessionCfg.Backend, dir)
+	if err != nil {
+		logger.WarnCF("agent", "Invalid session backend, using json",
+			map[string]interface{}{"error": err.Error()})
+		store = session.NewJSONStore(dir)
+	}
+	sm := session.NewSessionManagerWithStore(store, sessionCfg.MaxCached)
+	sm.SetIndex(session.NewHistoryIndex(dir, store))
+	return sm
+}
+
+// SessionsDir returns the directory holding the sessions of an agent.
+func SessionsDir(cfg *config.Config, agentID string) string {
+	agentID = routing.NormalizeAgentID(agentID)
+	var agentCfg *config.AgentConfig
+	for i := range cfg.Agents.List {
+		if routing.NormalizeAgentID(cfg.Agents.List[i].ID) == agentID {
+			agentCfg = &cfg.Agents.List[i]
+			break
+		}
+	}
+	return filepath.Join(resolveAgentWorkspace(agentCfg, &cfg.Agents.Defaults), "sessions")
+}
+
+// OpenSessionStore opens the session store of an agent without creating the
+// agent, for tools that work on stored sessions.
+func OpenSessionStore(cfg *config.Config, agentID string) (session.SessionStore, error) {
+	return session.NewStore(cfg.Session.Backend, SessionsDir(cfg, agentID))
+}
+
+// OpenSessionManager opens the sessions of an agent, with their history
+// index, without creating the agent.
+func OpenSessionManager(cfg *config.Config, agentID string) *session.SessionManager {
+	return newSessionManager(cfg, SessionsDir(cfg, agentID))
+}
+
+// ImageProvider returns the provider serving an image candidate.
+func (a *AgentInstance) ImageProvider(candidate providers.FallbackCandidate) providers.LLMProvider {
+	if p, ok := a.imageProviders[providers.ModelKey(candidate.Provider, candidate.Model)]; ok {
+		return p
+	}
+	return a.Provider
+}
+
+// resolveImageCandidates builds the candidate list for image requests from
+// image_model and image_model_fallbacks. Names found in model_list get their
+// own provider; other names are sent to the default provider, like text fallbacks.
+func resolveImageCandidates(cfg *config.Config, defaults *config.AgentDefaults, workspace string, defaultProvider providers.LLMProvider) ([]providers.FallbackCandidate, map[string]providers.LLMProvider) {
+	if defaults.ImageModel == "" {
+		return nil, nil
+	}
+
+	names := append([]string{defaults.ImageModel}, defaults.ImageModelFallbacks...)
+	seen := make(map[string]bool)
+	var candidates []providers.FallbackCandidate
+	providersByKey := make(map[string]providers.LLMProvider)
+
+	for _, name := range names {
+		candidate, provider := resolveImageCandidate(cfg, defaults, workspace, name)
+		if candidate == nil {
+			continue
+		}
+		key := providers.ModelKey(candidate.Provider, candidate.Model)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if provider == nil {
+			provider = defaultProvider
+		}
+		candidates = append(candidates, *candidate)
+		providersByKey[key] = provider
+	}
+
+	return candidates, providersByKey
+}
+
+func resolveImageCandidate(cfg *config.Config, defaults *config.AgentDefaults, workspace, name string) (*providers.FallbackCandidate, providers.LLMProvider) {
+	if cfg != nil {
+		if modelCfg, err := cfg.GetModelConfig(name); err == nil {
+			if modelCfg.Workspace == "" {
+				modelCfg.Workspace = workspace
+			}
+			if p, modelID, err := providers.CreateProviderFromConfig(modelCfg); err == nil {
+				protocol, _ := providers.ExtractProtocol(modelCfg.Model)
+				return &providers.FallbackCandidate{Provider: protocol, Model: modelID}, p
+			}
+		}
+	}
+
+	ref := providers.ParseModelRef(name, defaults.Provider)
+	if ref == nil {
+		return nil, nil
 	}
+	return &providers.FallbackCandidate{Provider: ref.Provider, Model: ref.Model}, nil
 }
 
 // SetupAgentTools holds the function reference for tool registration.
@@ -160,6 +310,14 @@ var (
 	extraToolsMu sync.Mutex
 )
 
+// approvalPolicy builds the tool approval policy for an agent workspace.
+func approvalPolicy(cfg *config.Config, workspace string) *tools.ApprovalPolicy {
+	if cfg == nil {
+		return nil
+	}
+	return tools.NewApprovalPolicy(cfg.Tools.Approval, workspace)
+}
+
 // resolveAgentWorkspace determines the workspace directory for an agent.
 func resolveAgentWorkspace(agentCfg *config.AgentConfig, defaults *config.AgentDefaults) string {
 	if agentCfg != nil && strings.TrimSpace(agentCfg.Workspace) != "" {
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+	"github.com/zhaopengme/mobaiclaw/pkg/routing"
+)
+
+// profileLocaleKeys are the profile entries that may hold the user's language.
+var profileLocaleKeys = []string{"locale", "language"}
+
+// Locale returns the language of built-in messages for a chat with this
+// agent: the language in the user's profile, or else routeLocale, which
+// comes from the binding or channel configuration.
+func (a *AgentInstance) Locale(routeLocale string) string {
+	if a == nil || a.Memory == nil {
+		return i18n.Resolve(routeLocale)
+	}
+	profile := a.Memory.ReadProfile()
+	candidates := make([]string, 0, len(profileLocaleKeys)+1)
+	for _, key := range profileLocaleKeys {
+		candidates = append(candidates, profile[key])
+	}
+	return i18n.Resolve(append(candidates, routeLocale)...)
+}
+
+// Locale returns the language of built-in messages for a route.
+func (r *AgentRegistry) Locale(route routing.ResolvedRoute) string {
+	agent, ok := r.GetAgent(route.AgentID)
+	if !ok {
+		agent = r.GetDefaultAgent()
+	}
+	return agent.Locale(route.Locale)
+}
+
+// LocaleFor returns the language of built-in messages for the chat msg came
+// from. Only the channel and chat ID are needed.
+func (al *AgentLoop) LocaleFor(msg bus.InboundMessage) string {
+	agent, _, route := al.resolveSession(msg)
+	return agent.Locale(route.Locale)
+}
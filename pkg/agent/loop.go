@@ -9,6 +9,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -19,6 +20,7 @@ import (
 	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 	"github.com/zhaopengme/mobaiclaw/pkg/config"
 	"github.com/zhaopengme/mobaiclaw/pkg/constants"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 	"github.com/zhaopengme/mobaiclaw/pkg/routing"
@@ -29,25 +31,56 @@ import (
 )
 
 type AgentLoop struct {
-	bus         bus.Broker
-	cfg         atomic.Value // stores *config.Config
-	registry    *AgentRegistry
-	state       *state.Manager
-	running     atomic.Bool
-	summarizing sync.Map
-	fallback    *providers.FallbackChain
+	bus            bus.Broker
+	cfg            atomic.Value // stores *config.Config
+	registry       *AgentRegistry
+	state          *state.Manager
+	running        atomic.Bool
+	summarizing    sync.Map
+	fallback       *providers.FallbackChain
+	activeTurns    sync.Map     // sessionKey -> *activeTurn
+	streamChannels atomic.Value // stores map[string]bool
+	approvals      sync.Map     // approval ID -> *pendingApproval
+	hooks          hookList
+
+	// dispatcher is set while Run is active, so that SessionBusy can see
+	// messages that are queued but not yet processing.
+	dispatcher atomic.Pointer[sessionDispatcher]
 }
 
+// activeTurn tracks the cancel func of the turn currently running for a session.
+type activeTurn struct {
+	cancel context.CancelCauseFunc
+}
+
+// errStoppedByUser is the cancellation cause recorded when a user issues /stop.
+var errStoppedByUser = errors.New("stopped by user")
+
+// cancelledMarker is stored in the session in place of an interrupted turn.
+const cancelledMarker = "[Cancelled by user]"
+
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string // Session identifier for history/context
-	Channel         string // Target channel for tool execution
-	ChatID          string // Target chat ID for tool execution
-	UserMessage     string // User message content (may include prefix)
-	DefaultResponse string // Response when LLM returns empty
-	EnableSummary   bool   // Whether to trigger summarization
-	SendResponse    bool   // Whether to send response via bus
-	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	SessionKey      string   // Session identifier for history/context
+	Channel         string   // Target channel for tool execution
+	ChatID          string   // Target chat ID for tool execution
+	SenderID        string   // Sender of the user message, if it came from a user
+	UserMessage     string   // User message content (may include prefix)
+	Media           []string // Local paths of media attached to the user message
+	DefaultResponse string   // Response when LLM returns empty
+	EnableSummary   bool     // Whether to trigger summarization
+	SendResponse    bool     // Whether to send response via bus
+	NoHistory       bool     // If true, don't load session history (for heartbeat)
+	Stream          bool     // Whether partial replies may be streamed to the channel
+	Model           string   // model_list name overriding the agent's model for this turn
+	Locale          string   // language of built-in messages shown to the user
+
+	// Reset decides when the session is archived and a new one started.
+	Reset *config.SessionResetConfig
+
+	// Ambient holds the group messages that were not addressed to the bot
+	// since it last was; they prefix the user message sent to the model.
+	Ambient []bus.AmbientMessage
 }
 
 func NewAgentLoop(cfg *config.Config, msgBus bus.Broker, provider providers.LLMProvider) *AgentLoop {
@@ -116,6 +149,19 @@ func setupAgentTools(agentID string, agent *AgentInstance, cfg *config.Config, m
 	})
 	agent.Tools.Register(messageTool)
 
+	// File attachments
+	sendFileTool := tools.NewSendFileTool(agent.Workspace, cfg.Agents.Defaults.RestrictToWorkspace)
+	sendFileTool.SetSendCallback(func(channel, chatID, caption string, media []string) error {
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: caption,
+			Media:   media,
+		})
+		return nil
+	})
+	agent.Tools.Register(sendFileTool)
+
 	// Skill discovery and installation tools
 	registryMgr := skills.NewRegistryManagerFromConfig(skills.RegistryConfig{
 		MaxConcurrentSearches: cfg.Tools.Skills.MaxConcurrentSearches,
@@ -126,8 +172,9 @@ func setupAgentTools(agentID string, agent *AgentInstance, cfg *config.Config, m
 	agent.Tools.Register(tools.NewInstallSkillTool(registryMgr, agent.Workspace))
 
 	// Spawn tool with allowlist checker
-	subagentManager := tools.NewSubagentManager(provider, agent.Model, agent.Workspace, msgBus)
+	subagentManager := tools.NewSubagentManager(newMeteredProvider(cfg, agent, provider), agent.Model, agent.Workspace, msgBus)
 	subagentManager.SetLLMOptions(agent.MaxTokens, agent.Temperature)
+	agent.SubagentMgr = subagentManager
 	spawnTool := tools.NewSpawnTool(subagentManager)
 	spawnTool.SetAllowlistChecker(func(targetAgentID string) bool {
 		return registry.CanSpawnSubagent(agentID, targetAgentID)
@@ -161,6 +208,10 @@ func registerSharedTools(cfg *config.Config, msgBus bus.Broker, registry *AgentR
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
 
+	dispatcher := newSessionDispatcher(al.GetConfig().Agents.Defaults.MaxConcurrentSessions, al.handleInbound)
+	al.dispatcher.Store(dispatcher)
+	defer dispatcher.Wait()
+
 	for al.running.Load() {
 		select {
 		case <-ctx.Done():
@@ -171,43 +222,102 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
-			response, err := al.processMessage(ctx, msg)
-			if err != nil {
-				response = fmt.Sprintf("Error processing message: %v", err)
-			}
-
-			if response != "" {
-				// Check if the message tool already sent a response during this round.
-				// If so, skip publishing to avoid duplicate messages to the user.
-				// Use default agent's tools to check (message tool is shared).
-				alreadySent := false
-				defaultAgent := al.registry.GetDefaultAgent()
-				if defaultAgent != nil {
-					if tool, ok := defaultAgent.Tools.Get("message"); ok {
-						if mt, ok := tool.(*tools.MessageTool); ok {
-							alreadySent = mt.HasSentInRound()
-						}
-					}
-				}
-
-				if !alreadySent {
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel: msg.Channel,
-						ChatID:  msg.ChatID,
-						Content: response,
-					})
-				}
-			}
+			dispatcher.Dispatch(ctx, al.dispatchKey(msg), msg)
 		}
 	}
 
 	return nil
 }
 
+// handleInbound processes one inbound message and publishes the response.
+// It is called by the session dispatcher, possibly concurrently for different sessions.
+func (al *AgentLoop) handleInbound(ctx context.Context, msg bus.InboundMessage) {
+	// Channels hand downloaded media over to the agent; drop it once the turn is done
+	defer utils.RemoveMedia(msg.Media)
+
+	turn := &tools.TurnContext{}
+	ctx = tools.WithTurnContext(ctx, turn)
+
+	response, err := al.processMessage(ctx, msg)
+	if err != nil {
+		response = i18n.T(al.LocaleFor(msg), "agent.error", err)
+	}
+
+	// If the message tool already sent a response during this turn,
+	// skip publishing to avoid duplicate messages to the user.
+	if response != "" && !turn.MessageSent() {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: response,
+		})
+	}
+}
+
+// dispatchKey returns the key used to serialize processing of msg.
+// It matches the session key the message will be processed under.
+func (al *AgentLoop) dispatchKey(msg bus.InboundMessage) string {
+	if msg.Channel == "system" {
+		if agent := al.registry.GetDefaultAgent(); agent != nil {
+			return routing.BuildAgentMainSessionKey(agent.ID)
+		}
+		return "system"
+	}
+	_, sessionKey, _ := al.resolveSession(msg)
+	return sessionKey
+}
+
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
 }
 
+// StopSession cancels the turn currently running for sessionKey, along with
+// any subagents spawned from that session. It returns true if anything was stopped.
+func (al *AgentLoop) StopSession(sessionKey string) bool {
+	stopped := false
+	if v, ok := al.activeTurns.Load(sessionKey); ok {
+		v.(*activeTurn).cancel(errStoppedByUser)
+		stopped = true
+	}
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok || agent.SubagentMgr == nil {
+			continue
+		}
+		if agent.SubagentMgr.CancelSession(sessionKey) > 0 {
+			stopped = true
+		}
+	}
+	if stopped {
+		logger.InfoCF("agent", "Session stopped by user", map[string]interface{}{"session_key": sessionKey})
+	}
+	return stopped
+}
+
+// SessionBusy reports whether a turn is currently running for sessionKey,
+// or messages for it are waiting in the dispatcher.
+func (al *AgentLoop) SessionBusy(sessionKey string) bool {
+	if _, ok := al.activeTurns.Load(sessionKey); ok {
+		return true
+	}
+	if d := al.dispatcher.Load(); d != nil && d.Busy(sessionKey) {
+		return true
+	}
+	return false
+}
+
+// beginTurn registers a cancellable context for the turn running in sessionKey.
+// The returned func must be called when the turn ends.
+func (al *AgentLoop) beginTurn(ctx context.Context, sessionKey string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	turn := &activeTurn{cancel: cancel}
+	al.activeTurns.Store(sessionKey, turn)
+	return ctx, func() {
+		al.activeTurns.CompareAndDelete(sessionKey, turn)
+		cancel(nil)
+	}
+}
+
 // RegisterTool registers a tool on all current agents and stores it
 // so that it is automatically re-registered after a config reload.
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
@@ -275,15 +385,17 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 // Each heartbeat is independent and doesn't accumulate context.
 func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, chatID string) (string, error) {
 	agent := al.registry.GetDefaultAgent()
+	locale := al.LocaleFor(bus.InboundMessage{Channel: channel, ChatID: chatID})
 	return al.runAgentLoop(ctx, agent, processOptions{
 		SessionKey:      "heartbeat",
 		Channel:         channel,
 		ChatID:          chatID,
 		UserMessage:     content,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		DefaultResponse: i18n.T(locale, "agent.no_response"),
 		EnableSummary:   false,
 		SendResponse:    false,
 		NoHistory:       true, // Don't load session history for heartbeat
+		Locale:          locale,
 	})
 }
 
@@ -308,7 +420,36 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return al.processSystemMessage(ctx, msg)
 	}
 
-	// Route to determine agent and session key
+	agent, sessionKey, route := al.resolveSession(msg)
+	locale := agent.Locale(route.Locale)
+
+	logger.InfoCF("agent", "Routed message",
+		map[string]interface{}{
+			"agent_id":    agent.ID,
+			"session_key": sessionKey,
+			"matched_by":  route.MatchedBy,
+		})
+
+	return al.runAgentLoop(ctx, agent, processOptions{
+		SessionKey:      sessionKey,
+		Channel:         msg.Channel,
+		ChatID:          msg.ChatID,
+		SenderID:        msg.SenderID,
+		UserMessage:     msg.Content,
+		Media:           msg.Media,
+		DefaultResponse: i18n.T(locale, "agent.no_response"),
+		EnableSummary:   true,
+		SendResponse:    false,
+		Stream:          true,
+		Model:           msg.Metadata["model"],
+		Locale:          locale,
+		Reset:           route.Reset,
+		Ambient:         msg.Ambient,
+	})
+}
+
+// resolveSession determines the agent, session key and route for an inbound message.
+func (al *AgentLoop) resolveSession(msg bus.InboundMessage) (*AgentInstance, string, routing.ResolvedRoute) {
 	route := al.registry.ResolveRoute(routing.RouteInput{
 		Channel:    msg.Channel,
 		AccountID:  msg.Metadata["account_id"],
@@ -335,22 +476,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		}
 	}
 
-	logger.InfoCF("agent", "Routed message",
-		map[string]interface{}{
-			"agent_id":    agent.ID,
-			"session_key": sessionKey,
-			"matched_by":  route.MatchedBy,
-		})
-
-	return al.runAgentLoop(ctx, agent, processOptions{
-		SessionKey:      sessionKey,
-		Channel:         msg.Channel,
-		ChatID:          msg.ChatID,
-		UserMessage:     msg.Content,
-		DefaultResponse: "I've completed processing but have no response to give.",
-		EnableSummary:   true,
-		SendResponse:    false,
-	})
+	return agent, sessionKey, route
 }
 
 func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
@@ -397,15 +523,17 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 
 	// Use the origin session for context
 	sessionKey := routing.BuildAgentMainSessionKey(agent.ID)
+	locale := al.LocaleFor(bus.InboundMessage{Channel: originChannel, ChatID: originChatID})
 
 	return al.runAgentLoop(ctx, agent, processOptions{
 		SessionKey:      sessionKey,
 		Channel:         originChannel,
 		ChatID:          originChatID,
 		UserMessage:     fmt.Sprintf("[System: %s] %s", msg.SenderID, msg.Content),
-		DefaultResponse: "Background task completed.",
+		DefaultResponse: i18n.T(locale, "agent.background_done"),
 		EnableSummary:   false,
 		SendResponse:    true,
+		Locale:          locale,
 	})
 }
 
@@ -422,8 +550,33 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		}
 	}
 
-	// 1. Update tool contexts
-	al.updateToolContexts(agent, opts.Channel, opts.ChatID, opts.SessionKey)
+	// 1. Make the turn cancellable via /stop and bind the per-turn tool context
+	// (shared tools read it from ctx)
+	ctx, endTurn := al.beginTurn(ctx, opts.SessionKey)
+	defer endTurn()
+
+	turn, ok := tools.TurnContextFrom(ctx)
+	if !ok {
+		turn = &tools.TurnContext{}
+		ctx = tools.WithTurnContext(ctx, turn)
+	}
+	turn.Channel = opts.Channel
+	turn.ChatID = opts.ChatID
+	turn.SessionKey = opts.SessionKey
+
+	// Start a new conversation when the chat's reset policy says so
+	if !opts.NoHistory {
+		al.maybeResetSession(agent, opts)
+	}
+
+	// A bare "continue" after a turn that ran out of iterations resumes its
+	// task, with a fresh iteration budget like any other turn
+	if !opts.NoHistory && agent.Sessions.IsUnfinished(opts.SessionKey) {
+		agent.Sessions.SetUnfinished(opts.SessionKey, false)
+		if isContinueRequest(opts.UserMessage) {
+			opts.UserMessage = continueInstruction
+		}
+	}
 
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
@@ -432,21 +585,30 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		history = agent.Sessions.GetHistory(opts.SessionKey)
 		summary = agent.Sessions.GetSummary(opts.SessionKey)
 	}
+	// Unaddressed group messages are shown to the model with this turn only;
+	// the session keeps what the user actually said
 	messages := agent.ContextBuilder.BuildMessages(
 		history,
 		summary,
-		opts.UserMessage,
-		nil,
+		withAmbientContext(opts.UserMessage, opts.Ambient),
+		opts.Media,
 		opts.Channel,
 		opts.ChatID,
 	)
 
 	// 3. Save user message to session
 	agent.Sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
+	agent.Sessions.SetChat(opts.SessionKey, opts.Channel, opts.ChatID)
+	agent.Sessions.SetAttachments(opts.SessionKey, len(opts.Media) > 0)
+	turnStart := len(agent.Sessions.GetHistory(opts.SessionKey))
 
 	// 4. Run LLM iteration loop
 	finalContent, iteration, err := al.runLLMIteration(ctx, agent, messages, opts)
 	if err != nil {
+		if errors.Is(context.Cause(ctx), errStoppedByUser) {
+			al.recordCancelledTurn(agent, opts.SessionKey, turnStart)
+			return "", nil
+		}
 		return "", err
 	}
 
@@ -464,7 +626,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 
 	// 7. Optional: summarization
 	if opts.EnableSummary {
-		al.maybeSummarize(agent, opts.SessionKey, opts.Channel, opts.ChatID)
+		al.maybeSummarize(agent, opts)
 	}
 
 	// 8. Optional: send response via bus
@@ -489,12 +651,59 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 	return finalContent, nil
 }
 
+// recordCancelledTurn drops the partial tool turns written after the user
+// message and records a cancellation marker so the history stays well-formed.
+func (al *AgentLoop) recordCancelledTurn(agent *AgentInstance, sessionKey string, turnStart int) {
+	history := agent.Sessions.GetHistory(sessionKey)
+	if turnStart <= len(history) {
+		agent.Sessions.SetHistory(sessionKey, history[:turnStart])
+	}
+	agent.Sessions.AddMessage(sessionKey, "assistant", cancelledMarker)
+	agent.Sessions.Save(sessionKey)
+
+	logger.InfoCF("agent", "Turn cancelled by user",
+		map[string]interface{}{
+			"agent_id":    agent.ID,
+			"session_key": sessionKey,
+		})
+}
+
 // runLLMIteration executes the LLM call loop with tool handling.
 func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance, messages []providers.Message, opts processOptions) (string, int, error) {
 	iteration := 0
 	var finalContent string
 
+	// A model override for this turn, or else the chat's /switch model, runs
+	// ahead of the agent's model and fallbacks
+	turnProvider, turnCandidates, turnModel := agent.Provider, agent.Candidates, agent.Model
+	override := opts.Model
+	if override == "" && opts.SessionKey != "" {
+		override = agent.Sessions.GetModel(opts.SessionKey)
+	}
+	if override != "" {
+		if p, c, err := al.resolveModel(agent, override); err == nil {
+			turnProvider, turnCandidates, turnModel = p, c, c[0].Model
+		} else {
+			logger.WarnCF("agent", "Ignoring model override", map[string]interface{}{
+				"agent_id": agent.ID,
+				"model":    override,
+				"error":    err.Error(),
+			})
+		}
+	}
+	providerFor := func(model string) providers.LLMProvider {
+		if model == turnModel {
+			return turnProvider
+		}
+		return agent.Provider
+	}
+	loopGuard := newToolLoopGuard(al.toolLoopThreshold())
+	toolsPending := false // the last iteration ran tools and awaits an answer
+
 	for iteration < agent.MaxIterations {
+		if err := ctx.Err(); err != nil {
+			return "", iteration, err
+		}
 		iteration++
 
 		logger.DebugCF("agent", "LLM iteration",
@@ -528,18 +737,57 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 				"tools_json":    formatToolsForLog(providerToolDefs),
 			})
 
+		provider, candidates, model := turnProvider, turnCandidates, turnModel
+
+		// Enforce the token/cost budget before spending more
+		if reason := al.budgetExceeded(agent, opts.Locale); reason != "" {
+			downgraded, ok := al.downgradeCandidate(agent)
+			if !ok {
+				logger.WarnCF("agent", "Budget exhausted, refusing LLM call",
+					map[string]interface{}{"agent_id": agent.ID, "reason": reason})
+				finalContent = i18n.T(opts.Locale, "agent.budget_exhausted", reason)
+				break
+			}
+			logger.InfoCF("agent", "Budget exhausted, downgrading model",
+				map[string]interface{}{"agent_id": agent.ID, "reason": reason, "model": downgraded.Model})
+			provider, candidates, model = agent.Provider, []providers.FallbackCandidate{downgraded}, downgraded.Model
+		}
+
 		// Call LLM with fallback chain if candidates are configured.
 		var response *providers.LLMResponse
 		var err error
+		usedModel := model
 
 		callLLM := func() (*providers.LLMResponse, error) {
-			if len(agent.Candidates) > 1 && al.fallback != nil {
-				fbResult, fbErr := al.fallback.Execute(ctx, agent.Candidates,
+			// Hooks may rewrite what is sent, without touching the history
+			event := newHookEvent(HookBeforeLLM, agent, iteration, opts)
+			event.Model = model
+			event.Messages = append([]providers.Message(nil), messages...)
+			if veto := al.runHooks(ctx, event); veto != nil {
+				return nil, veto
+			}
+			llmMessages := event.Messages
+
+			// Requests carrying images go to the image model when one is configured
+			if len(agent.ImageCandidates) > 0 && al.fallback != nil && hasImages(llmMessages) {
+				fbResult, fbErr := al.fallback.ExecuteImage(ctx, agent.ImageCandidates,
 					func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
-						return agent.Provider.Chat(ctx, messages, providerToolDefs, model, map[string]interface{}{
-							"max_tokens":  agent.MaxTokens,
-							"temperature": agent.Temperature,
-						})
+						candidate := providers.FallbackCandidate{Provider: provider, Model: model}
+						return al.chat(ctx, agent, agent.ImageProvider(candidate), llmMessages, providerToolDefs, model, opts)
+					},
+				)
+				if fbErr != nil {
+					return nil, fbErr
+				}
+				logger.InfoCF("agent", fmt.Sprintf("Image request served by %s/%s", fbResult.Provider, fbResult.Model),
+					map[string]interface{}{"agent_id": agent.ID, "iteration": iteration, "attempts": len(fbResult.Attempts) + 1})
+				usedModel = fbResult.Model
+				return fbResult.Response, nil
+			}
+			if len(candidates) > 1 && al.fallback != nil {
+				fbResult, fbErr := al.fallback.Execute(ctx, candidates,
+					func(ctx context.Context, _, model string) (*providers.LLMResponse, error) {
+						return al.chat(ctx, agent, providerFor(model), llmMessages, providerToolDefs, model, opts)
 					},
 				)
 				if fbErr != nil {
@@ -550,12 +798,12 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 						fbResult.Provider, fbResult.Model, len(fbResult.Attempts)+1),
 						map[string]interface{}{"agent_id": agent.ID, "iteration": iteration})
 				}
+				if fbResult.Model != "" {
+					usedModel = fbResult.Model
+				}
 				return fbResult.Response, nil
 			}
-			return agent.Provider.Chat(ctx, messages, providerToolDefs, agent.Model, map[string]interface{}{
-				"max_tokens":  agent.MaxTokens,
-				"temperature": agent.Temperature,
-			})
+			return al.chat(ctx, agent, provider, llmMessages, providerToolDefs, model, opts)
 		}
 
 		// Retry loop for context/token errors
@@ -565,7 +813,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 			startTime := time.Now()
 			response, err = callLLM()
 			llmDuration = time.Since(startTime)
-			if err == nil {
+			if err == nil || ctx.Err() != nil || errors.As(err, new(*HookVeto)) {
 				break
 			}
 
@@ -585,7 +833,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 					al.bus.PublishOutbound(bus.OutboundMessage{
 						Channel: opts.Channel,
 						ChatID:  opts.ChatID,
-						Content: "Context window exceeded. Compressing history and retrying...",
+						Content: i18n.T(opts.Locale, "agent.compressing"),
 					})
 				}
 
@@ -601,6 +849,11 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 			break
 		}
 
+		var veto *HookVeto
+		if errors.As(err, &veto) {
+			finalContent = i18n.T(opts.Locale, "agent.request_blocked", veto.Reason)
+			break
+		}
 		if err != nil {
 			logger.ErrorCF("agent", "LLM call failed",
 				map[string]interface{}{
@@ -612,8 +865,20 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
+		al.recordUsage(agent, opts.SessionKey, usedModel, response)
+
+		event := newHookEvent(HookAfterLLM, agent, iteration, opts)
+		event.Model = usedModel
+		event.Response = response
+		if veto := al.runHooks(ctx, event); veto != nil {
+			finalContent = i18n.T(opts.Locale, "agent.response_blocked", veto.Reason)
+			break
+		}
+		response = event.Response
+
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
+			toolsPending = false
 			finalContent = response.Content
 			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
 				map[string]interface{}{
@@ -649,6 +914,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 			Role:             "assistant",
 			Content:          response.Content,
 			ReasoningContent: response.ReasoningContent,
+			ThinkingBlocks:   response.ThinkingBlocks,
 		}
 		for _, tc := range normalizedToolCalls {
 			argumentsJSON, _ := json.Marshal(tc.Arguments)
@@ -684,7 +950,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 				toolNamesDisplay = append(toolNamesDisplay, formatToolCallDisplay(tc))
 			}
 
-			statusMsg := fmt.Sprintf("⚙️ 正在执行: %s...", strings.Join(toolNamesDisplay, ", "))
+			statusMsg := i18n.T(opts.Locale, "agent.running", strings.Join(toolNamesDisplay, ", "))
 			al.bus.PublishOutbound(bus.OutboundMessage{
 				Channel:  opts.Channel,
 				ChatID:   opts.ChatID,
@@ -693,122 +959,49 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, agent *AgentInstance,
 			})
 		}
 
-		// Execute tool calls
-		for _, tc := range normalizedToolCalls {
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
-				map[string]interface{}{
-					"agent_id":  agent.ID,
-					"tool":      tc.Name,
-					"iteration": iteration,
-				})
-
-			// Create async callback for tools that implement AsyncTool
-			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
-			// Instead, they notify the agent via PublishInbound, and the agent decides
-			// whether to forward the result to the user (in processSystemMessage).
-			asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
-				// Log the async completion but don't send directly to user
-				// The agent will handle user notification via processSystemMessage
-				if !result.Silent && result.ForUser != "" {
-					logger.InfoCF("agent", "Async tool completed, agent will handle notification",
-						map[string]interface{}{
-							"tool":        tc.Name,
-							"content_len": len(result.ForUser),
-						})
-				}
-			}
-
-			// Create progress callback with debouncing
-			lastUpdate := time.Now()
-			var mu sync.Mutex
-
-			progressCallback := func(content string) {
-				if constants.IsInternalChannel(opts.Channel) {
-					return
-				}
-
-				mu.Lock()
-				defer mu.Unlock()
-
-				// Debounce: max 1 update every 2 seconds to avoid Telegram rate limits
-				if time.Since(lastUpdate) > 2*time.Second {
-					statusMsg := fmt.Sprintf("⚙️ 正在执行: %s...\n\n<pre>%s</pre>", formatToolCallDisplay(tc), content)
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel:  opts.Channel,
-						ChatID:   opts.ChatID,
-						Content:  statusMsg,
-						Metadata: map[string]string{"status_update": "true"},
-					})
-					lastUpdate = time.Now()
-				}
-			}
-
-			toolResult := agent.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, opts.SessionKey, asyncCallback, progressCallback)
-
-			// Send ForUser content to user immediately if not Silent
-			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Content: toolResult.ForUser,
-				})
-				logger.DebugCF("agent", "Sent tool result to user",
-					map[string]interface{}{
-						"tool":        tc.Name,
-						"content_len": len(toolResult.ForUser),
-					})
-			}
-
-			// Determine content for LLM based on tool result
-			contentForLLM := toolResult.ForLLM
-			if contentForLLM == "" && toolResult.Err != nil {
-				contentForLLM = toolResult.Err.Error()
-			}
-
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    contentForLLM,
-				ToolCallID: tc.ID,
-			}
+		// Execute tool calls; results are appended in the original call order
+		toolResults, err := al.executeToolCalls(ctx, agent, normalizedToolCalls, iteration, opts)
+		if err != nil {
+			return "", iteration, err
+		}
+		for _, toolResultMsg := range toolResults {
 			messages = append(messages, toolResultMsg)
 
 			// Save tool result message to session
 			agent.Sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
 		}
-	}
-
-	return finalContent, iteration, nil
-}
-
-// updateToolContexts updates the context for tools that need channel/chatID/sessionKey info.
-func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID, sessionKey string) {
-	// Use ContextualTool interface instead of type assertions
-	if tool, ok := agent.Tools.Get("message"); ok {
-		if mt, ok := tool.(tools.ContextualTool); ok {
-			mt.SetContext(channel, chatID, sessionKey)
-		}
-	}
-	if tool, ok := agent.Tools.Get("spawn"); ok {
-		if st, ok := tool.(tools.ContextualTool); ok {
-			st.SetContext(channel, chatID, sessionKey)
-		}
-	}
-	if tool, ok := agent.Tools.Get("subagent"); ok {
-		if st, ok := tool.(tools.ContextualTool); ok {
-			st.SetContext(channel, chatID, sessionKey)
+		toolsPending = true
+
+		// Break out of repetitive tool-call loops
+		verdict := loopGuard.observe(normalizedToolCalls, toolResults)
+		if verdict == loopWarn {
+			logger.WarnCF("agent", "Repetitive tool calls detected, warning the LLM",
+				map[string]interface{}{"agent_id": agent.ID, "iteration": iteration, "tools": toolNames})
+			messages = append(messages, providers.Message{Role: "system", Content: toolLoopNote})
+		} else if verdict == loopStop {
+			logger.WarnCF("agent", "Repetitive tool calls continued, ending the turn",
+				map[string]interface{}{"agent_id": agent.ID, "iteration": iteration, "tools": toolNames})
+			finalContent = loopGuard.summary(opts.Locale)
+			break
 		}
 	}
-	if tool, ok := agent.Tools.Get("cron"); ok {
-		if ct, ok := tool.(tools.ContextualTool); ok {
-			ct.SetContext(channel, chatID, sessionKey)
+
+	// Out of iterations mid-task: report progress instead of going silent
+	if toolsPending && finalContent == "" && iteration >= agent.MaxIterations && ctx.Err() == nil {
+		logger.InfoCF("agent", "Iteration limit reached, wrapping up",
+			map[string]interface{}{"agent_id": agent.ID, "iterations": iteration})
+		finalContent = al.wrapUp(ctx, agent, turnProvider, messages, agent.Tools.ToProviderDefs(), turnModel, opts)
+		if !opts.NoHistory {
+			agent.Sessions.SetUnfinished(opts.SessionKey, true)
 		}
 	}
+
+	return finalContent, iteration, nil
 }
 
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
-func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, chatID string) {
+func (al *AgentLoop) maybeSummarize(agent *AgentInstance, opts processOptions) {
+	sessionKey, channel, chatID := opts.SessionKey, opts.Channel, opts.ChatID
 	newHistory := agent.Sessions.GetHistory(sessionKey)
 	tokenEstimate := al.estimateTokens(newHistory)
 	threshold := agent.ContextWindow * 75 / 100
@@ -822,7 +1015,7 @@ func (al *AgentLoop) maybeSummarize(agent *AgentInstance, sessionKey, channel, c
 					al.bus.PublishOutbound(bus.OutboundMessage{
 						Channel: channel,
 						ChatID:  chatID,
-						Content: "Memory threshold reached. Optimizing conversation history...",
+						Content: i18n.T(opts.Locale, "agent.summarizing"),
 					})
 				}
 				al.summarizeSession(agent, sessionKey)
@@ -964,6 +1157,8 @@ func formatToolsForLog(tools []providers.ToolDefinition) string {
 func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
+	// Summaries count against the session's usage like its turns
+	ctx = tools.WithTurnContext(ctx, tools.NewTurnContext("", "", sessionKey))
 
 	history := agent.Sessions.GetHistory(sessionKey)
 	summary := agent.Sessions.GetSummary(sessionKey)
@@ -1031,7 +1226,7 @@ func (al *AgentLoop) summarizeSession(agent *AgentInstance, sessionKey string) {
 		if summaryModel == "" {
 			summaryModel = agent.Model
 		}
-		resp, err := agent.Provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, summaryModel, map[string]interface{}{
+		resp, err := newMeteredProvider(al.GetConfig(), agent, agent.Provider).Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, summaryModel, map[string]interface{}{
 			"max_tokens":  1024,
 			"temperature": 0.3,
 		})
@@ -1097,7 +1292,7 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, agent *AgentInstance, b
 	if summaryModel == "" {
 		summaryModel = agent.Model
 	}
-	response, err := agent.Provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, summaryModel, map[string]interface{}{
+	response, err := newMeteredProvider(al.GetConfig(), agent, agent.Provider).Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, summaryModel, map[string]interface{}{
 		"max_tokens":  1024,
 		"temperature": 0.3,
 	})
@@ -797,3 +797,90 @@ func TestOmittedNotePreservesJSON(t *testing.T) {
 	}
 }
 
+
+// blockingToolCallProvider issues one tool call, then blocks until ctx is cancelled.
+type blockingToolCallProvider struct {
+	calls   int
+	blocked chan struct{}
+}
+
+func (p *blockingToolCallProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	if p.calls == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{{
+				ID:        "call_1",
+				Type:      "function",
+				Name:      "list_dir",
+				Arguments: map[string]interface{}{"path": "."},
+			}},
+		}, nil
+	}
+	close(p.blocked)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *blockingToolCallProvider) GetDefaultModel() string {
+	return "blocking-model"
+}
+
+func TestAgentLoop_StopSessionCancelsTurn(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	provider := &blockingToolCallProvider{blocked: make(chan struct{})}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	sessionKey := "agent:main:stop-test"
+
+	type result struct {
+		response string
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := al.ProcessDirectWithChannel(context.Background(), "do something slow", sessionKey, "cli", "direct")
+		done <- result{resp, err}
+	}()
+
+	select {
+	case <-provider.blocked:
+	case <-time.After(responseTimeout):
+		t.Fatal("Provider was never called a second time")
+	}
+
+	if !al.StopSession(sessionKey) {
+		t.Fatal("Expected StopSession to report a running turn")
+	}
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(responseTimeout):
+		t.Fatal("Turn did not stop after cancellation")
+	}
+	if res.err != nil || res.response != "" {
+		t.Errorf("Expected empty response and no error, got %q, %v", res.response, res.err)
+	}
+
+	history := al.registry.GetDefaultAgent().Sessions.GetHistory(sessionKey)
+	if len(history) != 2 {
+		t.Fatalf("Expected user message and cancel marker only, got %d messages", len(history))
+	}
+	if history[0].Role != "user" || history[1].Role != "assistant" || history[1].Content != cancelledMarker {
+		t.Errorf("Unexpected history after cancel: %+v", history)
+	}
+
+	if al.StopSession(sessionKey) {
+		t.Error("Expected no running turn after cancellation")
+	}
+}
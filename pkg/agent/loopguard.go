@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+const defaultToolLoopThreshold = 3
+
+// toolLoopNote is shown to the LLM when it starts repeating itself.
+const toolLoopNote = "You are repeating the same tool calls with the same arguments and getting the same results. " +
+	"Repeating them again will not help. Use the results you already have, try a different approach, " +
+	"or answer the user and explain what is blocking you."
+
+type loopVerdict int
+
+const (
+	loopNone loopVerdict = iota
+	loopWarn             // a loop started: tell the LLM
+	loopStop             // the loop went on after the warning: end the turn
+)
+
+// toolLoopGuard watches the tool calls of one turn for an LLM that keeps
+// making the same calls, or keeps cycling through the same two or three
+// sets of calls, across iterations.
+type toolLoopGuard struct {
+	threshold int      // identical rounds that make a loop
+	rounds    []string // signature of each iteration's calls
+	looping   []string // signatures of the loop the LLM was warned about
+	attempts  []toolAttempt
+}
+
+// toolAttempt is a distinct call made during the turn, for the summary.
+type toolAttempt struct {
+	call   string
+	count  int
+	result string
+}
+
+func newToolLoopGuard(threshold int) *toolLoopGuard {
+	return &toolLoopGuard{threshold: threshold}
+}
+
+// observe records the calls of an iteration and their results, and reports
+// whether the LLM is looping.
+func (g *toolLoopGuard) observe(calls []providers.ToolCall, results []providers.Message) loopVerdict {
+	if g == nil || g.threshold <= 0 {
+		return loopNone
+	}
+
+	sigs := make([]string, len(calls))
+	for i, tc := range calls {
+		sigs[i] = callSignature(tc)
+		result := ""
+		if i < len(results) {
+			result = results[i].Content
+		}
+		g.attempt(sigs[i], result)
+	}
+	slices.Sort(sigs)
+	round := strings.Join(sigs, "\n")
+	g.rounds = append(g.rounds, round)
+
+	if g.looping != nil {
+		if slices.Contains(g.looping, round) {
+			return loopStop
+		}
+		// The LLM moved on; watch for a new loop from here
+		g.looping = nil
+		g.rounds = []string{round}
+		return loopNone
+	}
+
+	if period := g.loopPeriod(); period > 0 {
+		g.looping = slices.Clone(g.rounds[len(g.rounds)-period:])
+		return loopWarn
+	}
+	return loopNone
+}
+
+// loopPeriod returns the length of the cycle the latest rounds repeat, or 0.
+// One set of calls must repeat threshold times; cycles of two or three sets
+// must repeat twice.
+func (g *toolLoopGuard) loopPeriod() int {
+	n := len(g.rounds)
+	if n >= g.threshold && g.threshold > 1 && repeats(g.rounds[n-g.threshold:], 1) {
+		return 1
+	}
+	for period := 2; period <= 3; period++ {
+		if n >= 2*period && repeats(g.rounds[n-2*period:], period) && !repeats(g.rounds[n-period:], 1) {
+			return period
+		}
+	}
+	return 0
+}
+
+// repeats reports whether rounds is one block of length period repeated.
+func repeats(rounds []string, period int) bool {
+	for i := period; i < len(rounds); i++ {
+		if rounds[i] != rounds[i-period] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *toolLoopGuard) attempt(call, result string) {
+	for i := range g.attempts {
+		if g.attempts[i].call == call {
+			g.attempts[i].count++
+			g.attempts[i].result = result
+			return
+		}
+	}
+	g.attempts = append(g.attempts, toolAttempt{call: call, count: 1, result: result})
+}
+
+// summary tells the user, in locale, that the turn was stopped and what was tried.
+func (g *toolLoopGuard) summary(locale string) string {
+	const maxAttempts = 10
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(locale, "agent.loop_stopped"))
+	for i, a := range g.attempts {
+		if i == maxAttempts {
+			sb.WriteString("\n- " + i18n.T(locale, "agent.loop_more", len(g.attempts)-maxAttempts))
+			break
+		}
+		fmt.Fprintf(&sb, "\n- %s", utils.Truncate(a.call, 120))
+		if a.count > 1 {
+			fmt.Fprintf(&sb, " ×%d", a.count)
+		}
+		if a.result != "" {
+			fmt.Fprintf(&sb, " → %s", utils.Truncate(strings.Join(strings.Fields(a.result), " "), 120))
+		}
+	}
+	sb.WriteString("\n\n" + i18n.T(locale, "agent.loop_hint"))
+	return sb.String()
+}
+
+// callSignature identifies a call by its tool and arguments. Map keys are
+// marshalled in sorted order, so equal arguments give equal signatures.
+func callSignature(tc providers.ToolCall) string {
+	args, _ := json.Marshal(tc.Arguments)
+	return tc.Name + "(" + string(args) + ")"
+}
+
+func (al *AgentLoop) toolLoopThreshold() int {
+	if cfg := al.GetConfig(); cfg != nil && cfg.Agents.Defaults.ToolLoopThreshold != 0 {
+		return cfg.Agents.Defaults.ToolLoopThreshold
+	}
+	return defaultToolLoopThreshold
+}
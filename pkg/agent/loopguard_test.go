@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+func loopCall(label string) []providers.ToolCall {
+	return []providers.ToolCall{{Name: "sleep", Arguments: map[string]interface{}{"label": label}}}
+}
+
+func loopResult(content string) []providers.Message {
+	return []providers.Message{{Role: "tool", Content: content}}
+}
+
+func TestToolLoopGuard_IdenticalCalls(t *testing.T) {
+	g := newToolLoopGuard(3)
+	want := []loopVerdict{loopNone, loopNone, loopWarn, loopStop}
+	for i, w := range want {
+		if got := g.observe(loopCall("a"), loopResult("same")); got != w {
+			t.Fatalf("round %d: verdict %d, want %d", i+1, got, w)
+		}
+	}
+	summary := g.summary("")
+	if !strings.Contains(summary, `sleep({"label":"a"}) ×4 → same`) {
+		t.Errorf("summary does not list the repeated call: %q", summary)
+	}
+}
+
+func TestToolLoopGuard_Oscillation(t *testing.T) {
+	g := newToolLoopGuard(3)
+	for i, label := range []string{"a", "b", "a"} {
+		if got := g.observe(loopCall(label), nil); got != loopNone {
+			t.Fatalf("round %d: verdict %d, want none", i+1, got)
+		}
+	}
+	if got := g.observe(loopCall("b"), nil); got != loopWarn {
+		t.Fatalf("a b a b: verdict %d, want warn", got)
+	}
+	if got := g.observe(loopCall("a"), nil); got != loopStop {
+		t.Errorf("continuing the cycle: verdict %d, want stop", got)
+	}
+}
+
+func TestToolLoopGuard_RecoversAfterWarning(t *testing.T) {
+	g := newToolLoopGuard(2)
+	g.observe(loopCall("a"), nil)
+	if got := g.observe(loopCall("a"), nil); got != loopWarn {
+		t.Fatalf("verdict %d, want warn", got)
+	}
+	if got := g.observe(loopCall("c"), nil); got != loopNone {
+		t.Errorf("new call after warning: verdict %d, want none", got)
+	}
+	if got := g.observe(loopCall("a"), nil); got != loopNone {
+		t.Errorf("old call once after recovering: verdict %d, want none", got)
+	}
+}
+
+func TestToolLoopGuard_DistinctCallsAndDisabled(t *testing.T) {
+	g := newToolLoopGuard(3)
+	for _, label := range []string{"a", "b", "c", "d", "e", "f"} {
+		if got := g.observe(loopCall(label), nil); got != loopNone {
+			t.Fatalf("distinct call %s flagged as a loop", label)
+		}
+	}
+
+	disabled := newToolLoopGuard(-1)
+	for i := 0; i < 5; i++ {
+		if got := disabled.observe(loopCall("a"), nil); got != loopNone {
+			t.Fatalf("disabled guard flagged a loop")
+		}
+	}
+}
+
+// stuckProvider calls the same tool forever and records the system notes it sees.
+type stuckProvider struct {
+	calls int
+	notes int
+}
+
+func (p *stuckProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	p.notes = 0
+	for _, m := range messages {
+		if m.Role == "system" && m.Content == toolLoopNote {
+			p.notes++
+		}
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+		ID:        "call_1",
+		Type:      "function",
+		Name:      "sleep",
+		Arguments: map[string]interface{}{"label": "poll"},
+	}}}, nil
+}
+
+func (p *stuckProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_BreaksToolLoop(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 20,
+			},
+		},
+	}
+	provider := &stuckProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	al.RegisterTool(&sleepTool{name: "sleep"})
+
+	response, err := al.ProcessDirect(context.Background(), "is it done yet?", "agent:main:loop")
+	if err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+	if provider.calls != 4 {
+		t.Errorf("LLM called %d times, want 4 (three repeats, one after the warning)", provider.calls)
+	}
+	if provider.notes != 1 {
+		t.Errorf("last request carried %d loop notes, want 1", provider.notes)
+	}
+	if !strings.Contains(response, "kept repeating") || !strings.Contains(response, "×4") {
+		t.Errorf("unexpected response: %q", response)
+	}
+}
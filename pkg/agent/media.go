@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"encoding/base64"
+	"net/http"
+	"os"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// maxImageBytes is the largest image passed to a model; most vision APIs
+// reject anything above ~5MB.
+const maxImageBytes = 5 << 20
+
+// buildContentParts turns a user message and its downloaded media into
+// multimodal content parts. Only image files are attached; other media
+// (voice, documents) are left to the text content. Returns nil when the
+// message carries no usable image.
+func buildContentParts(text string, media []string) []providers.ContentPart {
+	var images []providers.ContentPart
+	for _, path := range media {
+		part, ok := loadImagePart(path)
+		if ok {
+			images = append(images, part)
+		}
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	parts := make([]providers.ContentPart, 0, len(images)+1)
+	if text != "" {
+		parts = append(parts, providers.ContentPart{Type: "text", Text: text})
+	}
+	return append(parts, images...)
+}
+
+func loadImagePart(path string) (providers.ContentPart, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return providers.ContentPart{}, false
+	}
+	if info.Size() > maxImageBytes {
+		logger.WarnCF("agent", "Skipping oversized image", map[string]interface{}{
+			"path": path,
+			"size": info.Size(),
+		})
+		return providers.ContentPart{}, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to read image", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+		return providers.ContentPart{}, false
+	}
+
+	mediaType := http.DetectContentType(data)
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return providers.ContentPart{}, false
+	}
+
+	return providers.ContentPart{
+		Type:      "image",
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}, true
+}
+
+// hasImages reports whether any message in the request carries an image.
+func hasImages(messages []providers.Message) bool {
+	for _, msg := range messages {
+		if msg.HasImages() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// 1x1 transparent PNG
+var testPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4, 0x89, 0x00, 0x00, 0x00,
+	0x0a, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00,
+	0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00, 0x00, 0x00, 0x00, 0x49,
+	0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestBuildContentParts_AttachesImagesOnly(t *testing.T) {
+	image := writeTestFile(t, "photo.png", testPNG)
+	voice := writeTestFile(t, "voice.ogg", []byte("OggS not an image"))
+
+	parts := buildContentParts("what is this?", []string{image, voice, "/nonexistent.png"})
+	if len(parts) != 2 {
+		t.Fatalf("expected text + 1 image part, got %d", len(parts))
+	}
+	if parts[0].Type != "text" || parts[0].Text != "what is this?" {
+		t.Errorf("unexpected text part: %+v", parts[0])
+	}
+	if parts[1].Type != "image" || parts[1].MediaType != "image/png" || parts[1].Data == "" {
+		t.Errorf("unexpected image part: %+v", parts[1])
+	}
+
+	if parts := buildContentParts("hello", []string{voice}); parts != nil {
+		t.Errorf("expected no parts without images, got %+v", parts)
+	}
+}
+
+type recordingProvider struct {
+	models    []string
+	hadImages []bool
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.models = append(p.models, model)
+	p.hadImages = append(p.hadImages, hasImages(messages))
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *recordingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_RoutesImagesToImageModel(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "text-model",
+				ImageModel:        "vision-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &recordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	helper := testHelper{al: al}
+
+	image := writeTestFile(t, "photo.png", testPNG)
+	imageMsg := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "[image: photo]",
+		Media:    []string{image},
+	}
+	helper.executeAndGetResponse(t, context.Background(), imageMsg)
+	agent, sessionKey, _ := al.resolveSession(imageMsg)
+	if !agent.Sessions.HasAttachments(sessionKey) {
+		t.Error("the image turn should be marked as having attachments")
+	}
+	helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "and now plain text",
+	})
+	if agent.Sessions.HasAttachments(sessionKey) {
+		t.Error("the text turn should clear the attachments mark")
+	}
+
+	if len(provider.models) != 2 {
+		t.Fatalf("expected 2 LLM calls, got %d", len(provider.models))
+	}
+	if provider.models[0] != "vision-model" || !provider.hadImages[0] {
+		t.Errorf("image turn: model=%q images=%v, want vision-model with images", provider.models[0], provider.hadImages[0])
+	}
+	if provider.models[1] != "text-model" || provider.hadImages[1] {
+		t.Errorf("text turn: model=%q images=%v, want text-model without images", provider.models[1], provider.hadImages[1])
+	}
+}
@@ -13,11 +13,14 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
 )
 
 // MemoryStore manages persistent memory for the agent.
 // - Long-term memory: memory/profile.json
 // - Daily notes: memory/YYYYMM/YYYYMMDD.md
+// Files are encrypted at rest when workspace encryption is enabled.
 type MemoryStore struct {
 	mu          sync.RWMutex
 	workspace   string
@@ -60,7 +63,7 @@ func (ms *MemoryStore) ReadProfile() map[string]string {
 	defer ms.mu.RUnlock()
 
 	profile := make(map[string]string)
-	data, err := os.ReadFile(ms.profileFile)
+	data, err := crypt.ReadFile(ms.profileFile)
 	if err == nil && len(data) > 0 {
 		_ = json.Unmarshal(data, &profile)
 	}
@@ -73,8 +76,11 @@ func (ms *MemoryStore) WriteProfileKey(key, value string) error {
 	defer ms.mu.Unlock()
 
 	profile := make(map[string]string)
-	data, err := os.ReadFile(ms.profileFile)
-	if err == nil && len(data) > 0 {
+	data, err := crypt.ReadFile(ms.profileFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read profile.json: %w", err)
+	}
+	if len(data) > 0 {
 		if err := json.Unmarshal(data, &profile); err != nil {
 			return fmt.Errorf("failed to parse profile.json (file might be corrupted): %w", err)
 		}
@@ -86,7 +92,7 @@ func (ms *MemoryStore) WriteProfileKey(key, value string) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal profile data: %w", err)
 	}
-	return os.WriteFile(ms.profileFile, newData, 0644)
+	return crypt.WriteFile(ms.profileFile, newData, 0600)
 }
 
 // DeleteProfileKey safely removes a key from the profile.
@@ -95,8 +101,11 @@ func (ms *MemoryStore) DeleteProfileKey(key string) error {
 	defer ms.mu.Unlock()
 
 	profile := make(map[string]string)
-	data, err := os.ReadFile(ms.profileFile)
-	if err == nil && len(data) > 0 {
+	data, err := crypt.ReadFile(ms.profileFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read profile.json: %w", err)
+	}
+	if len(data) > 0 {
 		if err := json.Unmarshal(data, &profile); err != nil {
 			return fmt.Errorf("failed to parse profile.json (file might be corrupted): %w", err)
 		}
@@ -112,14 +121,14 @@ func (ms *MemoryStore) DeleteProfileKey(key string) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal profile data: %w", err)
 	}
-	return os.WriteFile(ms.profileFile, newData, 0644)
+	return crypt.WriteFile(ms.profileFile, newData, 0600)
 }
 
 // ReadToday reads today's daily note.
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadToday() string {
 	todayFile := ms.getTodayFile()
-	if data, err := os.ReadFile(todayFile); err == nil {
+	if data, err := crypt.ReadFile(todayFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -134,10 +143,11 @@ func (ms *MemoryStore) AppendToday(content string) error {
 	monthDir := filepath.Dir(todayFile)
 	os.MkdirAll(monthDir, 0755)
 
-	var existingContent string
-	if data, err := os.ReadFile(todayFile); err == nil {
-		existingContent = string(data)
+	data, err := crypt.ReadFile(todayFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read daily note: %w", err)
 	}
+	existingContent := string(data)
 
 	var newContent string
 	if existingContent == "" {
@@ -149,7 +159,7 @@ func (ms *MemoryStore) AppendToday(content string) error {
 		newContent = existingContent + "\n" + content
 	}
 
-	return os.WriteFile(todayFile, []byte(newContent), 0644)
+	return crypt.WriteFile(todayFile, []byte(newContent), 0600)
 }
 
 // GetRecentDailyNotes returns daily notes from the last N days.
@@ -163,7 +173,7 @@ func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
 		monthDir := dateStr[:6]            // YYYYMM
 		filePath := filepath.Join(ms.memoryDir, monthDir, dateStr+".md")
 
-		if data, err := os.ReadFile(filePath); err == nil {
+		if data, err := crypt.ReadFile(filePath); err == nil {
 			notes = append(notes, string(data))
 		}
 	}
@@ -1,10 +1,13 @@
 package agent
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
 )
 
 func TestProfileReadWrite(t *testing.T) {
@@ -117,3 +120,36 @@ func TestNewMemoryStore_AutoMigrates(t *testing.T) {
 		t.Errorf("NewMemoryStore did not auto-migrate USER.md")
 	}
 }
+
+func TestProfile_EncryptedAtRest(t *testing.T) {
+	if err := crypt.Enable(bytes.Repeat([]byte{5}, crypt.KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	defer crypt.Enable(nil)
+
+	tempDir := t.TempDir()
+	ms := NewMemoryStore(tempDir)
+	if err := ms.WriteProfileKey("passport", "E12345678"); err != nil {
+		t.Fatalf("WriteProfileKey: %v", err)
+	}
+	if err := ms.AppendToday("met Alice at the station"); err != nil {
+		t.Fatalf("AppendToday: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(tempDir, "memory", "profile.json"))
+	if strings.Contains(string(data), "E12345678") {
+		t.Errorf("profile.json holds plaintext: %q", data)
+	}
+	if got := ms.ReadProfile()["passport"]; got != "E12345678" {
+		t.Errorf("profile after reload = %q", got)
+	}
+	if got := ms.ReadToday(); !strings.Contains(got, "Alice") {
+		t.Errorf("daily note = %q", got)
+	}
+
+	// Without the key, updates fail instead of replacing the profile.
+	crypt.Enable(nil)
+	if err := ms.WriteProfileKey("other", "x"); err == nil {
+		t.Error("writing the profile without the key should fail")
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// modelProvider is a provider created for a model_list entry.
+type modelProvider struct {
+	provider providers.LLMProvider
+	modelID  string
+}
+
+// resolveModel returns the provider for a model_list entry used in place of
+// the agent's own model, and the fallback candidates for a turn on it: the
+// override first, then the agent's own candidates, which keep running on the
+// agent's provider.
+func (al *AgentLoop) resolveModel(agent *AgentInstance, name string) (providers.LLMProvider, []providers.FallbackCandidate, error) {
+	cfg := al.GetConfig()
+	if cfg == nil {
+		return nil, nil, fmt.Errorf("no config loaded")
+	}
+	modelCfg, err := cfg.GetModelConfig(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if modelCfg.Workspace == "" {
+		modelCfg.Workspace = agent.Workspace
+	}
+	provider, modelID, err := agent.providerFor(*modelCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create provider for model %q: %w", name, err)
+	}
+	protocol, _ := providers.ExtractProtocol(modelCfg.Model)
+
+	candidates := []providers.FallbackCandidate{{Provider: protocol, Model: modelID}}
+	for _, c := range agent.Candidates {
+		if c.Model != modelID {
+			candidates = append(candidates, c)
+		}
+	}
+	return provider, candidates, nil
+}
+
+// providerFor returns the provider of a model_list entry, creating it on
+// first use.
+func (a *AgentInstance) providerFor(modelCfg config.ModelConfig) (providers.LLMProvider, string, error) {
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	if cached, ok := a.modelProviders[modelCfg]; ok {
+		return cached.provider, cached.modelID, nil
+	}
+	provider, modelID, err := providers.CreateProviderFromConfig(&modelCfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if a.modelProviders == nil {
+		a.modelProviders = make(map[config.ModelConfig]modelProvider)
+	}
+	a.modelProviders[modelCfg] = modelProvider{provider: provider, modelID: modelID}
+	return provider, modelID, nil
+}
+
+// reasoningFor returns the reasoning effort or thinking budget for a turn in
+// a session. A /think override replaces the agent's configured effort and
+// budget; otherwise an explicit budget wins over the effort level.
+func reasoningFor(agent *AgentInstance, sessionKey string) (effort string, budget int) {
+	if sessionKey != "" && agent.Sessions != nil {
+		if override := agent.Sessions.GetThinking(sessionKey); override != "" {
+			return override, 0
+		}
+	}
+	if agent.ThinkingBudget > 0 {
+		return "", agent.ThinkingBudget
+	}
+	return agent.ReasoningEffort, 0
+}
@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+func TestAgentLoop_HonorsTurnModelOverride(t *testing.T) {
+	var requestedModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requestedModel, _ = body["model"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"from override"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "default-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "other", Model: "openai/other-model", APIKey: "sk-test", APIBase: server.URL},
+		},
+	}
+	provider := &recordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hi",
+		Metadata: map[string]string{"model": "other"},
+	})
+
+	if response != "from override" || requestedModel != "other-model" {
+		t.Errorf("response %q from model %q, want the override model", response, requestedModel)
+	}
+	if len(provider.models) != 0 {
+		t.Errorf("default provider was called with %v", provider.models)
+	}
+}
+
+func TestResolveModel_KeepsAgentFallbacks(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace: t.TempDir(),
+				Model:     "default-model",
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "other", Model: "openai/other-model", APIKey: "sk-test"},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	agent := al.GetRegistry().GetDefaultAgent()
+	agent.Candidates = []providers.FallbackCandidate{
+		{Provider: "openai", Model: "default-model"},
+		{Provider: "openai", Model: "other-model"},
+		{Provider: "openai", Model: "backup-model"},
+	}
+
+	_, candidates, err := al.resolveModel(agent, "other")
+	if err != nil {
+		t.Fatalf("resolveModel: %v", err)
+	}
+	var models []string
+	for _, c := range candidates {
+		models = append(models, c.Model)
+	}
+	if strings.Join(models, ",") != "other-model,default-model,backup-model" {
+		t.Errorf("candidates = %v", models)
+	}
+	first, _, _ := al.resolveModel(agent, "other")
+	second, _, _ := al.resolveModel(agent, "other")
+	if first == nil || first != second {
+		t.Errorf("providers = %p, %p, want one provider reused across turns", first, second)
+	}
+}
+
+func TestAgentLoop_HonorsSessionModel(t *testing.T) {
+	var requestedModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requestedModel, _ = body["model"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"from session model"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "default-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "other", Model: "openai/other-model", APIKey: "sk-test", APIBase: server.URL},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &recordingProvider{})
+	agent := al.GetRegistry().GetDefaultAgent()
+	agent.Sessions.SetModel("agent:main:main", "other")
+
+	response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hi",
+	})
+	if response != "from session model" || requestedModel != "other-model" {
+		t.Errorf("response %q from model %q, want the session model", response, requestedModel)
+	}
+}
+
+func TestReasoningFor_SessionOverridesAgent(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:      t.TempDir(),
+				Model:          "default-model",
+				ThinkingBudget: 5000,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	agent := al.GetRegistry().GetDefaultAgent()
+
+	if effort, budget := reasoningFor(agent, "s1"); effort != "" || budget != 5000 {
+		t.Errorf("reasoningFor = %q, %d, want the agent budget", effort, budget)
+	}
+
+	agent.Sessions.SetThinking("s1", "high")
+	if effort, budget := reasoningFor(agent, "s1"); effort != "high" || budget != 0 {
+		t.Errorf("reasoningFor = %q, %d, want the /think override", effort, budget)
+	}
+	if _, budget := reasoningFor(agent, "s2"); budget != 5000 {
+		t.Errorf("other sessions should keep the agent budget, got %d", budget)
+	}
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+)
+
+// resetNote starts the history of a session that was reset, so the model
+// does not expect it to remember the earlier conversation.
+const resetNote = "A new conversation started at %s because %s. " +
+	"The earlier conversation was archived and its messages are no longer available%s. " +
+	"Treat the next message as the start of a new conversation."
+
+// resetReason reports why a session last active at last must be reset at
+// now under policy, or "" when it may continue.
+func resetReason(policy *config.SessionResetConfig, last, now time.Time) string {
+	if policy == nil || last.IsZero() {
+		return ""
+	}
+	if policy.IdleMinutes > 0 {
+		idle := time.Duration(policy.IdleMinutes) * time.Minute
+		if now.Sub(last) >= idle {
+			return fmt.Sprintf("the chat was idle for more than %s", idle)
+		}
+	}
+	if policy.DailyAt != "" {
+		at, err := time.Parse("15:04", policy.DailyAt)
+		if err != nil {
+			logger.WarnCF("agent", "Invalid session daily_at, expected HH:MM",
+				map[string]interface{}{"daily_at": policy.DailyAt})
+			return ""
+		}
+		boundary := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+		if boundary.After(now) {
+			boundary = boundary.AddDate(0, 0, -1)
+		}
+		if last.Before(boundary) {
+			return fmt.Sprintf("of the daily reset at %s", policy.DailyAt)
+		}
+	}
+	return ""
+}
+
+// maybeResetSession archives the session of a turn and starts a new one
+// when the reset policy of its route says the conversation is over.
+func (al *AgentLoop) maybeResetSession(agent *AgentInstance, opts processOptions) {
+	policy := opts.Reset
+	if policy == nil {
+		return
+	}
+	snap := agent.Sessions.Snapshot(opts.SessionKey)
+	if snap == nil || len(snap.Messages) == 0 {
+		return
+	}
+	now := time.Now()
+	reason := resetReason(policy, snap.Updated, now)
+	if reason == "" {
+		return
+	}
+
+	carried := ""
+	if policy.CarrySummary && snap.Summary != "" {
+		carried = " except for the summary above"
+	}
+	note := fmt.Sprintf(resetNote, now.Format("2006-01-02 15:04 (Monday)"), reason, carried)
+	if err := agent.Sessions.Reset(opts.SessionKey, policy.CarrySummary, note); err != nil {
+		logger.WarnCF("agent", "Failed to archive session on reset",
+			map[string]interface{}{
+				"session_key": opts.SessionKey,
+				"error":       err.Error(),
+			})
+		return
+	}
+	logger.InfoCF("agent", "Session reset",
+		map[string]interface{}{
+			"agent_id":      agent.ID,
+			"session_key":   opts.SessionKey,
+			"reason":        reason,
+			"carry_summary": policy.CarrySummary,
+		})
+}
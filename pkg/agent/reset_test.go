@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+func TestResetReason(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		policy *config.SessionResetConfig
+		last   time.Time
+		reset  bool
+	}{
+		{"no policy", nil, now.Add(-48 * time.Hour), false},
+		{"idle", &config.SessionResetConfig{IdleMinutes: 60}, now.Add(-61 * time.Minute), true},
+		{"recent", &config.SessionResetConfig{IdleMinutes: 60}, now.Add(-59 * time.Minute), false},
+		{"daily passed", &config.SessionResetConfig{DailyAt: "04:00"}, now.Add(-6 * time.Hour), true},
+		{"daily after boundary", &config.SessionResetConfig{DailyAt: "04:00"}, now.Add(-5 * time.Hour), false},
+		{"daily later today", &config.SessionResetConfig{DailyAt: "22:00"}, now.Add(-10 * time.Hour), false},
+		{"daily yesterday", &config.SessionResetConfig{DailyAt: "22:00"}, now.Add(-12 * time.Hour), true},
+		{"invalid daily", &config.SessionResetConfig{DailyAt: "4am"}, now.Add(-48 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := resetReason(tt.policy, tt.last, now) != ""; got != tt.reset {
+			t.Errorf("%s: reset = %v, want %v", tt.name, got, tt.reset)
+		}
+	}
+}
+
+// promptRecorder answers "ok" and keeps the messages of the last call.
+type promptRecorder struct {
+	messages []providers.Message
+}
+
+func (p *promptRecorder) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.messages = messages
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *promptRecorder) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_ResetsIdleSession(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace: workspace,
+				Model:     "test-model",
+				MaxTokens: 4096,
+			},
+		},
+		Session: config.SessionConfig{
+			ResetChannels: map[string]config.SessionResetConfig{
+				"cli": {IdleMinutes: 30, CarrySummary: true},
+			},
+		},
+	}
+	provider := &promptRecorder{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	sessions := al.registry.GetDefaultAgent().Sessions
+	const key = "agent:main:reset"
+
+	if _, err := al.ProcessDirect(context.Background(), "plan my trip", key); err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+	sessions.SetSummary(key, "Planning a trip to Kyoto.")
+	sessions.GetOrCreate(key).Updated = time.Now().Add(-time.Hour)
+
+	if _, err := al.ProcessDirect(context.Background(), "what's the weather?", key); err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+
+	history := sessions.GetHistory(key)
+	if len(history) != 3 || history[0].Role != "system" || !strings.Contains(history[0].Content, "new conversation") {
+		t.Fatalf("history after reset = %+v", history)
+	}
+	if got := sessions.GetSummary(key); got != "Planning a trip to Kyoto." {
+		t.Errorf("summary not carried forward: %q", got)
+	}
+	for _, m := range provider.messages {
+		if strings.Contains(m.Content, "plan my trip") {
+			t.Error("the model still saw the archived conversation")
+		}
+	}
+
+	archived, _ := filepath.Glob(filepath.Join(workspace, "sessions", "archive", "agent_main_reset-*.json"))
+	if len(archived) != 1 {
+		t.Fatalf("archives = %v, want one", archived)
+	}
+	data, _ := os.ReadFile(archived[0])
+	if !strings.Contains(string(data), "plan my trip") {
+		t.Errorf("archive lacks the old conversation:\n%s", data)
+	}
+}
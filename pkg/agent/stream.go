@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/constants"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// streamFlushInterval is the minimum delay between two partial updates of a
+// streamed reply, to stay within the edit rate limits of chat platforms.
+const streamFlushInterval = time.Second
+
+// SetStreamingChannels sets the channels that can edit a message in place.
+// Replies to these channels are streamed when the provider supports it;
+// every other channel only receives the final text.
+func (al *AgentLoop) SetStreamingChannels(names []string) {
+	channels := make(map[string]bool, len(names))
+	for _, name := range names {
+		channels[name] = true
+	}
+	al.streamChannels.Store(channels)
+}
+
+func (al *AgentLoop) canStream(channel string) bool {
+	if constants.IsInternalChannel(channel) {
+		return false
+	}
+	channels, _ := al.streamChannels.Load().(map[string]bool)
+	return channels[channel]
+}
+
+// chat sends one request to provider, streaming partial text to the chat
+// when both the provider and the target channel support it and no after-LLM
+// hook needs to see the reply first.
+func (al *AgentLoop) chat(ctx context.Context, agent *AgentInstance, provider providers.LLMProvider, messages []providers.Message, toolDefs []providers.ToolDefinition, model string, opts processOptions) (*providers.LLMResponse, error) {
+	options := map[string]interface{}{
+		"max_tokens":  agent.MaxTokens,
+		"temperature": agent.Temperature,
+	}
+	effort, budget := reasoningFor(agent, opts.SessionKey)
+	if effort != "" {
+		options[providers.OptionReasoningEffort] = effort
+	} else if budget > 0 {
+		options[providers.OptionThinkingBudget] = budget
+	}
+
+	// After-LLM hooks may veto or rewrite the reply, so it cannot be shown
+	// before they have run
+	sp, ok := provider.(providers.StreamingProvider)
+	if !ok || !opts.Stream || !al.canStream(opts.Channel) || al.hasHooks(HookAfterLLM) {
+		return provider.Chat(ctx, messages, toolDefs, model, options)
+	}
+
+	w := &streamWriter{bus: al.bus, channel: opts.Channel, chatID: opts.ChatID}
+	resp, err := sp.ChatStream(ctx, messages, toolDefs, model, options, w.Write)
+	if err != nil {
+		return nil, err
+	}
+	// Text preceding tool calls would otherwise be cut at the last flush.
+	// A final answer needs no flush: the reply message replaces it anyway.
+	if len(resp.ToolCalls) > 0 {
+		w.Flush()
+	}
+	return resp, nil
+}
+
+// streamWriter accumulates streamed text and publishes it as debounced
+// partial updates flagged with the "stream_update" metadata.
+type streamWriter struct {
+	bus       bus.Broker
+	channel   string
+	chatID    string
+	buf       strings.Builder
+	published int
+	lastFlush time.Time
+}
+
+func (w *streamWriter) Write(delta string) {
+	w.buf.WriteString(delta)
+	if time.Since(w.lastFlush) >= streamFlushInterval {
+		w.Flush()
+	}
+}
+
+// Flush publishes the accumulated text if it changed since the last update.
+func (w *streamWriter) Flush() {
+	if w.buf.Len() == w.published || strings.TrimSpace(w.buf.String()) == "" {
+		return
+	}
+	w.bus.PublishOutbound(bus.OutboundMessage{
+		Channel:  w.channel,
+		ChatID:   w.chatID,
+		Content:  w.buf.String(),
+		Metadata: map[string]string{"stream_update": "true"},
+	})
+	w.published = w.buf.Len()
+	w.lastFlush = time.Now()
+}
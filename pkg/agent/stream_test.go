@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+type streamingMockProvider struct {
+	streamed bool
+}
+
+func (m *streamingMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	return &providers.LLMResponse{Content: "Hello"}, nil
+}
+
+func (m *streamingMockProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}, onDelta providers.StreamCallback) (*providers.LLMResponse, error) {
+	m.streamed = true
+	onDelta("Hel")
+	onDelta("lo")
+	return &providers.LLMResponse{Content: "Hello"}, nil
+}
+
+func (m *streamingMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func newStreamTestLoop(t *testing.T, provider providers.LLMProvider) (*AgentLoop, *bus.MessageBus) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	return NewAgentLoop(cfg, msgBus, provider), msgBus
+}
+
+func TestAgentLoop_StreamsToEditableChannel(t *testing.T) {
+	provider := &streamingMockProvider{}
+	al, msgBus := newStreamTestLoop(t, provider)
+	al.SetStreamingChannels([]string{"telegram"})
+
+	helper := testHelper{al: al}
+	response := helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hi",
+	})
+	if response != "Hello" {
+		t.Fatalf("response = %q, want %q", response, "Hello")
+	}
+	if !provider.streamed {
+		t.Fatal("expected ChatStream to be used")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	msg, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected a partial update on the bus")
+	}
+	if msg.Metadata["stream_update"] != "true" || msg.Content != "Hel" {
+		t.Fatalf("unexpected partial update: %+v", msg)
+	}
+}
+
+func TestAgentLoop_DoesNotStreamToOtherChannels(t *testing.T) {
+	provider := &streamingMockProvider{}
+	al, _ := newStreamTestLoop(t, provider)
+	al.SetStreamingChannels([]string{"telegram"})
+
+	helper := testHelper{al: al}
+	response := helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "line",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hi",
+	})
+	if response != "Hello" {
+		t.Fatalf("response = %q, want %q", response, "Hello")
+	}
+	if provider.streamed {
+		t.Fatal("expected Chat to be used for a channel that cannot edit messages")
+	}
+}
+
+func TestAgentLoop_DoesNotStreamPastAfterLLMHooks(t *testing.T) {
+	provider := &streamingMockProvider{}
+	al, msgBus := newStreamTestLoop(t, provider)
+	al.SetStreamingChannels([]string{"telegram"})
+	al.AddHook("censor", func(ctx context.Context, event *HookEvent) error {
+		return &HookVeto{Reason: "not allowed"}
+	}, HookAfterLLM)
+
+	helper := testHelper{al: al}
+	response := helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "user1",
+		ChatID:   "chat1",
+		Content:  "hi",
+	})
+	if response == "Hello" {
+		t.Fatalf("vetoed response was returned: %q", response)
+	}
+	if provider.streamed {
+		t.Fatal("expected Chat to be used while an after-LLM hook is registered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	for {
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok {
+			break
+		}
+		if msg.Metadata["stream_update"] == "true" {
+			t.Fatalf("vetoed text was streamed: %+v", msg)
+		}
+	}
+}
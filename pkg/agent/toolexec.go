@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/constants"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/tools"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+// executeToolCalls runs the tool calls of one LLM iteration and returns their
+// result messages in call order. Consecutive calls to parallel-safe tools run
+// concurrently, at most agent.MaxParallelTools at a time; calls to tools that
+// implement tools.SequentialTool run on their own, in order.
+func (al *AgentLoop) executeToolCalls(ctx context.Context, agent *AgentInstance, calls []providers.ToolCall, iteration int, opts processOptions) ([]providers.Message, error) {
+	results := make([]providers.Message, len(calls))
+	limit := agent.MaxParallelTools
+
+	for start := 0; start < len(calls); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		end := start + 1
+		if limit > 1 && !agent.Tools.IsSequential(calls[start].Name) {
+			for end < len(calls) && !agent.Tools.IsSequential(calls[end].Name) {
+				end++
+			}
+		}
+
+		if end-start == 1 {
+			results[start] = al.executeToolCall(ctx, agent, calls[start], iteration, opts)
+		} else {
+			logger.DebugCF("agent", "Running tool calls in parallel",
+				map[string]interface{}{
+					"agent_id":  agent.ID,
+					"count":     end - start,
+					"limit":     limit,
+					"iteration": iteration,
+				})
+
+			sem := make(chan struct{}, limit)
+			var wg sync.WaitGroup
+			for i := start; i < end; i++ {
+				wg.Add(1)
+				sem <- struct{}{}
+				go func(i int) {
+					defer wg.Done()
+					defer func() { <-sem }()
+					results[i] = al.executeToolCall(ctx, agent, calls[i], iteration, opts)
+				}(i)
+			}
+			wg.Wait()
+		}
+		start = end
+	}
+
+	return results, nil
+}
+
+// executeToolCall runs a single tool call and returns its tool result message.
+func (al *AgentLoop) executeToolCall(ctx context.Context, agent *AgentInstance, tc providers.ToolCall, iteration int, opts processOptions) providers.Message {
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	argsPreview := utils.Truncate(string(argsJSON), 200)
+	logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+		map[string]interface{}{
+			"agent_id":  agent.ID,
+			"tool":      tc.Name,
+			"iteration": iteration,
+		})
+
+	// Create async callback for tools that implement AsyncTool
+	// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
+	// Instead, they notify the agent via PublishInbound, and the agent decides
+	// whether to forward the result to the user (in processSystemMessage).
+	asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
+		// Log the async completion but don't send directly to user
+		// The agent will handle user notification via processSystemMessage
+		if !result.Silent && result.ForUser != "" {
+			logger.InfoCF("agent", "Async tool completed, agent will handle notification",
+				map[string]interface{}{
+					"tool":        tc.Name,
+					"content_len": len(result.ForUser),
+				})
+		}
+	}
+
+	// Create progress callback with debouncing
+	lastUpdate := time.Now()
+	var mu sync.Mutex
+
+	progressCallback := func(content string) {
+		if constants.IsInternalChannel(opts.Channel) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Debounce: max 1 update every 2 seconds to avoid Telegram rate limits
+		if time.Since(lastUpdate) > 2*time.Second {
+			statusMsg := i18n.T(opts.Locale, "agent.running", formatToolCallDisplay(tc)) + "\n\n<pre>" + content + "</pre>"
+			al.bus.PublishOutbound(bus.OutboundMessage{
+				Channel:  opts.Channel,
+				ChatID:   opts.ChatID,
+				Content:  statusMsg,
+				Metadata: map[string]string{"status_update": "true"},
+			})
+			lastUpdate = time.Now()
+		}
+	}
+
+	before := newHookEvent(HookBeforeTool, agent, iteration, opts)
+	before.ToolCall = &tc
+	if veto := al.runHooks(ctx, before); veto != nil {
+		return providers.Message{
+			Role:       "tool",
+			Content:    fmt.Sprintf("Tool call %s was blocked: %s. It was not executed.", tc.Name, veto.Reason),
+			ToolCallID: tc.ID,
+		}
+	}
+
+	if agent.Approvals.RequiresApproval(tc.Name, tc.Arguments) {
+		if reason := al.awaitApproval(ctx, agent, tc, opts); reason != "" {
+			return providers.Message{Role: "tool", Content: reason, ToolCallID: tc.ID}
+		}
+	}
+
+	toolResult := agent.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, opts.SessionKey, asyncCallback, progressCallback)
+
+	// Send ForUser content to user immediately if not Silent
+	if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Content: toolResult.ForUser,
+		})
+		logger.DebugCF("agent", "Sent tool result to user",
+			map[string]interface{}{
+				"tool":        tc.Name,
+				"content_len": len(toolResult.ForUser),
+			})
+	}
+
+	// Determine content for LLM based on tool result
+	contentForLLM := toolResult.ForLLM
+	if contentForLLM == "" && toolResult.Err != nil {
+		contentForLLM = toolResult.Err.Error()
+	}
+
+	after := newHookEvent(HookAfterTool, agent, iteration, opts)
+	after.ToolCall = &tc
+	after.ToolResult = &HookToolResult{Content: contentForLLM, IsError: toolResult.IsError}
+	if veto := al.runHooks(ctx, after); veto != nil {
+		contentForLLM = fmt.Sprintf("The result of %s was withheld: %s", tc.Name, veto.Reason)
+	} else if after.ToolResult != nil {
+		contentForLLM = after.ToolResult.Content
+	}
+
+	toolResultMsg := providers.Message{
+		Role:       "tool",
+		Content:    contentForLLM,
+		ToolCallID: tc.ID,
+	}
+	return toolResultMsg
+}
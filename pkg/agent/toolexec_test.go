@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/tools"
+)
+
+// sleepTool sleeps for the requested milliseconds and tracks how many calls overlap.
+type sleepTool struct {
+	name       string
+	sequential bool
+	running    atomic.Int32
+	maxRunning atomic.Int32
+}
+
+func (t *sleepTool) Name() string        { return t.name }
+func (t *sleepTool) Description() string { return "sleeps" }
+func (t *sleepTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (t *sleepTool) Sequential() bool { return t.sequential }
+
+func (t *sleepTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	n := t.running.Add(1)
+	defer t.running.Add(-1)
+	for {
+		max := t.maxRunning.Load()
+		if n <= max || t.maxRunning.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	ms, _ := args["ms"].(float64)
+	time.Sleep(time.Duration(ms) * time.Millisecond)
+	label, _ := args["label"].(string)
+	return tools.NewToolResult(label)
+}
+
+// multiToolCallProvider requests several calls to one tool, then records the
+// tool results it receives.
+type multiToolCallProvider struct {
+	tool    string
+	mu      sync.Mutex
+	calls   int
+	results []providers.Message
+}
+
+func (p *multiToolCallProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls == 1 {
+		var calls []providers.ToolCall
+		for i, label := range []string{"a", "b", "c"} {
+			calls = append(calls, providers.ToolCall{
+				ID:        "call_" + label,
+				Type:      "function",
+				Name:      p.tool,
+				Arguments: map[string]interface{}{"label": label, "ms": float64(150 - 50*i)},
+			})
+		}
+		return &providers.LLMResponse{ToolCalls: calls}, nil
+	}
+	for _, m := range messages {
+		if m.Role == "tool" {
+			p.results = append(p.results, m)
+		}
+	}
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (p *multiToolCallProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func runMultiToolTurn(t *testing.T, tool *sleepTool, maxParallel int) *multiToolCallProvider {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				MaxParallelTools:  maxParallel,
+			},
+		},
+	}
+	provider := &multiToolCallProvider{tool: tool.name}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	al.RegisterTool(tool)
+
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "go", "agent:main:parallel", "cli", "direct"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel failed: %v", err)
+	}
+
+	if len(provider.results) != 3 {
+		t.Fatalf("expected 3 tool results, got %d", len(provider.results))
+	}
+	for i, label := range []string{"a", "b", "c"} {
+		if got := provider.results[i]; got.ToolCallID != "call_"+label || got.Content != label {
+			t.Errorf("result %d = %s/%q, want call_%s/%q", i, got.ToolCallID, got.Content, label, label)
+		}
+	}
+	return provider
+}
+
+func TestAgentLoop_RunsToolCallsInParallel(t *testing.T) {
+	tool := &sleepTool{name: "sleep"}
+	runMultiToolTurn(t, tool, 4)
+	if got := tool.maxRunning.Load(); got < 2 {
+		t.Errorf("max concurrent calls = %d, want parallel execution", got)
+	}
+}
+
+func TestAgentLoop_ParallelismCap(t *testing.T) {
+	tool := &sleepTool{name: "sleep"}
+	runMultiToolTurn(t, tool, 1)
+	if got := tool.maxRunning.Load(); got != 1 {
+		t.Errorf("max concurrent calls = %d, want 1", got)
+	}
+}
+
+func TestAgentLoop_SequentialToolsOptOut(t *testing.T) {
+	tool := &sleepTool{name: "sleep", sequential: true}
+	runMultiToolTurn(t, tool, 4)
+	if got := tool.maxRunning.Load(); got != 1 {
+		t.Errorf("max concurrent calls = %d, want 1 for a sequential tool", got)
+	}
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/tools"
+	"github.com/zhaopengme/mobaiclaw/pkg/usage"
+)
+
+// recordUsage adds the token usage reported for one LLM call to the agent's usage store.
+func (al *AgentLoop) recordUsage(agent *AgentInstance, sessionKey, model string, resp *providers.LLMResponse) {
+	recordUsage(al.GetConfig(), agent, sessionKey, model, resp)
+}
+
+func recordUsage(cfg *config.Config, agent *AgentInstance, sessionKey, model string, resp *providers.LLMResponse) {
+	if agent.Usage == nil || resp == nil || resp.Usage == nil {
+		return
+	}
+	u := resp.Usage
+	if u.CacheReadTokens > 0 || u.CacheWriteTokens > 0 {
+		logger.DebugCF("agent", "Prompt cache usage", map[string]interface{}{
+			"agent_id":      agent.ID,
+			"model":         model,
+			"prompt_tokens": u.PromptTokens,
+			"cache_read":    u.CacheReadTokens,
+			"cache_write":   u.CacheWriteTokens,
+		})
+	}
+	cost := usageCost(cfg, model, u.PromptTokens, u.CompletionTokens)
+	if err := agent.Usage.Add(agent.ID, sessionKey, model, u.PromptTokens, u.CompletionTokens, u.TotalTokens, cost); err != nil {
+		logger.WarnCF("agent", "Failed to save token usage", map[string]interface{}{
+			"agent_id": agent.ID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// usageCost prices a call using the model_list prices of model.
+func usageCost(cfg *config.Config, model string, promptTokens, completionTokens int) float64 {
+	if cfg == nil {
+		return 0
+	}
+	input, output := cfg.GetModelPrice(model)
+	return (float64(promptTokens)*input + float64(completionTokens)*output) / 1e6
+}
+
+// budgetExceeded reports which of the configured budgets the agent has used
+// up, in locale, or "" when it may keep spending.
+func (al *AgentLoop) budgetExceeded(agent *AgentInstance, locale string) string {
+	return budgetExceeded(al.GetConfig(), agent, locale)
+}
+
+func budgetExceeded(cfg *config.Config, agent *AgentInstance, locale string) string {
+	budget := budgetOf(cfg)
+	if budget == nil || agent.Usage == nil {
+		return ""
+	}
+
+	now := agent.Usage.Now()
+	day := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, Period: usage.Today(now)})
+	month := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, Period: usage.ThisMonth(now)})
+
+	switch {
+	case budget.DailyTokens > 0 && day.TotalTokens >= budget.DailyTokens:
+		return i18n.T(locale, "budget.daily_tokens", budget.DailyTokens)
+	case budget.MonthlyTokens > 0 && month.TotalTokens >= budget.MonthlyTokens:
+		return i18n.T(locale, "budget.monthly_tokens", budget.MonthlyTokens)
+	case budget.DailyCost > 0 && day.Cost >= budget.DailyCost:
+		return i18n.T(locale, "budget.daily_cost", budget.DailyCost)
+	case budget.MonthlyCost > 0 && month.Cost >= budget.MonthlyCost:
+		return i18n.T(locale, "budget.monthly_cost", budget.MonthlyCost)
+	}
+	return ""
+}
+
+func budgetOf(cfg *config.Config) *config.BudgetConfig {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Agents.Defaults.Budget
+}
+
+// downgradeCandidate picks the model an over-budget agent continues on when
+// on_exceeded is "downgrade": the cheapest priced fallback, or the last
+// fallback when none has a price.
+func (al *AgentLoop) downgradeCandidate(agent *AgentInstance) (providers.FallbackCandidate, bool) {
+	return downgradeCandidate(al.GetConfig(), agent)
+}
+
+func downgradeCandidate(cfg *config.Config, agent *AgentInstance) (providers.FallbackCandidate, bool) {
+	budget := budgetOf(cfg)
+	if budget == nil || budget.OnExceeded != "downgrade" || len(agent.Candidates) < 2 {
+		return providers.FallbackCandidate{}, false
+	}
+
+	fallbacks := agent.Candidates[1:]
+	best := fallbacks[len(fallbacks)-1]
+	bestPrice := -1.0
+	for _, c := range fallbacks {
+		input, output := cfg.GetModelPrice(c.Model)
+		if input == 0 && output == 0 {
+			continue
+		}
+		if price := input + output; bestPrice < 0 || price < bestPrice {
+			best, bestPrice = c, price
+		}
+	}
+	return best, true
+}
+
+// meteredProvider makes the LLM calls of an agent that happen outside its
+// main loop, such as subagent runs and summaries. Like the main loop, it
+// records their usage against the session of the turn in ctx, and holds them
+// to the agent's budget.
+type meteredProvider struct {
+	cfg   *config.Config
+	agent *AgentInstance
+	inner providers.LLMProvider
+}
+
+func newMeteredProvider(cfg *config.Config, agent *AgentInstance, inner providers.LLMProvider) *meteredProvider {
+	return &meteredProvider{cfg: cfg, agent: agent, inner: inner}
+}
+
+func (p *meteredProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	if reason := budgetExceeded(p.cfg, p.agent, i18n.Default); reason != "" {
+		downgraded, ok := downgradeCandidate(p.cfg, p.agent)
+		if !ok {
+			return nil, fmt.Errorf("usage budget exhausted: %s", reason)
+		}
+		model = downgraded.Model
+	}
+
+	resp, err := p.inner.Chat(ctx, messages, defs, model, options)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey := ""
+	if tc, ok := tools.TurnContextFrom(ctx); ok {
+		sessionKey = tc.SessionKey
+	}
+	recordUsage(p.cfg, p.agent, sessionKey, model, resp)
+	return resp, nil
+}
+
+func (p *meteredProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// UsageReport formats the token usage of a session and its agent for the
+// given period: "today" (default), "month" or "all", in locale.
+func (al *AgentLoop) UsageReport(agent *AgentInstance, sessionKey, period, locale string) string {
+	if agent == nil || agent.Usage == nil {
+		return i18n.T(locale, "usage.unavailable")
+	}
+
+	now := agent.Usage.Now()
+	var prefix, label string
+	switch period {
+	case "", "today":
+		prefix, label = usage.Today(now), i18n.T(locale, "usage.today")
+	case "month":
+		prefix, label = usage.ThisMonth(now), i18n.T(locale, "usage.month")
+	case "all":
+		prefix, label = "", i18n.T(locale, "usage.all")
+	default:
+		return i18n.T(locale, "usage.usage")
+	}
+
+	session := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, SessionKey: sessionKey, Period: prefix})
+	total := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, Period: prefix})
+	byModel := agent.Usage.ByModel(usage.Filter{AgentID: agent.ID, Period: prefix})
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(locale, "usage.title", label) + "\n")
+	sb.WriteString(i18n.T(locale, "usage.chat", formatTotals(session, locale)) + "\n")
+	sb.WriteString(i18n.T(locale, "usage.agent", agent.ID, formatTotals(total, locale)))
+	if len(byModel) > 0 {
+		sb.WriteString("\n\n" + i18n.T(locale, "usage.by_model"))
+		for _, model := range usage.Models(byModel) {
+			fmt.Fprintf(&sb, "\n- %s: %s", model, formatTotals(byModel[model], locale))
+		}
+	}
+
+	if budget := budgetOf(al.GetConfig()); budget != nil {
+		day := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, Period: usage.Today(now)})
+		month := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, Period: usage.ThisMonth(now)})
+		var limits []string
+		if budget.DailyTokens > 0 {
+			limits = append(limits, i18n.T(locale, "usage.day_tokens", day.TotalTokens, budget.DailyTokens))
+		}
+		if budget.MonthlyTokens > 0 {
+			limits = append(limits, i18n.T(locale, "usage.month_tokens", month.TotalTokens, budget.MonthlyTokens))
+		}
+		if budget.DailyCost > 0 {
+			limits = append(limits, i18n.T(locale, "usage.day_cost", day.Cost, budget.DailyCost))
+		}
+		if budget.MonthlyCost > 0 {
+			limits = append(limits, i18n.T(locale, "usage.month_cost", month.Cost, budget.MonthlyCost))
+		}
+		if len(limits) > 0 {
+			sb.WriteString("\n\n" + i18n.T(locale, "usage.budget", strings.Join(limits, ", ")))
+		}
+	}
+	return sb.String()
+}
+
+func formatTotals(t usage.Totals, locale string) string {
+	s := i18n.T(locale, "usage.totals", t.TotalTokens, t.PromptTokens, t.CompletionTokens, t.Requests)
+	if t.Cost > 0 {
+		s += fmt.Sprintf(", $%.4f", t.Cost)
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/tools"
+	"github.com/zhaopengme/mobaiclaw/pkg/usage"
+)
+
+// usageProvider reports fixed token usage and records the models it was called with.
+type usageProvider struct {
+	models []string
+}
+
+func (p *usageProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.models = append(p.models, model)
+	return &providers.LLMResponse{
+		Content: "ok",
+		Usage:   &providers.UsageInfo{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	}, nil
+}
+
+func (p *usageProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func newUsageTestLoop(t *testing.T, budget *config.BudgetConfig) (*AgentLoop, *usageProvider) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "big",
+				ModelFallbacks:    []string{"small", "tiny"},
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				Budget:            budget,
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "big", Model: "openai/big", InputPrice: 10, OutputPrice: 30},
+			{ModelName: "small", Model: "openai/small", InputPrice: 1, OutputPrice: 2},
+			{ModelName: "tiny", Model: "openai/tiny", InputPrice: 3, OutputPrice: 3},
+		},
+	}
+	provider := &usageProvider{}
+	return NewAgentLoop(cfg, bus.NewMessageBus(), provider), provider
+}
+
+func TestAgentLoop_RecordsUsage(t *testing.T) {
+	al, _ := newUsageTestLoop(t, nil)
+	helper := testHelper{al: al}
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "hi"}
+
+	helper.executeAndGetResponse(t, context.Background(), msg)
+	helper.executeAndGetResponse(t, context.Background(), msg)
+
+	agent := al.GetRegistry().GetDefaultAgent()
+	totals := agent.Usage.Totals(usage.Filter{AgentID: agent.ID})
+	if totals.Requests != 2 || totals.TotalTokens != 240 {
+		t.Fatalf("totals = %+v, want 2 requests / 240 tokens", totals)
+	}
+	wantCost := 2 * (100*10.0 + 20*30.0) / 1e6
+	if diff := totals.Cost - wantCost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("cost = %v, want %v", totals.Cost, wantCost)
+	}
+
+	// Reloading the store from disk keeps the totals
+	reloaded := usage.NewStore(agent.Workspace)
+	if got := reloaded.Totals(usage.Filter{AgentID: agent.ID}); got.TotalTokens != 240 {
+		t.Errorf("reloaded totals = %+v, want 240 tokens", got)
+	}
+
+	report := al.UsageReport(agent, "", "today", "en")
+	if !strings.Contains(report, "big") || !strings.Contains(report, "240 tokens") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestAgentLoop_BudgetRefuses(t *testing.T) {
+	al, provider := newUsageTestLoop(t, &config.BudgetConfig{DailyTokens: 100})
+	helper := testHelper{al: al}
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "hi"}
+
+	helper.executeAndGetResponse(t, context.Background(), msg)
+	response := helper.executeAndGetResponse(t, context.Background(), msg)
+
+	if len(provider.models) != 1 {
+		t.Fatalf("expected 1 LLM call before the budget stopped the agent, got %d", len(provider.models))
+	}
+	if !strings.Contains(response, "budget") {
+		t.Errorf("expected budget notice, got %q", response)
+	}
+}
+
+func TestAgentLoop_BudgetDowngradesToCheapestFallback(t *testing.T) {
+	al, provider := newUsageTestLoop(t, &config.BudgetConfig{DailyTokens: 100, OnExceeded: "downgrade"})
+	helper := testHelper{al: al}
+	msg := bus.InboundMessage{Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "hi"}
+
+	helper.executeAndGetResponse(t, context.Background(), msg)
+	helper.executeAndGetResponse(t, context.Background(), msg)
+
+	if len(provider.models) != 2 {
+		t.Fatalf("expected 2 LLM calls, got %d", len(provider.models))
+	}
+	if provider.models[0] != "big" || provider.models[1] != "small" {
+		t.Errorf("models = %v, want [big small]", provider.models)
+	}
+}
+
+func TestAgentLoop_SubagentCallsCountAgainstBudget(t *testing.T) {
+	al, provider := newUsageTestLoop(t, &config.BudgetConfig{DailyTokens: 100})
+	agent := al.GetRegistry().GetDefaultAgent()
+	ctx := tools.WithTurnContext(context.Background(), tools.NewTurnContext("telegram", "chat1", "s1"))
+	subagent := tools.NewSubagentTool(agent.SubagentMgr)
+	args := map[string]interface{}{"task": "look it up"}
+
+	if result := subagent.Execute(ctx, args); result.IsError {
+		t.Fatalf("first subagent run failed: %s", result.ForLLM)
+	}
+	if got := agent.Usage.Totals(usage.Filter{AgentID: agent.ID, SessionKey: "s1"}); got.TotalTokens != 120 {
+		t.Errorf("session totals = %+v, want the subagent's 120 tokens", got)
+	}
+
+	result := subagent.Execute(ctx, args)
+	if !result.IsError || !strings.Contains(result.ForLLM, "budget") {
+		t.Errorf("expected the budget to stop the subagent, got %+v", result)
+	}
+	if len(provider.models) != 1 {
+		t.Errorf("expected 1 LLM call before the budget stopped the subagent, got %d", len(provider.models))
+	}
+}
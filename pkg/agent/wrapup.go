@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// wrapUpNote asks for a final answer when a turn runs out of iterations.
+const wrapUpNote = "You have reached the tool-call limit for this turn and cannot call any more tools. " +
+	"Write your reply to the user now: summarize the progress made, what remains to be done, " +
+	"and the important results the tools returned so far."
+
+// continueInstruction replaces a "continue" reply to an unfinished turn.
+const continueInstruction = "continue — resume the unfinished task from where you left off."
+
+// wrapUp makes one last LLM call, after a turn used all its iterations, to
+// tell the user where the task stands. Tool definitions are still sent since
+// some APIs reject tool history without them; any calls in the answer are
+// dropped.
+func (al *AgentLoop) wrapUp(ctx context.Context, agent *AgentInstance, provider providers.LLMProvider, messages []providers.Message, toolDefs []providers.ToolDefinition, model string, opts processOptions) string {
+	notice := i18n.T(opts.Locale, "agent.iteration_limit", agent.MaxIterations)
+
+	event := newHookEvent(HookBeforeLLM, agent, agent.MaxIterations+1, opts)
+	event.Model = model
+	event.Messages = append(append([]providers.Message(nil), messages...),
+		providers.Message{Role: "system", Content: wrapUpNote})
+	if veto := al.runHooks(ctx, event); veto != nil {
+		return notice
+	}
+
+	resp, err := al.chat(ctx, agent, provider, event.Messages, toolDefs, model, opts)
+	if err != nil {
+		logger.WarnCF("agent", "Wrap-up call failed",
+			map[string]interface{}{"agent_id": agent.ID, "error": err.Error()})
+		return notice
+	}
+	al.recordUsage(agent, opts.SessionKey, model, resp)
+
+	event = newHookEvent(HookAfterLLM, agent, agent.MaxIterations+1, opts)
+	event.Model = model
+	event.Response = resp
+	if veto := al.runHooks(ctx, event); veto != nil {
+		return notice
+	}
+
+	content := strings.TrimSpace(event.Response.Content)
+	if content == "" {
+		return notice
+	}
+	return content + "\n\n" + notice
+}
+
+// isContinueRequest reports whether a user message only asks to go on.
+func isContinueRequest(content string) bool {
+	switch strings.ToLower(strings.Trim(strings.TrimSpace(content), ".!。！")) {
+	case "continue", "go on", "keep going", "继续":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// busyProvider keeps calling tools until told to wrap up or to continue.
+type busyProvider struct {
+	calls    int
+	lastUser string
+}
+
+func (p *busyProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	for _, m := range messages {
+		if m.Role == "user" {
+			p.lastUser = m.Content
+		}
+	}
+	last := messages[len(messages)-1]
+	switch {
+	case last.Role == "system" && last.Content == wrapUpNote:
+		return &providers.LLMResponse{Content: "Checked 3 of 5 files.",
+			ToolCalls: []providers.ToolCall{{ID: "ignored", Name: "sleep"}}}, nil
+	case p.lastUser == continueInstruction && last.Role == "user":
+		return &providers.LLMResponse{Content: "All 5 files checked."}, nil
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+		ID:        fmt.Sprintf("call_%d", p.calls),
+		Type:      "function",
+		Name:      "sleep",
+		Arguments: map[string]interface{}{"label": fmt.Sprintf("file %d", p.calls)},
+	}}}, nil
+}
+
+func (p *busyProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_WrapsUpAtIterationLimit(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 3,
+			},
+		},
+	}
+	provider := &busyProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	al.RegisterTool(&sleepTool{name: "sleep"})
+	sessions := al.registry.GetDefaultAgent().Sessions
+	const key = "agent:main:wrapup"
+
+	response, err := al.ProcessDirect(context.Background(), "check the files", key)
+	if err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+	if provider.calls != 4 {
+		t.Errorf("LLM called %d times, want 3 iterations and a wrap-up", provider.calls)
+	}
+	if !strings.HasPrefix(response, "Checked 3 of 5 files.") || !strings.Contains(response, `Reply "continue"`) {
+		t.Errorf("unexpected wrap-up: %q", response)
+	}
+	if !sessions.IsUnfinished(key) {
+		t.Error("session not marked unfinished")
+	}
+
+	response, err = al.ProcessDirect(context.Background(), "Continue", key)
+	if err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+	if response != "All 5 files checked." {
+		t.Errorf("continue reply = %q", response)
+	}
+	if sessions.IsUnfinished(key) {
+		t.Error("session still unfinished after continuing")
+	}
+}
+
+func TestIsContinueRequest(t *testing.T) {
+	for _, s := range []string{"continue", " Continue. ", "继续", "keep going!"} {
+		if !isContinueRequest(s) {
+			t.Errorf("isContinueRequest(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"continue with the next task", "stop", ""} {
+		if isContinueRequest(s) {
+			t.Errorf("isContinueRequest(%q) = true", s)
+		}
+	}
+}
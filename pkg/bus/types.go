@@ -1,5 +1,7 @@
 package bus
 
+import "time"
+
 type InboundMessage struct {
 	Channel    string            `json:"channel"`
 	SenderID   string            `json:"sender_id"`
@@ -8,12 +10,22 @@ type InboundMessage struct {
 	Media      []string          `json:"media,omitempty"`
 	SessionKey string            `json:"session_key"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
+	Ambient    []AmbientMessage  `json:"ambient,omitempty"` // group messages not addressed to the bot since it last was
+}
+
+// AmbientMessage is a group message that was not addressed to the bot, kept
+// so the next message that is has the conversation around it.
+type AmbientMessage struct {
+	Sender  string    `json:"sender"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
 }
 
 type OutboundMessage struct {
 	Channel  string            `json:"channel"`
 	ChatID   string            `json:"chat_id"`
 	Content  string            `json:"content"`
+	Media    []string          `json:"media,omitempty"` // local file paths to upload as attachments
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
@@ -0,0 +1,85 @@
+package channels
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+// ambientContentLimit caps the length of one buffered message, so a pasted
+// log does not crowd out the rest of the conversation.
+const ambientContentLimit = 500
+
+// ambientBuffer keeps the recent group messages that were not addressed to
+// the bot, per chat, until the next message that is.
+type ambientBuffer struct {
+	limit  int
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	chats map[string][]bus.AmbientMessage
+}
+
+// newAmbientBuffer keeps up to limit messages per chat, none older than
+// maxAge. It returns nil, which records nothing, when limit is not positive.
+func newAmbientBuffer(limit int, maxAge time.Duration) *ambientBuffer {
+	if limit <= 0 {
+		return nil
+	}
+	return &ambientBuffer{
+		limit:  limit,
+		maxAge: maxAge,
+		now:    time.Now,
+		chats:  make(map[string][]bus.AmbientMessage),
+	}
+}
+
+// record adds a message to the buffer of a chat, dropping the oldest ones
+// beyond the limit.
+func (b *ambientBuffer) record(chatID, sender, content string) {
+	if b == nil || content == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	msgs := append(b.fresh(b.chats[chatID], now), bus.AmbientMessage{
+		Sender:  sender,
+		Content: utils.Truncate(content, ambientContentLimit),
+		Time:    now,
+	})
+	if len(msgs) > b.limit {
+		msgs = msgs[len(msgs)-b.limit:]
+	}
+	b.chats[chatID] = msgs
+}
+
+// take returns the buffered messages of a chat that are still recent enough,
+// oldest first, and empties its buffer.
+func (b *ambientBuffer) take(chatID string) []bus.AmbientMessage {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msgs := b.fresh(b.chats[chatID], b.now())
+	delete(b.chats, chatID)
+	if len(msgs) == 0 {
+		return nil
+	}
+	return msgs
+}
+
+// fresh drops the messages older than maxAge. The caller holds b.mu.
+func (b *ambientBuffer) fresh(msgs []bus.AmbientMessage, now time.Time) []bus.AmbientMessage {
+	if b.maxAge <= 0 {
+		return msgs
+	}
+	for len(msgs) > 0 && now.Sub(msgs[0].Time) > b.maxAge {
+		msgs = msgs[1:]
+	}
+	return msgs
+}
@@ -0,0 +1,61 @@
+package channels
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+)
+
+func TestAmbientBuffer_KeepsRecentMessagesPerChat(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	b := newAmbientBuffer(2, 10*time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.record("a", "alice", "first")
+	now = now.Add(time.Minute)
+	b.record("a", "bob", "second")
+	b.record("a", "carol", "third")
+	b.record("b", "dave", "elsewhere")
+
+	got := b.take("a")
+	if len(got) != 2 || got[0].Content != "second" || got[1].Sender != "carol" {
+		t.Fatalf("take = %+v, want the last two messages", got)
+	}
+	if got := b.take("a"); got != nil {
+		t.Errorf("second take = %+v, want nil", got)
+	}
+
+	now = now.Add(11 * time.Minute)
+	b.record("b", "erin", "fresh")
+	if got := b.take("b"); len(got) != 1 || got[0].Sender != "erin" {
+		t.Errorf("take = %+v, want only the message within the max age", got)
+	}
+}
+
+func TestAmbientBuffer_Disabled(t *testing.T) {
+	b := newAmbientBuffer(0, time.Hour)
+	b.record("a", "alice", "hello")
+	if got := b.take("a"); got != nil {
+		t.Errorf("take = %+v, want nil", got)
+	}
+}
+
+func TestBaseChannel_AttachesAmbientToNextMessage(t *testing.T) {
+	mb := bus.NewMessageBus()
+	c := NewBaseChannel("test", nil, mb, []string{"1", "2"})
+	c.ambient = newAmbientBuffer(10, time.Hour)
+
+	c.recordAmbient("1", "alice", "group:9", "lunch?")
+	c.recordAmbient("3", "mallory", "group:9", "not allowed")
+	c.HandleMessage("2", "group:9", "@bot any ideas?", nil, nil)
+
+	msg, ok := mb.ConsumeInbound(context.Background())
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if len(msg.Ambient) != 1 || msg.Ambient[0].Sender != "alice" || msg.Ambient[0].Content != "lunch?" {
+		t.Errorf("Ambient = %+v, want alice's message only", msg.Ambient)
+	}
+}
@@ -16,12 +16,51 @@ type Channel interface {
 	IsAllowed(senderID string) bool
 }
 
+// StreamingChannel is implemented by channels that can edit a message they
+// already sent. Only these channels receive partial replies (outbound messages
+// with the "stream_update" metadata flag); each partial replaces the previous
+// one in place, and the next regular message for the chat finalizes it.
+type StreamingChannel interface {
+	Channel
+	SupportsStreaming() bool
+}
+
+// MediaChannel is implemented by channels that can upload the files listed
+// in OutboundMessage.Media. Other channels get a text notice instead.
+type MediaChannel interface {
+	Channel
+	SupportsMedia() bool
+}
+
+// isStreamUpdate reports whether msg carries the partial text of a reply that is still being generated.
+func isStreamUpdate(msg bus.OutboundMessage) bool {
+	return msg.Metadata != nil && msg.Metadata["stream_update"] == "true"
+}
+
+// isTemporaryMedia reports whether the files attached to msg were created
+// only to be sent, and are to be removed once it is.
+func isTemporaryMedia(msg bus.OutboundMessage) bool {
+	return msg.Metadata != nil && msg.Metadata["temporary_media"] == "true"
+}
+
+// messageLocale returns the language of the text a channel adds to msg, such
+// as button labels, or "" for the default.
+func messageLocale(msg bus.OutboundMessage) string {
+	return msg.Metadata["locale"]
+}
+
+// isToolStatus reports whether msg is a transient tool status line.
+func isToolStatus(msg bus.OutboundMessage) bool {
+	return msg.Metadata != nil && msg.Metadata["status_update"] == "true"
+}
+
 type BaseChannel struct {
 	config    interface{}
 	bus       bus.Broker
 	running   bool
 	name      string
 	allowList []string
+	ambient   *ambientBuffer // unaddressed group messages; nil when not kept
 }
 
 func NewBaseChannel(name string, config interface{}, bus bus.Broker, allowList []string) *BaseChannel {
@@ -93,11 +132,36 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		Content:  content,
 		Media:    media,
 		Metadata: metadata,
+		Ambient:  c.ambient.take(chatID),
 	}
 
 	c.bus.PublishInbound(msg)
 }
 
+// recordAmbient keeps a group message that was not addressed to the bot, to
+// be passed along with the next message of the chat that is.
+func (c *BaseChannel) recordAmbient(senderID, senderName, chatID, content string) {
+	if c.IsAllowed(senderID) {
+		c.ambient.record(chatID, senderName, content)
+	}
+}
+
+// handOffMedia drops the files passed on in an inbound message from a
+// channel's cleanup list. The agent removes them once the message is processed.
+func handOffMedia(localFiles, media []string) []string {
+	passed := make(map[string]bool, len(media))
+	for _, path := range media {
+		passed[path] = true
+	}
+	kept := localFiles[:0]
+	for _, file := range localFiles {
+		if !passed[file] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
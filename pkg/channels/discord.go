@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +20,7 @@ import (
 const (
 	transcriptionTimeout = 30 * time.Second
 	sendTimeout          = 10 * time.Second
+	uploadTimeout        = 60 * time.Second
 )
 
 type DiscordChannel struct {
@@ -30,6 +32,7 @@ type DiscordChannel struct {
 	typingMu    sync.Mutex
 	typingStop  map[string]chan struct{} // chatID → stop signal
 	botUserID   string                   // stored for mention checking
+	streams     sync.Map                 // chatID → ID of the message being streamed
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus bus.Broker) (*DiscordChannel, error) {
@@ -39,6 +42,7 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus bus.Broker) (*DiscordChanne
 	}
 
 	base := NewBaseChannel("discord", cfg, bus, cfg.AllowFrom)
+	base.ambient = newAmbientBuffer(cfg.AmbientMessages, time.Duration(cfg.AmbientMaxAgeMinutes)*time.Minute)
 
 	return &DiscordChannel{
 		BaseChannel: base,
@@ -120,13 +124,29 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("channel ID is empty")
 	}
 
+	if len(msg.Media) > 0 {
+		return c.sendWithMedia(ctx, channelID, msg)
+	}
+
 	runes := []rune(msg.Content)
 	if len(runes) == 0 {
 		return nil
 	}
 
+	if isStreamUpdate(msg) {
+		return c.sendStreamUpdate(ctx, channelID, msg.Content)
+	}
+
 	chunks := utils.SplitMessage(msg.Content, 2000) // Split messages into chunks, Discord length limit: 2000 chars
 
+	// Finalize a streamed reply by replacing its text with the first chunk.
+	// Status lines don't finalize it; they just close the stream.
+	if id, ok := c.streams.LoadAndDelete(channelID); ok && !isToolStatus(msg) {
+		if err := c.editChunk(ctx, channelID, id.(string), chunks[0]); err == nil {
+			chunks = chunks[1:]
+		}
+	}
+
 	for _, chunk := range chunks {
 		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
 			return err
@@ -136,6 +156,126 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	return nil
 }
 
+// SupportsMedia reports that Discord uploads attachments natively.
+func (c *DiscordChannel) SupportsMedia() bool {
+	return true
+}
+
+// sendWithMedia sends the text part as regular messages, then all files as
+// attachments of a single message (Discord allows up to 10 per message).
+func (c *DiscordChannel) sendWithMedia(ctx context.Context, channelID string, msg bus.OutboundMessage) error {
+	if msg.Content != "" {
+		textMsg := msg
+		textMsg.Media = nil
+		if err := c.Send(ctx, textMsg); err != nil {
+			return err
+		}
+	}
+
+	const maxAttachments = 10
+	for start := 0; start < len(msg.Media); start += maxAttachments {
+		end := min(start+maxAttachments, len(msg.Media))
+		if err := c.sendFiles(ctx, channelID, msg.Media[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *DiscordChannel) sendFiles(ctx context.Context, channelID string, paths []string) error {
+	files := make([]*discordgo.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open attachment: %w", err)
+		}
+		defer f.Close()
+		files = append(files, &discordgo.File{
+			Name:   filepath.Base(path),
+			Reader: f,
+		})
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Files: files})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to upload discord attachments: %w", err)
+		}
+		return nil
+	case <-sendCtx.Done():
+		return fmt.Errorf("upload timeout: %w", sendCtx.Err())
+	}
+}
+
+// SupportsStreaming reports that Discord replies can be edited while they stream.
+func (c *DiscordChannel) SupportsStreaming() bool {
+	return true
+}
+
+// sendStreamUpdate posts the first partial of a reply and edits that message for later partials.
+func (c *DiscordChannel) sendStreamUpdate(ctx context.Context, channelID, content string) error {
+	chunks := utils.SplitMessage(content, 2000)
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	if id, ok := c.streams.Load(channelID); ok {
+		return c.editChunk(ctx, channelID, id.(string), chunks[0])
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		sent, err := c.session.ChannelMessageSend(channelID, chunks[0])
+		if err == nil {
+			c.streams.Store(channelID, sent.ID)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send discord message: %w", err)
+		}
+		return nil
+	case <-sendCtx.Done():
+		return fmt.Errorf("send message timeout: %w", sendCtx.Err())
+	}
+}
+
+func (c *DiscordChannel) editChunk(ctx context.Context, channelID, messageID, content string) error {
+	editCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.session.ChannelMessageEdit(channelID, messageID, content)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to edit discord message: %w", err)
+		}
+		return nil
+	case <-editCtx.Done():
+		return fmt.Errorf("edit message timeout: %w", editCtx.Err())
+	}
+}
+
 func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
 	// Use the passed ctx for timeout control
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
@@ -183,6 +323,12 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		return
 	}
 
+	senderID := m.Author.ID
+	senderName := m.Author.Username
+	if m.Author.Discriminator != "" && m.Author.Discriminator != "0" {
+		senderName += "#" + m.Author.Discriminator
+	}
+
 	// If configured to only respond to mentions, check if bot is mentioned
 	// Skip this check for DMs (GuildID is empty) - DMs should always be responded to
 	if c.config.MentionOnly && m.GuildID != "" {
@@ -197,16 +343,11 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 			logger.DebugCF("discord", "Message ignored - bot not mentioned", map[string]any{
 				"user_id": m.Author.ID,
 			})
+			c.recordAmbient(senderID, senderName, m.ChannelID, ambientText(m))
 			return
 		}
 	}
 
-	senderID := m.Author.ID
-	senderName := m.Author.Username
-	if m.Author.Discriminator != "" && m.Author.Discriminator != "0" {
-		senderName += "#" + m.Author.Discriminator
-	}
-
 	content := m.Content
 	content = c.stripBotMention(content)
 	mediaPaths := make([]string, 0, len(m.Attachments))
@@ -304,9 +445,20 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		"peer_id":      peerID,
 	}
 
+	localFiles = handOffMedia(localFiles, mediaPaths)
 	c.HandleMessage(senderID, m.ChannelID, content, mediaPaths, metadata)
 }
 
+// ambientText describes an unmentioned message for the ambient context,
+// naming its attachments instead of downloading them.
+func ambientText(m *discordgo.MessageCreate) string {
+	content := m.Content
+	for _, attachment := range m.Attachments {
+		content = appendContent(content, fmt.Sprintf("[attachment: %s]", attachment.Filename))
+	}
+	return content
+}
+
 // startTyping starts a continuous typing indicator loop for the given chatID.
 // It stops any existing typing loop for that chatID before starting a new one.
 func (c *DiscordChannel) startTyping(chatID string) {
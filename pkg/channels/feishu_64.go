@@ -26,8 +26,9 @@ type FeishuChannel struct {
 	client   *lark.Client
 	wsClient *larkws.Client
 
-	mu     sync.Mutex
-	cancel context.CancelFunc
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	streams sync.Map // chatID -> ID of the message being streamed
 }
 
 func NewFeishuChannel(cfg config.FeishuConfig, bus bus.Broker) (*FeishuChannel, error) {
@@ -88,6 +89,30 @@ func (c *FeishuChannel) Stop(ctx context.Context) error {
 	return nil
 }
 
+// SupportsStreaming reports that Feishu text messages can be edited while they stream.
+func (c *FeishuChannel) SupportsStreaming() bool {
+	return true
+}
+
+func (c *FeishuChannel) updateMessage(ctx context.Context, messageID, content string) error {
+	req := larkim.NewUpdateMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewUpdateMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeText).
+			Content(content).
+			Build()).
+		Build()
+
+	resp, err := c.client.Im.V1.Message.Update(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to update feishu message: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
 func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if !c.IsRunning() {
 		return fmt.Errorf("feishu channel not running")
@@ -102,6 +127,17 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("failed to marshal feishu content: %w", err)
 	}
 
+	if isStreamUpdate(msg) {
+		if id, ok := c.streams.Load(msg.ChatID); ok {
+			return c.updateMessage(ctx, id.(string), string(payload))
+		}
+	} else if id, ok := c.streams.LoadAndDelete(msg.ChatID); ok && !isToolStatus(msg) {
+		// Replace the streamed text with the final reply
+		if err := c.updateMessage(ctx, id.(string), string(payload)); err == nil {
+			return nil
+		}
+	}
+
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
@@ -121,6 +157,10 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
 	}
 
+	if isStreamUpdate(msg) && resp.Data != nil && resp.Data.MessageId != nil {
+		c.streams.Store(msg.ChatID, *resp.Data.MessageId)
+	}
+
 	logger.DebugCF("feishu", "Feishu message sent", map[string]interface{}{
 		"chat_id": msg.ChatID,
 	})
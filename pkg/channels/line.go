@@ -385,6 +385,7 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 	// Show typing/loading indicator (requires user ID, not group ID)
 	c.sendLoading(senderID)
 
+	localFiles = handOffMedia(localFiles, mediaPaths)
 	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 }
 
@@ -15,6 +15,7 @@ import (
 	"github.com/zhaopengme/mobaiclaw/pkg/config"
 	"github.com/zhaopengme/mobaiclaw/pkg/constants"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
 )
 
 type Manager struct {
@@ -23,6 +24,10 @@ type Manager struct {
 	config       *config.Config
 	dispatchTask *asyncTask
 	mu           sync.RWMutex
+
+	// locale returns the language of a chat, for the text channels add to
+	// the messages sent to it.
+	locale func(channel, chatID string) string
 }
 
 type asyncTask struct {
@@ -43,6 +48,14 @@ func NewManager(cfg *config.Config, messageBus bus.Broker) (*Manager, error) {
 	return m, nil
 }
 
+// SetLocaleFunc sets how the language of the text channels add to outbound
+// messages, such as attachment notices, is chosen from the chat it is sent to.
+func (m *Manager) SetLocaleFunc(fn func(channel, chatID string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locale = fn
+}
+
 func (m *Manager) initChannels() error {
 	logger.InfoC("channels", "Initializing channel manager")
 
@@ -281,33 +294,71 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 			if !ok {
 				continue
 			}
+			m.sendOutbound(ctx, msg)
+		}
+	}
+}
 
-			// Silently skip internal channels
-			if constants.IsInternalChannel(msg.Channel) {
-				continue
-			}
+// sendOutbound delivers msg to its channel, then removes its files when they
+// were created only to be sent.
+func (m *Manager) sendOutbound(ctx context.Context, msg bus.OutboundMessage) {
+	if isTemporaryMedia(msg) {
+		defer utils.RemoveMedia(msg.Media)
+	}
 
-			m.mu.RLock()
-			channel, exists := m.channels[msg.Channel]
-			m.mu.RUnlock()
+	// Silently skip internal channels
+	if constants.IsInternalChannel(msg.Channel) {
+		return
+	}
 
-			if !exists {
-				logger.WarnCF("channels", "Unknown channel for outbound message", map[string]interface{}{
-					"channel": msg.Channel,
-				})
-				continue
-			}
+	m.mu.RLock()
+	channel, exists := m.channels[msg.Channel]
+	m.mu.RUnlock()
 
-			if err := channel.Send(ctx, msg); err != nil {
-				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
-					"channel": msg.Channel,
-					"error":   err.Error(),
-				})
-			}
-		}
+	if !exists {
+		logger.WarnCF("channels", "Unknown channel for outbound message", map[string]interface{}{
+			"channel": msg.Channel,
+		})
+		return
+	}
+
+	// Partial replies only make sense where they can be edited in place
+	if isStreamUpdate(msg) && !supportsStreaming(channel) {
+		return
+	}
+	if len(msg.Media) > 0 {
+		msg = m.withLocale(msg)
+	}
+	if !supportsMedia(channel) {
+		msg = withAttachmentNotice(msg)
+	}
+
+	if err := channel.Send(ctx, msg); err != nil {
+		logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
 	}
 }
 
+// withLocale records the language of the chat msg is sent to in its
+// metadata, unless the sender already did.
+func (m *Manager) withLocale(msg bus.OutboundMessage) bus.OutboundMessage {
+	m.mu.RLock()
+	locale := m.locale
+	m.mu.RUnlock()
+	if locale == nil || messageLocale(msg) != "" {
+		return msg
+	}
+	metadata := make(map[string]string, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		metadata[k] = v
+	}
+	metadata["locale"] = locale(msg.Channel, msg.ChatID)
+	msg.Metadata = metadata
+	return msg
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -340,6 +391,26 @@ func (m *Manager) GetEnabledChannels() []string {
 	return names
 }
 
+// StreamingChannels returns the names of enabled channels that can edit
+// messages in place and should therefore receive streamed partial replies.
+func (m *Manager) StreamingChannels() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.channels))
+	for name, channel := range m.channels {
+		if supportsStreaming(channel) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func supportsStreaming(channel Channel) bool {
+	sc, ok := channel.(StreamingChannel)
+	return ok && sc.SupportsStreaming()
+}
+
 func (m *Manager) RegisterChannel(name string, channel Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
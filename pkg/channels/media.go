@@ -0,0 +1,48 @@
+package channels
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+)
+
+// isImageFile reports whether path looks like an image that chat platforms
+// display inline, based on its extension.
+func isImageFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return true
+	}
+	return false
+}
+
+// attachmentNotice describes files a channel could not upload, in locale.
+func attachmentNotice(locale string, paths []string) string {
+	lines := make([]string, 0, len(paths))
+	for _, path := range paths {
+		lines = append(lines, i18n.T(locale, "channel.attachment", filepath.Base(path)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withAttachmentNotice folds msg.Media into a text notice for channels that cannot upload files.
+func withAttachmentNotice(msg bus.OutboundMessage) bus.OutboundMessage {
+	if len(msg.Media) == 0 {
+		return msg
+	}
+	notice := attachmentNotice(messageLocale(msg), msg.Media)
+	if msg.Content != "" {
+		msg.Content += "\n\n" + notice
+	} else {
+		msg.Content = notice
+	}
+	msg.Media = nil
+	return msg
+}
+
+func supportsMedia(channel Channel) bool {
+	mc, ok := channel.(MediaChannel)
+	return ok && mc.SupportsMedia()
+}
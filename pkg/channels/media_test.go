@@ -0,0 +1,143 @@
+package channels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+func TestWithAttachmentNotice(t *testing.T) {
+	msg := withAttachmentNotice(bus.OutboundMessage{
+		Content: "Here is the report",
+		Media:   []string{"/workspace/out/report.pdf"},
+	})
+	if len(msg.Media) != 0 {
+		t.Errorf("Media = %v, want none", msg.Media)
+	}
+	if !strings.HasPrefix(msg.Content, "Here is the report\n\n") || !strings.Contains(msg.Content, "report.pdf") {
+		t.Errorf("unexpected content %q", msg.Content)
+	}
+	if strings.Contains(msg.Content, "/workspace") {
+		t.Errorf("notice should not leak full paths: %q", msg.Content)
+	}
+
+	plain := bus.OutboundMessage{Content: "hi"}
+	if got := withAttachmentNotice(plain); got.Content != "hi" {
+		t.Errorf("message without media changed: %q", got.Content)
+	}
+}
+
+func TestIsImageFile(t *testing.T) {
+	for path, want := range map[string]bool{
+		"a.PNG":    true,
+		"b.jpeg":   true,
+		"c.webp":   true,
+		"d.pdf":    false,
+		"noext":    false,
+		"e.tar.gz": false,
+	} {
+		if got := isImageFile(path); got != want {
+			t.Errorf("isImageFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// mediaRecorder is a media-capable channel that keeps the files of the
+// messages it is asked to send.
+type mediaRecorder struct {
+	*BaseChannel
+	sent []string
+}
+
+func (c *mediaRecorder) Start(ctx context.Context) error { return nil }
+func (c *mediaRecorder) Stop(ctx context.Context) error  { return nil }
+func (c *mediaRecorder) SupportsMedia() bool             { return true }
+
+func (c *mediaRecorder) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	for _, path := range msg.Media {
+		if _, err := os.Stat(path); err == nil {
+			c.sent = append(c.sent, path)
+		}
+	}
+	return nil
+}
+
+func TestSendOutbound_RemovesTemporaryMedia(t *testing.T) {
+	if err := os.MkdirAll(utils.MediaDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp(utils.MediaDir(), "export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "chat.md")
+	if err := os.WriteFile(path, []byte("# chat"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := &mediaRecorder{BaseChannel: NewBaseChannel("test", nil, nil, nil)}
+	m := &Manager{channels: map[string]Channel{"test": ch}}
+	m.sendOutbound(context.Background(), bus.OutboundMessage{
+		Channel:  "test",
+		ChatID:   "1",
+		Media:    []string{path},
+		Metadata: map[string]string{"temporary_media": "true"},
+	})
+
+	if len(ch.sent) != 1 {
+		t.Fatalf("sent = %v, want the file delivered before removal", ch.sent)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("export directory still exists: %v", err)
+	}
+}
+
+// textRecorder is a channel without file uploads that keeps the text of the
+// messages it is asked to send.
+type textRecorder struct {
+	*BaseChannel
+	sent []string
+}
+
+func (c *textRecorder) Start(ctx context.Context) error { return nil }
+func (c *textRecorder) Stop(ctx context.Context) error  { return nil }
+
+func (c *textRecorder) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	c.sent = append(c.sent, msg.Content)
+	return nil
+}
+
+func TestSendOutbound_AttachmentNoticeInChatLocale(t *testing.T) {
+	ch := &textRecorder{BaseChannel: NewBaseChannel("test", nil, nil, nil)}
+	m := &Manager{channels: map[string]Channel{"test": ch}}
+	m.SetLocaleFunc(func(channel, chatID string) string {
+		if chatID == "zh-chat" {
+			return "zh"
+		}
+		return "en"
+	})
+
+	for _, chatID := range []string{"zh-chat", "en-chat"} {
+		m.sendOutbound(context.Background(), bus.OutboundMessage{
+			Channel: "test",
+			ChatID:  chatID,
+			Media:   []string{"/tmp/report.pdf"},
+		})
+	}
+
+	if len(ch.sent) != 2 {
+		t.Fatalf("sent = %v, want 2 messages", ch.sent)
+	}
+	if !strings.Contains(ch.sent[0], "附件：report.pdf") {
+		t.Errorf("zh notice = %q", ch.sent[0])
+	}
+	if !strings.Contains(ch.sent[1], "Attachment: report.pdf") {
+		t.Errorf("en notice = %q", ch.sent[1])
+	}
+}
@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -99,6 +100,7 @@ type oneBotMessageSegment struct {
 
 func NewOneBotChannel(cfg config.OneBotConfig, messageBus bus.Broker) (*OneBotChannel, error) {
 	base := NewBaseChannel("onebot", cfg, messageBus, cfg.AllowFrom)
+	base.ambient = newAmbientBuffer(cfg.AmbientMessages, time.Duration(cfg.AmbientMaxAgeMinutes)*time.Minute)
 
 	const dedupSize = 1024
 	return &OneBotChannel{
@@ -420,7 +422,12 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	return nil
 }
 
-func (c *OneBotChannel) buildMessageSegments(chatID, content string) []oneBotMessageSegment {
+// SupportsMedia reports that OneBot sends images as CQ image segments.
+func (c *OneBotChannel) SupportsMedia() bool {
+	return true
+}
+
+func (c *OneBotChannel) buildMessageSegments(chatID, content, locale string, media []string) []oneBotMessageSegment {
 	var segments []oneBotMessageSegment
 
 	if lastMsgID, ok := c.lastMessageID.Load(chatID); ok {
@@ -432,17 +439,50 @@ func (c *OneBotChannel) buildMessageSegments(chatID, content string) []oneBotMes
 		}
 	}
 
-	segments = append(segments, oneBotMessageSegment{
-		Type: "text",
-		Data: map[string]interface{}{"text": content},
-	})
+	// Images become CQ image segments; other files can't be sent inline,
+	// so they are mentioned in the text instead.
+	var images []oneBotMessageSegment
+	var others []string
+	for _, path := range media {
+		if !isImageFile(path) {
+			others = append(others, path)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.WarnCF("onebot", "Failed to read attachment", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+			others = append(others, path)
+			continue
+		}
+		images = append(images, oneBotMessageSegment{
+			Type: "image",
+			Data: map[string]interface{}{"file": "base64://" + base64.StdEncoding.EncodeToString(data)},
+		})
+	}
+	if len(others) > 0 {
+		if content != "" {
+			content += "\n\n"
+		}
+		content += attachmentNotice(locale, others)
+	}
+
+	if content != "" {
+		segments = append(segments, oneBotMessageSegment{
+			Type: "text",
+			Data: map[string]interface{}{"text": content},
+		})
+	}
+	segments = append(segments, images...)
 
 	return segments
 }
 
 func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, interface{}, error) {
 	chatID := msg.ChatID
-	segments := c.buildMessageSegments(chatID, msg.Content)
+	segments := c.buildMessageSegments(chatID, msg.Content, messageLocale(msg), msg.Media)
 
 	var action, idKey string
 	var rawID string
@@ -895,6 +935,11 @@ func (c *OneBotChannel) handleMessage(raw *oneBotRawEvent) {
 				"is_mentioned": isBotMentioned,
 				"content":      truncate(content, 100),
 			})
+			senderName := metadata["sender_name"]
+			if senderName == "" {
+				senderName = senderID
+			}
+			c.recordAmbient(senderID, senderName, chatID, content)
 			return
 		}
 		content = strippedContent
@@ -928,6 +973,7 @@ func (c *OneBotChannel) handleMessage(raw *oneBotRawEvent) {
 		c.pendingEmojiMsg.Store(chatID, messageID)
 	}
 
+	parsed.LocalFiles = handOffMedia(parsed.LocalFiles, parsed.Media)
 	c.HandleMessage(senderID, chatID, content, parsed.Media, metadata)
 }
 
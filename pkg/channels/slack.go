@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +31,7 @@ type SlackChannel struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	pendingAcks  sync.Map
+	streams      sync.Map // chatID -> timestamp of the message being streamed
 }
 
 type slackMessageRef struct {
@@ -109,6 +111,59 @@ func (c *SlackChannel) Stop(ctx context.Context) error {
 	return nil
 }
 
+// SupportsStreaming reports that Slack replies can be updated while they stream.
+func (c *SlackChannel) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsMedia reports that Slack uploads attachments natively.
+func (c *SlackChannel) SupportsMedia() bool {
+	return true
+}
+
+// sendWithMedia sends the text part as a regular message, then uploads each
+// file into the same channel/thread.
+func (c *SlackChannel) sendWithMedia(ctx context.Context, channelID, threadTS string, msg bus.OutboundMessage) error {
+	if msg.Content != "" {
+		textMsg := msg
+		textMsg.Media = nil
+		if err := c.Send(ctx, textMsg); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range msg.Media {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat attachment: %w", err)
+		}
+		_, err = c.api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+			File:            path,
+			FileSize:        int(info.Size()),
+			Filename:        filepath.Base(path),
+			Channel:         channelID,
+			ThreadTimestamp: threadTS,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload slack file: %w", err)
+		}
+	}
+
+	c.ackPending(msg.ChatID)
+	return nil
+}
+
+// ackPending marks the inbound message that started this reply as handled.
+func (c *SlackChannel) ackPending(chatID string) {
+	if ref, ok := c.pendingAcks.LoadAndDelete(chatID); ok {
+		msgRef := ref.(slackMessageRef)
+		c.api.AddReaction("white_check_mark", slack.ItemRef{
+			Channel:   msgRef.ChannelID,
+			Timestamp: msgRef.Timestamp,
+		})
+	}
+}
+
 func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if !c.IsRunning() {
 		return fmt.Errorf("slack channel not running")
@@ -119,27 +174,45 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
 
+	if len(msg.Media) > 0 {
+		return c.sendWithMedia(ctx, channelID, threadTS, msg)
+	}
+
 	opts := []slack.MsgOption{
 		slack.MsgOptionText(msg.Content, false),
 	}
 
+	if isStreamUpdate(msg) {
+		if ts, ok := c.streams.Load(msg.ChatID); ok {
+			if _, _, _, err := c.api.UpdateMessageContext(ctx, channelID, ts.(string), opts...); err != nil {
+				return fmt.Errorf("failed to update slack message: %w", err)
+			}
+			return nil
+		}
+	} else if ts, ok := c.streams.LoadAndDelete(msg.ChatID); ok && !isToolStatus(msg) {
+		// Replace the streamed text with the final reply
+		if _, _, _, err := c.api.UpdateMessageContext(ctx, channelID, ts.(string), opts...); err == nil {
+			c.ackPending(msg.ChatID)
+			return nil
+		}
+	}
+
 	if threadTS != "" {
 		opts = append(opts, slack.MsgOptionTS(threadTS))
 	}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+	_, ts, err := c.api.PostMessageContext(ctx, channelID, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to send slack message: %w", err)
 	}
 
-	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
-		msgRef := ref.(slackMessageRef)
-		c.api.AddReaction("white_check_mark", slack.ItemRef{
-			Channel:   msgRef.ChannelID,
-			Timestamp: msgRef.Timestamp,
-		})
+	if isStreamUpdate(msg) {
+		c.streams.Store(msg.ChatID, ts)
+		return nil
 	}
 
+	c.ackPending(msg.ChatID)
+
 	logger.DebugCF("slack", "Message sent", map[string]interface{}{
 		"channel_id": channelID,
 		"thread_ts":  threadTS,
@@ -300,6 +373,7 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 		"has_thread": threadTS != "",
 	})
 
+	localFiles = handOffMedia(localFiles, mediaPaths)
 	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 }
 
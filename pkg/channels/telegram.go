@@ -20,6 +20,7 @@ import (
 
 	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/utils"
 	"github.com/zhaopengme/mobaiclaw/pkg/voice"
@@ -122,6 +123,10 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return c.handleMessage(ctx, &message)
 	}, th.Or(th.AnyMessageWithText(), th.AnyMessageWithCaption(), th.AnyMessageWithMedia()))
 
+	bh.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
+		return c.handleCallbackQuery(ctx, query)
+	}, th.AnyCallbackQueryWithMessage())
+
 	c.setRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]interface{}{
 		"username": c.bot.Username(),
@@ -136,6 +141,12 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 
 	return nil
 }
+
+// SupportsStreaming reports that Telegram edits the placeholder message in place.
+func (c *TelegramChannel) SupportsStreaming() bool {
+	return true
+}
+
 func (c *TelegramChannel) Stop(ctx context.Context) error {
 	logger.InfoC("telegram", "Stopping Telegram bot...")
 	c.setRunning(false)
@@ -147,13 +158,18 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("telegram bot not running")
 	}
 
-	isStatusUpdate := msg.Metadata != nil && msg.Metadata["status_update"] == "true"
+	// Streamed partial replies reuse the placeholder bubble exactly like status updates
+	isStatusUpdate := isToolStatus(msg) || isStreamUpdate(msg)
 
 	chatID, threadID, err := parseCompositeChatID(msg.ChatID)
 	if err != nil {
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
+	if len(msg.Media) > 0 {
+		return c.sendWithMedia(ctx, msg, chatID, threadID)
+	}
+
 	// 取消思考动画
 	if !isStatusUpdate {
 		if stop, ok := c.stopThinking.Load(msg.ChatID); ok {
@@ -167,6 +183,10 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	// 将长 Markdown 文本拆分为多个长度安全的块 (Telegram 最大限制是 4096 字符)
 	// 我们预留一些余量，设置为 4000
 	chunks := splitMarkdownContent(msg.Content, 4000)
+	if isStreamUpdate(msg) && len(chunks) > 1 {
+		// Keep streaming inside one bubble; the final message delivers the rest.
+		chunks = chunks[:1]
+	}
 
 	var lastErr error
 	for i, chunk := range chunks {
@@ -182,10 +202,11 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 				}
 
 				editMsg := &telego.EditMessageTextParams{
-					ChatID:    tu.ID(chatID),
-					MessageID: pID.(int),
-					Text:      htmlContent,
-					ParseMode: telego.ModeHTML,
+					ChatID:      tu.ID(chatID),
+					MessageID:   pID.(int),
+					Text:        htmlContent,
+					ParseMode:   telego.ModeHTML,
+					ReplyMarkup: approvalKeyboard(msg),
 				}
 
 				if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil || strings.Contains(err.Error(), "message is not modified") {
@@ -215,6 +236,9 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		if threadID != 0 {
 			tgMsg.MessageThreadID = threadID
 		}
+		if keyboard := approvalKeyboard(msg); keyboard != nil && i == len(chunks)-1 {
+			tgMsg.ReplyMarkup = keyboard
+		}
 
 		sentMsg, sendErr := c.bot.SendMessage(ctx, tgMsg)
 		if sendErr != nil {
@@ -239,6 +263,121 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	return lastErr
 }
 
+// approvalKeyboard returns approve/deny buttons for tool approval requests.
+// The buttons send the matching /approve or /deny command back as callback data.
+func approvalKeyboard(msg bus.OutboundMessage) *telego.InlineKeyboardMarkup {
+	id := msg.Metadata["approval_id"]
+	if id == "" {
+		return nil
+	}
+	locale := messageLocale(msg)
+	return tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(i18n.T(locale, "channel.approve")).WithCallbackData("/approve "+id),
+		tu.InlineKeyboardButton(i18n.T(locale, "channel.deny")).WithCallbackData("/deny "+id),
+	))
+}
+
+// handleCallbackQuery turns approval button presses into /approve and /deny commands.
+func (c *TelegramChannel) handleCallbackQuery(ctx context.Context, query telego.CallbackQuery) error {
+	if err := c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID)); err != nil {
+		logger.DebugCF("telegram", "Failed to answer callback query", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if !strings.HasPrefix(query.Data, "/approve ") && !strings.HasPrefix(query.Data, "/deny ") {
+		return nil
+	}
+
+	senderID := fmt.Sprintf("%d", query.From.ID)
+	if query.From.Username != "" {
+		senderID = fmt.Sprintf("%d|%s", query.From.ID, query.From.Username)
+	}
+	if !c.IsAllowed(senderID) {
+		return nil
+	}
+
+	message := query.Message
+	chatID := message.GetChat().ID
+	chatIDStr := fmt.Sprintf("%d", chatID)
+	if msg := message.Message(); msg != nil && msg.MessageThreadID != 0 {
+		chatIDStr = fmt.Sprintf("%d:%d", chatID, msg.MessageThreadID)
+	}
+
+	// Remove the buttons so the request cannot be answered twice
+	if _, err := c.bot.EditMessageReplyMarkup(ctx, &telego.EditMessageReplyMarkupParams{
+		ChatID:    tu.ID(chatID),
+		MessageID: message.GetMessageID(),
+	}); err != nil {
+		logger.DebugCF("telegram", "Failed to remove approval buttons", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	c.HandleMessage(fmt.Sprintf("%d", query.From.ID), chatIDStr, query.Data, nil, map[string]string{
+		"user_id":  fmt.Sprintf("%d", query.From.ID),
+		"username": query.From.Username,
+	})
+	return nil
+}
+
+// SupportsMedia reports that Telegram uploads attachments natively.
+func (c *TelegramChannel) SupportsMedia() bool {
+	return true
+}
+
+// sendWithMedia sends the text part as a regular message, then uploads each
+// file as a photo or document.
+func (c *TelegramChannel) sendWithMedia(ctx context.Context, msg bus.OutboundMessage, chatID int64, threadID int) error {
+	var lastErr error
+	if msg.Content != "" {
+		textMsg := msg
+		textMsg.Media = nil
+		lastErr = c.Send(ctx, textMsg)
+	}
+
+	for _, path := range msg.Media {
+		if err := c.sendFile(ctx, chatID, threadID, path); err != nil {
+			logger.ErrorCF("telegram", "Failed to upload attachment", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (c *TelegramChannel) sendFile(ctx context.Context, chatID int64, threadID int, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer file.Close()
+
+	if isImageFile(path) {
+		params := &telego.SendPhotoParams{
+			ChatID: tu.ID(chatID),
+			Photo:  tu.File(file),
+		}
+		if threadID != 0 {
+			params.MessageThreadID = threadID
+		}
+		_, err = c.bot.SendPhoto(ctx, params)
+		return err
+	}
+
+	params := &telego.SendDocumentParams{
+		ChatID:   tu.ID(chatID),
+		Document: tu.File(file),
+	}
+	if threadID != 0 {
+		params.MessageThreadID = threadID
+	}
+	_, err = c.bot.SendDocument(ctx, params)
+	return err
+}
+
 func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Message) error {
 	if message == nil {
 		return fmt.Errorf("message is nil")
@@ -446,6 +585,7 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 		metadata["thread_id"] = fmt.Sprintf("%d", message.MessageThreadID)
 	}
 
+	localFiles = handOffMedia(localFiles, mediaPaths)
 	c.HandleMessage(fmt.Sprintf("%d", user.ID), chatIDStr, content, mediaPaths, metadata)
 	return nil
 }
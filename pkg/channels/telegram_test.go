@@ -2,6 +2,8 @@ package channels
 
 import (
 	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 )
 
 func TestParseCompositeChatID(t *testing.T) {
@@ -81,3 +83,23 @@ func TestMarkdownToTelegramHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestApprovalKeyboard(t *testing.T) {
+	if kb := approvalKeyboard(bus.OutboundMessage{Content: "hi"}); kb != nil {
+		t.Errorf("keyboard for a message without approval_id: %+v", kb)
+	}
+
+	kb := approvalKeyboard(bus.OutboundMessage{
+		Metadata: map[string]string{"approval_id": "a1", "locale": "zh"},
+	})
+	if kb == nil || len(kb.InlineKeyboard) != 1 || len(kb.InlineKeyboard[0]) != 2 {
+		t.Fatalf("unexpected keyboard %+v", kb)
+	}
+	approve, deny := kb.InlineKeyboard[0][0], kb.InlineKeyboard[0][1]
+	if approve.Text != "✅ 批准" || approve.CallbackData != "/approve a1" {
+		t.Errorf("approve button = %q %q", approve.Text, approve.CallbackData)
+	}
+	if deny.Text != "🚫 拒绝" || deny.CallbackData != "/deny a1" {
+		t.Errorf("deny button = %q %q", deny.Text, deny.CallbackData)
+	}
+}
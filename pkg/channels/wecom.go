@@ -183,7 +183,15 @@ func (c *WeComBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		"preview": utils.Truncate(msg.Content, 100),
 	})
 
-	return c.sendWebhookReply(ctx, msg.ChatID, msg.Content)
+	if msg.Content != "" {
+		if err := c.sendWebhookReply(ctx, msg.ChatID, msg.Content); err != nil {
+			return err
+		}
+	}
+	if len(msg.Media) > 0 {
+		return c.sendMedia(ctx, msg)
+	}
+	return nil
 }
 
 // handleWebhook handles incoming webhook requests from WeCom
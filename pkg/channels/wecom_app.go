@@ -223,7 +223,15 @@ func (c *WeComAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		"preview": utils.Truncate(msg.Content, 100),
 	})
 
-	return c.sendTextMessage(ctx, accessToken, msg.ChatID, msg.Content)
+	if msg.Content != "" {
+		if err := c.sendTextMessage(ctx, accessToken, msg.ChatID, msg.Content); err != nil {
+			return err
+		}
+	}
+	if len(msg.Media) > 0 {
+		return c.sendMedia(ctx, accessToken, msg)
+	}
+	return nil
 }
 
 // handleWebhook handles incoming webhook requests from WeCom
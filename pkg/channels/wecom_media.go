@@ -0,0 +1,196 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+)
+
+const (
+	wecomUploadTimeout = 60 * time.Second
+	// Webhook image messages carry the image inline and are limited to 2MB
+	wecomMaxInlineImage = 2 << 20
+)
+
+// wecomUploadMedia uploads a file to a WeCom media upload endpoint (webhook
+// or app API, both use the "media" multipart field) and returns its media_id.
+func wecomUploadMedia(ctx context.Context, uploadURL, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("media", filepath.Base(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to finish form: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, wecomUploadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, uploadURL, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		MediaID string `json:"media_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse upload response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return "", fmt.Errorf("media upload error: %s (code: %d)", result.ErrMsg, result.ErrCode)
+	}
+	return result.MediaID, nil
+}
+
+// postWeComJSON posts payload to a WeCom API endpoint and checks errcode.
+func postWeComJSON(ctx context.Context, apiURL string, payload interface{}, timeout time.Duration) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, apiURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("API error: %s (code: %d)", result.ErrMsg, result.ErrCode)
+	}
+	return nil
+}
+
+// SupportsMedia reports that the WeCom bot uploads attachments through its webhook.
+func (c *WeComBotChannel) SupportsMedia() bool {
+	return true
+}
+
+// sendMedia sends small JPG/PNG images inline and uploads everything else
+// as a file through the webhook's upload_media endpoint.
+func (c *WeComBotChannel) sendMedia(ctx context.Context, msg bus.OutboundMessage) error {
+	for _, path := range msg.Media {
+		payload, err := c.mediaPayload(ctx, path)
+		if err != nil {
+			return err
+		}
+		if err := postWeComJSON(ctx, c.config.WebhookURL, payload, wecomUploadTimeout); err != nil {
+			return fmt.Errorf("failed to send attachment %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+func (c *WeComBotChannel) mediaPayload(ctx context.Context, path string) (map[string]interface{}, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".jpg" || ext == ".jpeg" || ext == ".png" {
+		if info, err := os.Stat(path); err == nil && info.Size() <= wecomMaxInlineImage {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attachment: %w", err)
+			}
+			sum := md5.Sum(data)
+			return map[string]interface{}{
+				"msgtype": "image",
+				"image": map[string]string{
+					"base64": base64.StdEncoding.EncodeToString(data),
+					"md5":    hex.EncodeToString(sum[:]),
+				},
+			}, nil
+		}
+	}
+
+	uploadURL := strings.Replace(c.config.WebhookURL, "/webhook/send", "/webhook/upload_media", 1) + "&type=file"
+	mediaID, err := wecomUploadMedia(ctx, uploadURL, path)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"msgtype": "file",
+		"file":    map[string]string{"media_id": mediaID},
+	}, nil
+}
+
+// SupportsMedia reports that the WeCom app uploads attachments through the media API.
+func (c *WeComAppChannel) SupportsMedia() bool {
+	return true
+}
+
+// sendMedia uploads each attachment as temporary media and sends it as an
+// image or file message.
+func (c *WeComAppChannel) sendMedia(ctx context.Context, accessToken string, msg bus.OutboundMessage) error {
+	for _, path := range msg.Media {
+		msgType := "file"
+		if isImageFile(path) {
+			msgType = "image"
+		}
+
+		uploadURL := fmt.Sprintf("%s/cgi-bin/media/upload?access_token=%s&type=%s", wecomAPIBase, accessToken, msgType)
+		mediaID, err := wecomUploadMedia(ctx, uploadURL, path)
+		if err != nil {
+			return err
+		}
+
+		payload := map[string]interface{}{
+			"touser":  msg.ChatID,
+			"msgtype": msgType,
+			"agentid": c.config.AgentID,
+			msgType:   map[string]string{"media_id": mediaID},
+		}
+		apiURL := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", wecomAPIBase, accessToken)
+		if err := postWeComJSON(ctx, apiURL, payload, wecomUploadTimeout); err != nil {
+			return fmt.Errorf("failed to send attachment %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"sort"
 	"strings"
 	"testing"
@@ -753,3 +754,68 @@ func TestWeComBotMessageStructure(t *testing.T) {
 		t.Errorf("Text.Content = %q, want %q", msg.Text.Content, "Hello World")
 	}
 }
+
+func TestWeComBotSendMedia(t *testing.T) {
+	var uploads int
+	var sent []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/webhook/upload_media"):
+			uploads++
+			if r.URL.Query().Get("type") != "file" {
+				t.Errorf("upload type = %q, want file", r.URL.Query().Get("type"))
+			}
+			if _, _, err := r.FormFile("media"); err != nil {
+				t.Errorf("missing media form file: %v", err)
+			}
+			w.Write([]byte(`{"errcode":0,"media_id":"m1"}`))
+		case strings.HasSuffix(r.URL.Path, "/webhook/send"):
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			sent = append(sent, payload)
+			w.Write([]byte(`{"errcode":0}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	image := dir + "/chart.png"
+	doc := dir + "/report.pdf"
+	if err := os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(doc, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := NewWeComBotChannel(config.WeComConfig{
+		Token:      "test_token",
+		WebhookURL: server.URL + "/cgi-bin/webhook/send?key=test",
+	}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.setRunning(true)
+
+	err = ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:  "chat1",
+		Content: "attached",
+		Media:   []string{image, doc},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if uploads != 1 {
+		t.Errorf("uploads = %d, want 1 (images are sent inline)", uploads)
+	}
+	var types []string
+	for _, p := range sent {
+		types = append(types, fmt.Sprint(p["msgtype"]))
+	}
+	if strings.Join(types, ",") != "text,image,file" {
+		t.Errorf("message types = %v, want [text image file]", types)
+	}
+}
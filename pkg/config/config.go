@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 
 	"github.com/caarlos0/env/v11"
@@ -57,6 +58,62 @@ type Config struct {
 	Tools     ToolsConfig     `json:"tools"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Devices   DevicesConfig   `json:"devices"`
+	Hooks     []HookConfig    `json:"hooks,omitempty"`
+	Cassette  *CassetteConfig `json:"cassette,omitempty"`
+	Locale    LocaleConfig    `json:"locale,omitempty"`
+
+	// Encryption protects sessions, memory and cron jobs at rest.
+	Encryption EncryptionConfig `json:"encryption,omitempty"`
+}
+
+// EncryptionConfig locates the key used to encrypt workspace files. The
+// MOBAICLAW_ENCRYPTION_KEY environment variable, holding the key itself,
+// takes precedence. Without a key, files are written in plaintext.
+type EncryptionConfig struct {
+	KeyFile string `json:"key_file,omitempty" env:"MOBAICLAW_ENCRYPTION_KEY_FILE"`
+}
+
+// LocaleConfig selects the language of built-in messages such as command
+// replies and status notices ("en" or "zh"). A binding's locale, or a
+// "language" entry in the user's profile, takes precedence.
+type LocaleConfig struct {
+	Default  string            `json:"default,omitempty" env:"MOBAICLAW_LOCALE"`
+	Channels map[string]string `json:"channels,omitempty"` // channel name -> locale
+}
+
+// For returns the locale configured for channel, or the default.
+func (l LocaleConfig) For(channel string) string {
+	if locale := l.Channels[channel]; locale != "" {
+		return locale
+	}
+	return l.Default
+}
+
+// CassetteConfig records every LLM request and response to a JSONL file, or
+// replays responses from one instead of calling the providers.
+type CassetteConfig struct {
+	Mode string `json:"mode"` // "record" or "replay"
+	Path string `json:"path"`
+}
+
+// HookConfig runs an external executable as an agent loop hook. The hook
+// event is written to its stdin as JSON; it may answer on stdout with
+// {"veto": true, "reason": "..."} or with replacement "messages", "response",
+// "tool_call" arguments or "tool_result" for its stage. Empty output
+// continues unchanged.
+type HookConfig struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Stages limits the hook to "before_llm", "after_llm", "before_tool"
+	// and/or "after_tool". Empty runs it at every stage.
+	Stages []string `json:"stages,omitempty"`
+	// Tools limits the tool stages to these tool names. Empty matches all tools.
+	Tools          []string `json:"tools,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // default 10
+	// FailClosed treats a crash, timeout or invalid output as a veto.
+	// Otherwise failures are logged and ignored.
+	FailClosed bool `json:"fail_closed,omitempty"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Config
@@ -77,7 +134,8 @@ func (c Config) MarshalJSON() ([]byte, error) {
 	}
 
 	// Only include session if not empty
-	if c.Session.DMScope != "" || len(c.Session.IdentityLinks) > 0 {
+	if c.Session.DMScope != "" || len(c.Session.IdentityLinks) > 0 || c.Session.Backend != "" || c.Session.MaxCached > 0 ||
+		c.Session.Reset != nil || len(c.Session.ResetChannels) > 0 {
 		aux.Session = &c.Session
 	}
 
@@ -136,6 +194,9 @@ type AgentConfig struct {
 	Model     *AgentModelConfig `json:"model,omitempty"`
 	Skills    []string          `json:"skills,omitempty"`
 	Subagents *SubagentsConfig  `json:"subagents,omitempty"`
+	// ReasoningEffort and ThinkingBudget override the defaults for this agent.
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ThinkingBudget  int    `json:"thinking_budget,omitempty"`
 }
 
 type SubagentsConfig struct {
@@ -159,26 +220,80 @@ type BindingMatch struct {
 type AgentBinding struct {
 	AgentID string       `json:"agent_id"`
 	Match   BindingMatch `json:"match"`
+	Locale  string       `json:"locale,omitempty"` // language of built-in messages
+	// Reset overrides the session reset policy for matched chats.
+	Reset *SessionResetConfig `json:"reset,omitempty"`
 }
 
 type SessionConfig struct {
 	DMScope       string              `json:"dm_scope,omitempty"`
 	IdentityLinks map[string][]string `json:"identity_links,omitempty"`
+	// Backend is how sessions are stored: "json" (default) rewrites one file
+	// per session on every turn, "jsonl" appends to a log per session.
+	Backend string `json:"backend,omitempty" env:"MOBAICLAW_SESSION_BACKEND"`
+	// MaxCached caps the sessions each agent keeps in memory; the least
+	// recently used are unloaded beyond it. 0 means no limit.
+	MaxCached int `json:"max_cached,omitempty" env:"MOBAICLAW_SESSION_MAX_CACHED"`
+	// Reset starts a new conversation after a period of inactivity or once a
+	// day. ResetChannels overrides it per channel; a binding's reset
+	// overrides both.
+	Reset         *SessionResetConfig           `json:"reset,omitempty"`
+	ResetChannels map[string]SessionResetConfig `json:"reset_channels,omitempty"`
+}
+
+// SessionResetConfig decides when a chat's session is archived and a new
+// conversation started.
+type SessionResetConfig struct {
+	IdleMinutes  int    `json:"idle_minutes,omitempty"`  // reset after this long without messages
+	DailyAt      string `json:"daily_at,omitempty"`      // reset once a day at this local time, "HH:MM"
+	CarrySummary bool   `json:"carry_summary,omitempty"` // start the new session with the old summary
+}
+
+// ResetFor returns the reset policy of channel, or nil when sessions are
+// never reset.
+func (s SessionConfig) ResetFor(channel string) *SessionResetConfig {
+	if policy, ok := s.ResetChannels[channel]; ok {
+		return &policy
+	}
+	return s.Reset
 }
 
 type AgentDefaults struct {
-	Workspace           string   `json:"workspace" env:"MOBAICLAW_AGENTS_DEFAULTS_WORKSPACE"`
-	RestrictToWorkspace bool     `json:"restrict_to_workspace" env:"MOBAICLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE"`
-	Provider            string   `json:"provider" env:"MOBAICLAW_AGENTS_DEFAULTS_PROVIDER"`
-	Model               string   `json:"model" env:"MOBAICLAW_AGENTS_DEFAULTS_MODEL"`
-	ModelFallbacks      []string `json:"model_fallbacks,omitempty"`
-	ImageModel          string   `json:"image_model,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_IMAGE_MODEL"`
-	ImageModelFallbacks []string `json:"image_model_fallbacks,omitempty"`
-	MaxTokens           int      `json:"max_tokens" env:"MOBAICLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
-	Temperature         *float64 `json:"temperature,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_TEMPERATURE"`
-	MaxToolIterations   int      `json:"max_tool_iterations" env:"MOBAICLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
-	ContextWindow       int      `json:"context_window" env:"MOBAICLAW_AGENTS_DEFAULTS_CONTEXT_WINDOW"`
-	SummaryModel        string   `json:"summary_model,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_SUMMARY_MODEL"`
+	Workspace             string        `json:"workspace" env:"MOBAICLAW_AGENTS_DEFAULTS_WORKSPACE"`
+	RestrictToWorkspace   bool          `json:"restrict_to_workspace" env:"MOBAICLAW_AGENTS_DEFAULTS_RESTRICT_TO_WORKSPACE"`
+	Provider              string        `json:"provider" env:"MOBAICLAW_AGENTS_DEFAULTS_PROVIDER"`
+	Model                 string        `json:"model" env:"MOBAICLAW_AGENTS_DEFAULTS_MODEL"`
+	ModelFallbacks        []string      `json:"model_fallbacks,omitempty"`
+	ImageModel            string        `json:"image_model,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_IMAGE_MODEL"`
+	ImageModelFallbacks   []string      `json:"image_model_fallbacks,omitempty"`
+	MaxTokens             int           `json:"max_tokens" env:"MOBAICLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
+	Temperature           *float64      `json:"temperature,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_TEMPERATURE"`
+	MaxToolIterations     int           `json:"max_tool_iterations" env:"MOBAICLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	ContextWindow         int           `json:"context_window" env:"MOBAICLAW_AGENTS_DEFAULTS_CONTEXT_WINDOW"`
+	SummaryModel          string        `json:"summary_model,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_SUMMARY_MODEL"`
+	MaxConcurrentSessions int           `json:"max_concurrent_sessions,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_MAX_CONCURRENT_SESSIONS"`
+	MaxParallelTools      int           `json:"max_parallel_tools,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_MAX_PARALLEL_TOOLS"`
+	Budget                *BudgetConfig `json:"budget,omitempty"`
+	// ReasoningEffort ("low", "medium" or "high") and ThinkingBudget (tokens)
+	// request extended reasoning from models that support it. Each provider
+	// translates them into its own parameters; unset leaves the model default.
+	ReasoningEffort string `json:"reasoning_effort,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_REASONING_EFFORT"`
+	ThinkingBudget  int    `json:"thinking_budget,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_THINKING_BUDGET"`
+	// ToolLoopThreshold is how many iterations in a row may make identical
+	// tool calls before the agent intervenes (default 3, negative disables).
+	ToolLoopThreshold int `json:"tool_loop_threshold,omitempty" env:"MOBAICLAW_AGENTS_DEFAULTS_TOOL_LOOP_THRESHOLD"`
+}
+
+// BudgetConfig limits the tokens or cost each agent may spend. Zero limits are
+// disabled. Costs are computed from the input_price/output_price of model_list entries.
+type BudgetConfig struct {
+	DailyTokens   int64   `json:"daily_tokens,omitempty"`
+	MonthlyTokens int64   `json:"monthly_tokens,omitempty"`
+	DailyCost     float64 `json:"daily_cost,omitempty"`   // USD
+	MonthlyCost   float64 `json:"monthly_cost,omitempty"` // USD
+	// OnExceeded is "refuse" (default) to stop answering, or "downgrade" to
+	// keep going on the cheapest model in the agent's fallback list.
+	OnExceeded string `json:"on_exceeded,omitempty"`
 }
 
 type ChannelsConfig struct {
@@ -223,6 +338,11 @@ type DiscordConfig struct {
 	Token       string              `json:"token" env:"MOBAICLAW_CHANNELS_DISCORD_TOKEN"`
 	AllowFrom   FlexibleStringSlice `json:"allow_from" env:"MOBAICLAW_CHANNELS_DISCORD_ALLOW_FROM"`
 	MentionOnly bool                `json:"mention_only" env:"MOBAICLAW_CHANNELS_DISCORD_MENTION_ONLY"`
+
+	// Unmentioned guild messages sent to the model with the next mention;
+	// off (0 messages) by default.
+	AmbientMessages      int `json:"ambient_messages" env:"MOBAICLAW_CHANNELS_DISCORD_AMBIENT_MESSAGES"`
+	AmbientMaxAgeMinutes int `json:"ambient_max_age_minutes" env:"MOBAICLAW_CHANNELS_DISCORD_AMBIENT_MAX_AGE_MINUTES"`
 }
 
 type MaixCamConfig struct {
@@ -270,6 +390,11 @@ type OneBotConfig struct {
 	ReconnectInterval  int                 `json:"reconnect_interval" env:"MOBAICLAW_CHANNELS_ONEBOT_RECONNECT_INTERVAL"`
 	GroupTriggerPrefix []string            `json:"group_trigger_prefix" env:"MOBAICLAW_CHANNELS_ONEBOT_GROUP_TRIGGER_PREFIX"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from" env:"MOBAICLAW_CHANNELS_ONEBOT_ALLOW_FROM"`
+
+	// Untriggered group messages sent to the model with the next trigger;
+	// off (0 messages) by default.
+	AmbientMessages      int `json:"ambient_messages" env:"MOBAICLAW_CHANNELS_ONEBOT_AMBIENT_MESSAGES"`
+	AmbientMaxAgeMinutes int `json:"ambient_max_age_minutes" env:"MOBAICLAW_CHANNELS_ONEBOT_AMBIENT_MAX_AGE_MINUTES"`
 }
 
 type WeComConfig struct {
@@ -396,6 +521,10 @@ type ModelConfig struct {
 	// Optional optimizations
 	RPM            int    `json:"rpm,omitempty"`              // Requests per minute limit
 	MaxTokensField string `json:"max_tokens_field,omitempty"` // Field name for max tokens (e.g., "max_completion_tokens")
+
+	// Pricing in USD per million tokens, used for usage reports and cost budgets
+	InputPrice  float64 `json:"input_price,omitempty"`
+	OutputPrice float64 `json:"output_price,omitempty"`
 }
 
 // Validate checks if the ModelConfig has all required fields.
@@ -412,6 +541,28 @@ func (c *ModelConfig) Validate() error {
 type GatewayConfig struct {
 	Host string `json:"host" env:"MOBAICLAW_GATEWAY_HOST"`
 	Port int    `json:"port" env:"MOBAICLAW_GATEWAY_PORT"`
+
+	// Admins may use admin commands such as /sessions, and answer tool
+	// approvals asked for by other users. Entries are sender IDs, optionally
+	// prefixed with their channel ("telegram:123456"). With an empty list
+	// admin commands are only available from the local CLI.
+	Admins FlexibleStringSlice `json:"admins,omitempty" env:"MOBAICLAW_GATEWAY_ADMINS"`
+}
+
+// IsAdmin reports whether senderID on channel is listed in Admins. Sender
+// IDs of the form "id|username" match on either part.
+func (g GatewayConfig) IsAdmin(channel, senderID string) bool {
+	id, user, _ := strings.Cut(senderID, "|")
+	for _, admin := range g.Admins {
+		if prefix, rest, ok := strings.Cut(admin, ":"); ok && prefix == channel {
+			admin = rest
+		}
+		admin = strings.TrimPrefix(admin, "@")
+		if admin == senderID || admin == id || (user != "" && admin == user) {
+			return true
+		}
+	}
+	return false
 }
 
 type BraveConfig struct {
@@ -446,11 +597,38 @@ type ExecConfig struct {
 	CustomDenyPatterns []string `json:"custom_deny_patterns" env:"MOBAICLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS"`
 }
 
+// ApprovalConfig makes matching tool calls wait until the user approves them
+// in the chat the turn came from.
+type ApprovalConfig struct {
+	Enabled        bool           `json:"enabled" env:"MOBAICLAW_TOOLS_APPROVAL_ENABLED"`
+	TimeoutSeconds int            `json:"timeout_seconds,omitempty" env:"MOBAICLAW_TOOLS_APPROVAL_TIMEOUT_SECONDS"`
+	Rules          []ApprovalRule `json:"rules,omitempty"`
+}
+
+// ApprovalRule marks calls of a tool as requiring approval.
+type ApprovalRule struct {
+	Tool string `json:"tool"`
+	// Args maps argument names to regular expressions that must all match.
+	Args map[string]string `json:"args,omitempty"`
+	// AllowedPaths exempts calls whose "path" argument lies inside one of
+	// these directories. Relative entries are resolved against the workspace.
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+}
+
 type ToolsConfig struct {
-	Web    WebToolsConfig    `json:"web"`
-	Cron   CronToolsConfig   `json:"cron"`
-	Exec   ExecConfig        `json:"exec"`
-	Skills SkillsToolsConfig `json:"skills"`
+	Web      WebToolsConfig    `json:"web"`
+	Cron     CronToolsConfig   `json:"cron"`
+	Exec     ExecConfig        `json:"exec"`
+	Skills   SkillsToolsConfig `json:"skills"`
+	Approval ApprovalConfig    `json:"approval"`
+	History  HistoryToolConfig `json:"history"`
+}
+
+// HistoryToolConfig configures the history_search tool.
+type HistoryToolConfig struct {
+	// AllChats lets searches see the conversations of every chat served by
+	// the agent instead of only the current one.
+	AllChats bool `json:"all_chats" env:"MOBAICLAW_TOOLS_HISTORY_ALL_CHATS"`
 }
 
 type SkillsToolsConfig struct {
@@ -530,6 +708,12 @@ func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// EncryptionKeyFile returns the path of the encryption key file, or "" when
+// none is configured.
+func (c *Config) EncryptionKeyFile() string {
+	return expandHome(c.Encryption.KeyFile)
+}
+
 func (c *Config) GetAPIKey() string {
 	if c.Providers.OpenRouter.APIKey != "" {
 		return c.Providers.OpenRouter.APIKey
@@ -608,6 +792,23 @@ func (c *Config) GetModelConfig(modelName string) (*ModelConfig, error) {
 	return &matches[idx], nil
 }
 
+// GetModelPrice returns the configured input/output price (USD per million
+// tokens) of a model, matched by model_name or by the model identifier with or
+// without its protocol prefix. Unknown models are free.
+func (c *Config) GetModelPrice(model string) (input, output float64) {
+	for i := range c.ModelList {
+		mc := &c.ModelList[i]
+		if mc.InputPrice == 0 && mc.OutputPrice == 0 {
+			continue
+		}
+		_, id, _ := strings.Cut(mc.Model, "/")
+		if mc.ModelName == model || mc.Model == model || id == model {
+			return mc.InputPrice, mc.OutputPrice
+		}
+	}
+	return 0, 0
+}
+
 // findMatches finds all ModelConfig entries with the given model_name.
 func (c *Config) findMatches(modelName string) []ModelConfig {
 	var matches []ModelConfig
@@ -10,14 +10,16 @@ func DefaultConfig() *Config {
 	return &Config{
 		Agents: AgentsConfig{
 			Defaults: AgentDefaults{
-				Workspace:           "~/.mobaiclaw/workspace",
-				RestrictToWorkspace: true,
-				Provider:            "",
-				Model:               "glm-4.7",
-				MaxTokens:           8192,
-				Temperature:         nil, // nil means use provider default
-				MaxToolIterations:   20,
-				ContextWindow:       32768,
+				Workspace:             "~/.mobaiclaw/workspace",
+				RestrictToWorkspace:   true,
+				Provider:              "",
+				Model:                 "glm-4.7",
+				MaxTokens:             8192,
+				Temperature:           nil, // nil means use provider default
+				MaxToolIterations:     20,
+				ContextWindow:         32768,
+				MaxConcurrentSessions: 4,
+				MaxParallelTools:      4,
 			},
 		},
 		Bindings: []AgentBinding{},
@@ -48,6 +50,9 @@ func DefaultConfig() *Config {
 				Token:       "",
 				AllowFrom:   FlexibleStringSlice{},
 				MentionOnly: false,
+
+				AmbientMessages:      0,
+				AmbientMaxAgeMinutes: 30,
 			},
 			MaixCam: MaixCamConfig{
 				Enabled:   false,
@@ -89,6 +94,9 @@ func DefaultConfig() *Config {
 				ReconnectInterval:  5,
 				GroupTriggerPrefix: []string{},
 				AllowFrom:          FlexibleStringSlice{},
+
+				AmbientMessages:      0,
+				AmbientMaxAgeMinutes: 30,
 			},
 			WeCom: WeComConfig{
 				Enabled:        false,
@@ -304,6 +312,18 @@ func DefaultConfig() *Config {
 					TTLSeconds: 300,
 				},
 			},
+			Approval: ApprovalConfig{
+				Enabled:        false,
+				TimeoutSeconds: 300,
+				Rules: []ApprovalRule{
+					{Tool: "exec"},
+					{Tool: "write_file", AllowedPaths: []string{"."}},
+					{Tool: "edit_file", AllowedPaths: []string{"."}},
+					{Tool: "append_file", AllowedPaths: []string{"."}},
+					{Tool: "i2c", Args: map[string]string{"action": "^write$"}},
+					{Tool: "spi", Args: map[string]string{"action": "^transfer$"}},
+				},
+			},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
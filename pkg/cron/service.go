@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/adhocore/gronx"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
 )
 
 type CronSchedule struct {
@@ -320,7 +322,7 @@ func (cs *CronService) loadStore() error {
 		Jobs:    []CronJob{},
 	}
 
-	data, err := os.ReadFile(cs.storePath)
+	data, err := crypt.ReadFile(cs.storePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil
@@ -342,7 +344,7 @@ func (cs *CronService) saveStoreUnsafe() error {
 		return err
 	}
 
-	return os.WriteFile(cs.storePath, data, 0600)
+	return crypt.WriteFile(cs.storePath, data, 0600)
 }
 
 func (cs *CronService) AddJob(name string, schedule CronSchedule, message string, deliver bool, channel, to, sessionKey string) (*CronJob, error) {
@@ -1,10 +1,13 @@
 package cron
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
 )
 
 func TestSaveStore_FilePermissions(t *testing.T) {
@@ -33,6 +36,27 @@ func TestSaveStore_FilePermissions(t *testing.T) {
 	}
 }
 
+func TestStore_EncryptedAtRest(t *testing.T) {
+	if err := crypt.Enable(bytes.Repeat([]byte{9}, crypt.KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	defer crypt.Enable(nil)
+
+	storePath := filepath.Join(t.TempDir(), "cron", "jobs.json")
+	cs := NewCronService(storePath, nil)
+	if _, err := cs.AddJob("pills", CronSchedule{Kind: "every", EveryMS: int64Ptr(60000)}, "take the pills", false, "cli", "direct", ""); err != nil {
+		t.Fatalf("AddJob failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(storePath)
+	if bytes.Contains(data, []byte("pills")) {
+		t.Errorf("cron store holds plaintext: %q", data)
+	}
+	if jobs := NewCronService(storePath, nil).ListJobs(true); len(jobs) != 1 || jobs[0].Name != "pills" {
+		t.Errorf("jobs after reload = %+v", jobs)
+	}
+}
+
 func int64Ptr(v int64) *int64 {
 	return &v
 }
@@ -0,0 +1,255 @@
+// Package crypt encrypts workspace files at rest with ChaCha20-Poly1305.
+//
+// Encryption is off until Enable is given a key. Once on, files written
+// through this package are sealed; files are always read transparently
+// whether they are sealed or still plaintext, so a workspace can be migrated
+// one file at a time.
+package crypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// KeyEnv names the environment variable holding the key, base64 or hex
+// encoded. It takes precedence over the configured key file.
+const KeyEnv = "MOBAICLAW_ENCRYPTION_KEY"
+
+// KeySize is the size of a key in bytes.
+const KeySize = chacha20poly1305.KeySize
+
+// Sealed files start with fileMagic; sealed lines of append-only logs start
+// with lineMagic and hold the base64 of the nonce and ciphertext.
+var (
+	fileMagic = []byte("MCENC\x01")
+	lineMagic = []byte("enc1:")
+)
+
+// ErrNoKey is returned when reading a sealed file while encryption is off.
+var ErrNoKey = errors.New("file is encrypted but no encryption key is configured (set " + KeyEnv + " or encryption.key_file)")
+
+var (
+	mu   sync.RWMutex
+	aead cipher.AEAD
+)
+
+// Enable turns encryption on with key. A nil key turns it off.
+func Enable(key []byte) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if key == nil {
+		aead = nil
+		return nil
+	}
+	c, err := chacha20poly1305.New(key)
+	if err != nil {
+		return err
+	}
+	aead = c
+	return nil
+}
+
+// Enabled reports whether files are written encrypted.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return aead != nil
+}
+
+func current() cipher.AEAD {
+	mu.RLock()
+	defer mu.RUnlock()
+	return aead
+}
+
+// LoadKey returns the key from KeyEnv or, when unset, from keyFile. It
+// returns nil when neither is set. A configured key file that is missing is
+// an error, so a misconfiguration does not silently write plaintext.
+func LoadKey(keyFile string) ([]byte, error) {
+	if v := strings.TrimSpace(os.Getenv(KeyEnv)); v != "" {
+		key, err := decodeKey([]byte(v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", KeyEnv, err)
+		}
+		return key, nil
+	}
+	if keyFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key file: %w", err)
+	}
+	key, err := decodeKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key file %s: %w", keyFile, err)
+	}
+	return key, nil
+}
+
+// decodeKey accepts a key as base64, hex or raw bytes.
+func decodeKey(data []byte) ([]byte, error) {
+	if len(data) == KeySize {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key must be %d bytes, base64 or hex encoded", KeySize)
+}
+
+// GenerateKeyFile writes a new random key to path, base64 encoded and
+// readable only by its owner. It does not overwrite an existing file.
+func GenerateKeyFile(path string) ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return key, err
+}
+
+// IsSealed reports whether data is an encrypted file or log line.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, fileMagic) || bytes.HasPrefix(data, lineMagic)
+}
+
+func seal(c cipher.AEAD, plaintext []byte) []byte {
+	nonce := make([]byte, c.NonceSize(), c.NonceSize()+len(plaintext)+c.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic("crypt: reading random nonce: " + err.Error())
+	}
+	return c.Seal(nonce, nonce, plaintext, nil)
+}
+
+func open(c cipher.AEAD, sealed []byte) ([]byte, error) {
+	if c == nil {
+		return nil, ErrNoKey
+	}
+	if len(sealed) < c.NonceSize()+c.Overhead() {
+		return nil, errors.New("crypt: encrypted data is truncated")
+	}
+	plaintext, err := c.Open(nil, sealed[:c.NonceSize()], sealed[c.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("crypt: cannot decrypt (wrong key or corrupted file)")
+	}
+	return plaintext, nil
+}
+
+// Seal encrypts the contents of a file, or returns them unchanged when
+// encryption is off.
+func Seal(plaintext []byte) []byte {
+	c := current()
+	if c == nil {
+		return plaintext
+	}
+	return append(append([]byte{}, fileMagic...), seal(c, plaintext)...)
+}
+
+// Open decrypts the contents of a file written by Seal. Plaintext is
+// returned unchanged.
+func Open(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, fileMagic) {
+		return data, nil
+	}
+	return open(current(), data[len(fileMagic):])
+}
+
+// SealLine encrypts one line of an append-only log into a line of text, or
+// returns it unchanged when encryption is off. line must not hold the
+// trailing newline.
+func SealLine(line []byte) []byte {
+	c := current()
+	if c == nil {
+		return line
+	}
+	sealed := seal(c, line)
+	out := make([]byte, len(lineMagic)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, lineMagic)
+	base64.StdEncoding.Encode(out[len(lineMagic):], sealed)
+	return out
+}
+
+// OpenLine decrypts a line written by SealLine. Plaintext lines are returned
+// unchanged.
+func OpenLine(line []byte) ([]byte, error) {
+	if !bytes.HasPrefix(line, lineMagic) {
+		return line, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(line[len(lineMagic):]))
+	if err != nil {
+		return nil, fmt.Errorf("crypt: malformed encrypted line: %w", err)
+	}
+	return open(current(), sealed)
+}
+
+// ReadFile reads a file that may be encrypted.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Open(data)
+}
+
+// WriteFile writes a file, encrypted when encryption is on. It writes a temp
+// file and renames it over path, so a crash never leaves a truncated file and
+// perm applies even when path already exists.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(Seal(data)); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(perm); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
@@ -0,0 +1,115 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func enableTestKey(t *testing.T, key []byte) {
+	t.Helper()
+	if err := Enable(key); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	t.Cleanup(func() { Enable(nil) })
+}
+
+func TestSealOpen_RoundTrip(t *testing.T) {
+	plain := []byte(`{"key":"telegram:1"}`)
+	if got := Seal(plain); !bytes.Equal(got, plain) {
+		t.Fatal("Seal should pass data through while encryption is off")
+	}
+
+	enableTestKey(t, bytes.Repeat([]byte{7}, KeySize))
+	sealed := Seal(plain)
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("telegram")) {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	if got, err := Open(sealed); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if got, err := Open(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Open of plaintext = %q, %v", got, err)
+	}
+
+	line := SealLine(plain)
+	if bytes.ContainsAny(line, "\n") || !IsSealed(line) {
+		t.Fatalf("sealed line = %q", line)
+	}
+	if got, err := OpenLine(line); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("OpenLine = %q, %v", got, err)
+	}
+
+	enableTestKey(t, bytes.Repeat([]byte{8}, KeySize))
+	if _, err := Open(sealed); err == nil {
+		t.Error("Open with the wrong key should fail")
+	}
+	Enable(nil)
+	if _, err := OpenLine(line); err != ErrNoKey {
+		t.Errorf("OpenLine without a key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	if key, err := LoadKey(""); key != nil || err != nil {
+		t.Fatalf("LoadKey without a key = %v, %v", key, err)
+	}
+	if _, err := LoadKey(filepath.Join(t.TempDir(), "missing.key")); err == nil {
+		t.Error("a configured key file that is missing should be an error")
+	}
+
+	path := filepath.Join(t.TempDir(), "encryption.key")
+	generated, err := GenerateKeyFile(path)
+	if err != nil {
+		t.Fatalf("GenerateKeyFile: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v", info.Mode().Perm())
+	}
+	if key, err := LoadKey(path); err != nil || !bytes.Equal(key, generated) {
+		t.Errorf("LoadKey(file) = %x, %v", key, err)
+	}
+	if _, err := GenerateKeyFile(path); err == nil {
+		t.Error("GenerateKeyFile should not overwrite a key")
+	}
+
+	envKey := bytes.Repeat([]byte{1}, KeySize)
+	t.Setenv(KeyEnv, hex.EncodeToString(envKey))
+	if key, err := LoadKey(path); err != nil || !bytes.Equal(key, envKey) {
+		t.Errorf("LoadKey should prefer %s: %x, %v", KeyEnv, key, err)
+	}
+	t.Setenv(KeyEnv, "too-short")
+	if _, err := LoadKey(""); err == nil {
+		t.Error("a malformed key should be an error")
+	}
+}
+
+func TestWriteFile_ReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "MEMORY.md")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	enableTestKey(t, bytes.Repeat([]byte{7}, KeySize))
+	if err := WriteFile(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if got, err := ReadFile(path); err != nil || string(got) != "new" {
+		t.Errorf("ReadFile = %q, %v", got, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("mode = %o, want 600 even for an existing file", perm)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("dir has %d entries, want no leftover temp files", len(entries))
+	}
+}
@@ -1,6 +1,10 @@
 package events
 
-import "context"
+import (
+	"context"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
+)
 
 type EventSource interface {
 	Kind() Kind
@@ -36,22 +40,21 @@ type DeviceEvent struct {
 	Raw          map[string]string // Raw properties for extensibility
 }
 
-func (e *DeviceEvent) FormatMessage() string {
-	actionEmoji := "🔌"
-	actionText := "Connected"
+// FormatMessage describes the event for the user in locale.
+func (e *DeviceEvent) FormatMessage(locale string) string {
+	title := i18n.T(locale, "device.connected")
 	if e.Action == ActionRemove {
-		actionEmoji = "🔌"
-		actionText = "Disconnected"
+		title = i18n.T(locale, "device.disconnected")
 	}
 
-	msg := actionEmoji + " Device " + actionText + "\n\n"
-	msg += "Type: " + string(e.Kind) + "\n"
-	msg += "Device: " + e.Vendor + " " + e.Product + "\n"
+	msg := title + "\n\n"
+	msg += i18n.T(locale, "device.type", string(e.Kind)) + "\n"
+	msg += i18n.T(locale, "device.device", e.Vendor+" "+e.Product) + "\n"
 	if e.Capabilities != "" {
-		msg += "Capabilities: " + e.Capabilities + "\n"
+		msg += i18n.T(locale, "device.capabilities", e.Capabilities) + "\n"
 	}
 	if e.Serial != "" {
-		msg += "Serial: " + e.Serial + "\n"
+		msg += i18n.T(locale, "device.serial", e.Serial) + "\n"
 	}
 	return msg
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/zhaopengme/mobaiclaw/pkg/constants"
 	"github.com/zhaopengme/mobaiclaw/pkg/devices/events"
 	"github.com/zhaopengme/mobaiclaw/pkg/devices/sources"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/state"
 )
@@ -17,6 +18,7 @@ type Service struct {
 	bus     *bus.MessageBus
 	state   *state.Manager
 	sources []events.EventSource
+	locale  func(channel, chatID string) string
 	enabled bool
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -49,6 +51,14 @@ func (s *Service) SetBus(msgBus *bus.MessageBus) {
 	s.bus = msgBus
 }
 
+// SetLocaleFunc sets how the language of a notification is chosen from the
+// chat it is sent to.
+func (s *Service) SetLocaleFunc(fn func(channel, chatID string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locale = fn
+}
+
 func (s *Service) Start(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -107,6 +117,7 @@ func (s *Service) handleEvents(kind events.Kind, eventCh <-chan *events.DeviceEv
 func (s *Service) sendNotification(ev *events.DeviceEvent) {
 	s.mu.RLock()
 	msgBus := s.bus
+	localeFn := s.locale
 	s.mu.RUnlock()
 
 	if msgBus == nil {
@@ -116,7 +127,7 @@ func (s *Service) sendNotification(ev *events.DeviceEvent) {
 	lastChannel := s.state.GetLastChannel()
 	if lastChannel == "" {
 		logger.DebugCF("devices", "No last channel, skipping notification", map[string]interface{}{
-			"event": ev.FormatMessage(),
+			"event": ev.FormatMessage(i18n.Default),
 		})
 		return
 	}
@@ -126,7 +137,11 @@ func (s *Service) sendNotification(ev *events.DeviceEvent) {
 		return
 	}
 
-	msg := ev.FormatMessage()
+	locale := i18n.Default
+	if localeFn != nil {
+		locale = localeFn(platform, userID)
+	}
+	msg := ev.FormatMessage(locale)
 	msgBus.PublishOutbound(bus.OutboundMessage{
 		Channel: platform,
 		ChatID:  userID,
@@ -0,0 +1,209 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ToolCall is a tool call the LLM made during a turn.
+type ToolCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// Turn is what one step produced.
+type Turn struct {
+	Reply      string
+	ToolCalls  []ToolCall
+	Iterations int
+}
+
+// Check returns a description of every expectation the turn does not meet.
+func (e Expect) Check(turn Turn) []string {
+	var failures []string
+	for _, s := range e.Contains {
+		if !strings.Contains(turn.Reply, s) {
+			failures = append(failures, fmt.Sprintf("reply does not contain %q", s))
+		}
+	}
+	for _, s := range e.NotContains {
+		if strings.Contains(turn.Reply, s) {
+			failures = append(failures, fmt.Sprintf("reply contains %q", s))
+		}
+	}
+	for _, pattern := range e.Regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid regex %q: %v", pattern, err))
+		} else if !re.MatchString(turn.Reply) {
+			failures = append(failures, fmt.Sprintf("reply does not match /%s/", pattern))
+		}
+	}
+
+	if len(e.JSON) > 0 {
+		doc, ok := extractJSON(turn.Reply)
+		if !ok {
+			failures = append(failures, "reply contains no JSON")
+		}
+		for _, a := range e.JSON {
+			if !ok {
+				break
+			}
+			value, found := lookupPath(doc, a.Path)
+			switch {
+			case !found:
+				failures = append(failures, fmt.Sprintf("JSON path %s not found", a.Path))
+			case a.Equals != nil && !sameJSON(value, a.Equals):
+				failures = append(failures, fmt.Sprintf("JSON path %s is %s, want %s", a.Path, toJSON(value), toJSON(a.Equals)))
+			}
+		}
+	}
+
+	for _, a := range e.Tools {
+		n := 0
+		for _, call := range turn.ToolCalls {
+			if call.Name == a.Name && argsMatch(call.Args, a.Args) {
+				n++
+			}
+		}
+		switch {
+		case a.Times > 0 && n != a.Times:
+			failures = append(failures, fmt.Sprintf("tool %s%s called %d times, want %d", a.Name, describeArgs(a.Args), n, a.Times))
+		case n == 0:
+			failures = append(failures, fmt.Sprintf("tool %s%s was not called (calls: %s)", a.Name, describeArgs(a.Args), describeCalls(turn.ToolCalls)))
+		}
+	}
+	for _, name := range e.NoTools {
+		for _, call := range turn.ToolCalls {
+			if name == "*" || call.Name == name {
+				failures = append(failures, fmt.Sprintf("tool %s must not be called", call.Name))
+				break
+			}
+		}
+	}
+
+	if e.MinIterations > 0 && turn.Iterations < e.MinIterations {
+		failures = append(failures, fmt.Sprintf("took %d iterations, want at least %d", turn.Iterations, e.MinIterations))
+	}
+	if e.MaxIterations > 0 && turn.Iterations > e.MaxIterations {
+		failures = append(failures, fmt.Sprintf("took %d iterations, want at most %d", turn.Iterations, e.MaxIterations))
+	}
+	return failures
+}
+
+var jsonFence = regexp.MustCompile("(?s)```(?:json)?\\s*\\n(.*?)```")
+
+// extractJSON finds the JSON document in a reply: the whole reply, a fenced
+// code block, or the outermost object or array in the text.
+func extractJSON(text string) (interface{}, bool) {
+	candidates := []string{strings.TrimSpace(text)}
+	for _, m := range jsonFence.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, m[1])
+	}
+	for _, pair := range []string{"{}", "[]"} {
+		start, end := strings.IndexByte(text, pair[0]), strings.LastIndexByte(text, pair[1])
+		if start >= 0 && end > start {
+			candidates = append(candidates, text[start:end+1])
+		}
+	}
+	for _, c := range candidates {
+		var doc interface{}
+		if json.Unmarshal([]byte(c), &doc) == nil {
+			switch doc.(type) {
+			case map[string]interface{}, []interface{}:
+				return doc, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookupPath resolves a path such as "$.items[0].name" in a decoded document.
+func lookupPath(doc interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return doc, true
+	}
+	cur := doc
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []string
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+			for _, idx := range strings.Split(part[i+1:], "[") {
+				indexes = append(indexes, strings.TrimSuffix(idx, "]"))
+			}
+		}
+		if key != "" {
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = obj[key]; !ok {
+				return nil, false
+			}
+		}
+		for _, idx := range indexes {
+			arr, ok := cur.([]interface{})
+			n, err := strconv.Atoi(idx)
+			if !ok || err != nil || n < 0 || n >= len(arr) {
+				return nil, false
+			}
+			cur = arr[n]
+		}
+	}
+	return cur, true
+}
+
+// argsMatch reports whether every expected argument has the expected value.
+func argsMatch(args, want map[string]interface{}) bool {
+	for k, v := range want {
+		got, ok := args[k]
+		if !ok || !sameJSON(got, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameJSON compares values by their JSON form, so that numbers decoded from
+// YAML and from a tool call compare equal.
+func sameJSON(a, b interface{}) bool {
+	var na, nb interface{}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil || json.Unmarshal(ja, &na) != nil || json.Unmarshal(jb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func describeArgs(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return toJSON(args)
+}
+
+func describeCalls(calls []ToolCall) string {
+	if len(calls) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(calls))
+	for i, c := range calls {
+		parts[i] = c.Name + toJSON(c.Args)
+	}
+	return strings.Join(parts, ", ")
+}
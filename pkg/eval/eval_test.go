@@ -0,0 +1,183 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/agent"
+	"github.com/zhaopengme/mobaiclaw/pkg/bus"
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/tools"
+)
+
+// weatherProvider looks the weather up once, then answers in JSON.
+type weatherProvider struct{}
+
+func (p *weatherProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	if last.Role == "user" {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+			ID:        "call_1",
+			Type:      "function",
+			Name:      "weather",
+			Arguments: map[string]interface{}{"city": "Paris", "days": 1},
+		}}}, nil
+	}
+	return &providers.LLMResponse{Content: "Here you go:\n```json\n{\"city\": \"Paris\", \"forecast\": [{\"temp\": " + last.Content + "}]}\n```"}, nil
+}
+
+func (p *weatherProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+type weatherTool struct{}
+
+func (t *weatherTool) Name() string        { return "weather" }
+func (t *weatherTool) Description() string { return "looks up the weather" }
+func (t *weatherTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (t *weatherTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	return tools.NewToolResult("21")
+}
+
+func newTestRunner(t *testing.T) *Runner {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := agent.NewAgentLoop(cfg, bus.NewMessageBus(), &weatherProvider{})
+	al.RegisterTool(&weatherTool{})
+	return NewRunner(al)
+}
+
+func TestLoadScenarios_YAMLAndJSON(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(`
+scenarios:
+  - name: weather
+    steps:
+      - user: "weather in Paris?"
+        expect:
+          contains: [Paris]
+          tools:
+            - name: weather
+              args: {city: Paris}
+  - steps:
+      - user: hi
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"steps": [{"user": "hello", "expect": {"max_iterations": 1}}]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+	scenarios, err := LoadScenarios(dir)
+	if err != nil {
+		t.Fatalf("LoadScenarios: %v", err)
+	}
+	var names []string
+	for _, s := range scenarios {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "weather,a#2,b" {
+		t.Fatalf("scenario names = %s", got)
+	}
+	if scenarios[0].Steps[0].Expect.Tools[0].Args["city"] != "Paris" {
+		t.Errorf("tool args not loaded: %+v", scenarios[0].Steps[0].Expect.Tools)
+	}
+	if scenarios[2].Steps[0].Expect.MaxIterations != 1 {
+		t.Errorf("JSON scenario not loaded: %+v", scenarios[2])
+	}
+}
+
+func TestLoadScenarios_RejectsEmptySteps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.yaml")
+	os.WriteFile(path, []byte("name: empty\n"), 0644)
+	if _, err := LoadScenarios(path); err == nil {
+		t.Error("expected an error for a scenario without steps")
+	}
+}
+
+func TestExpect_Check(t *testing.T) {
+	turn := Turn{
+		Reply:      "Result: {\"items\": [{\"name\": \"a\", \"n\": 2}]} done",
+		ToolCalls:  []ToolCall{{Name: "exec", Args: map[string]interface{}{"command": "ls", "timeout": 5.0}}},
+		Iterations: 2,
+	}
+
+	pass := Expect{
+		Contains:      []string{"Result"},
+		NotContains:   []string{"error"},
+		Regex:         []string{`done$`},
+		JSON:          []JSONAssertion{{Path: "$.items[0].name", Equals: "a"}, {Path: "items[0].n", Equals: 2}, {Path: "items"}},
+		Tools:         []ToolAssertion{{Name: "exec", Args: map[string]interface{}{"timeout": 5}, Times: 1}},
+		NoTools:       []string{"write_file"},
+		MinIterations: 2,
+		MaxIterations: 2,
+	}
+	if failures := pass.Check(turn); len(failures) != 0 {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+
+	fail := Expect{
+		Contains:      []string{"missing"},
+		Regex:         []string{`^done`},
+		JSON:          []JSONAssertion{{Path: "items[1]"}, {Path: "items[0].name", Equals: "b"}},
+		Tools:         []ToolAssertion{{Name: "exec", Args: map[string]interface{}{"command": "pwd"}}},
+		NoTools:       []string{"*"},
+		MaxIterations: 1,
+	}
+	if failures := fail.Check(turn); len(failures) != 7 {
+		t.Errorf("got %d failures, want 7: %v", len(failures), failures)
+	}
+}
+
+func TestRunner_ObservesToolCallsAndIterations(t *testing.T) {
+	r := newTestRunner(t)
+	scenario := Scenario{Name: "Weather check", Steps: []Step{{
+		User: "weather in Paris?",
+		Expect: Expect{
+			Contains:      []string{"Paris"},
+			JSON:          []JSONAssertion{{Path: "forecast[0].temp", Equals: 21}},
+			Tools:         []ToolAssertion{{Name: "weather", Args: map[string]interface{}{"city": "Paris", "days": 1}}},
+			MaxIterations: 2,
+			MinIterations: 2,
+		},
+	}}}
+
+	result := r.Run(context.Background(), scenario)
+	if !result.Passed() {
+		t.Fatalf("scenario failed: %v %v", result.Err, result.Failures)
+	}
+
+	scenario.Steps[0].Expect = Expect{NoTools: []string{"weather"}, MaxIterations: 1}
+	result = r.Run(context.Background(), scenario)
+	if len(result.Failures) != 2 {
+		t.Errorf("got failures %v, want a tool and an iteration failure", result.Failures)
+	}
+
+	var sb strings.Builder
+	if failed := WriteReport(&sb, []Result{result}); failed != 1 {
+		t.Errorf("WriteReport counted %d failures, want 1", failed)
+	}
+	if !strings.Contains(sb.String(), "✗ Weather check") || !strings.Contains(sb.String(), "0 passed, 1 failed") {
+		t.Errorf("unexpected report:\n%s", sb.String())
+	}
+}
+
+func TestRunner_UnknownAgent(t *testing.T) {
+	r := newTestRunner(t)
+	result := r.Run(context.Background(), Scenario{Name: "x", Agent: "nobody", Steps: []Step{{User: "hi"}}})
+	if result.Err == nil || result.Passed() {
+		t.Error("expected an error for an unknown agent")
+	}
+}
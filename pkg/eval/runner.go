@@ -0,0 +1,145 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/agent"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+// Result is the outcome of one scenario.
+type Result struct {
+	Scenario Scenario
+	Failures []string // "step N: ..." per unmet expectation
+	Err      error    // the scenario could not be run to the end
+	Reply    string   // reply of the last step that ran
+	Duration time.Duration
+}
+
+// Passed reports whether every step ran and met its expectations.
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Runner plays scenarios through an agent loop. Each scenario runs in its own
+// session, which is deleted before and, unless KeepSessions is set, after it.
+type Runner struct {
+	loop         *agent.AgentLoop
+	KeepSessions bool
+
+	mu    sync.Mutex
+	turns map[string]*Turn // session key -> turn in progress
+}
+
+// NewRunner returns a runner for al. It registers a hook on al that observes
+// tool calls and iterations of the turns it runs.
+func NewRunner(al *agent.AgentLoop) *Runner {
+	r := &Runner{loop: al, turns: make(map[string]*Turn)}
+	al.AddHook("eval", r.observe, agent.HookAfterLLM, agent.HookBeforeTool)
+	return r
+}
+
+func (r *Runner) observe(ctx context.Context, event *agent.HookEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	turn, ok := r.turns[event.SessionKey]
+	if !ok {
+		return nil
+	}
+	switch event.Stage {
+	case agent.HookAfterLLM:
+		if event.Iteration > turn.Iterations {
+			turn.Iterations = event.Iteration
+		}
+	case agent.HookBeforeTool:
+		turn.ToolCalls = append(turn.ToolCalls, ToolCall{Name: event.ToolCall.Name, Args: event.ToolCall.Arguments})
+	}
+	return nil
+}
+
+// Run plays every step of s and checks its expectations. A step whose
+// ProcessDirect call fails ends the scenario.
+func (r *Runner) Run(ctx context.Context, s Scenario) Result {
+	start := time.Now()
+	result := Result{Scenario: s}
+	defer func() { result.Duration = time.Since(start) }()
+
+	inst := r.loop.GetRegistry().GetDefaultAgent()
+	if s.Agent != "" {
+		var ok bool
+		if inst, ok = r.loop.GetRegistry().GetAgent(s.Agent); !ok {
+			result.Err = fmt.Errorf("unknown agent %q", s.Agent)
+			return result
+		}
+	}
+	sessionKey := fmt.Sprintf("agent:%s:eval-%s", inst.ID, slug(s.Name))
+	inst.Sessions.Delete(sessionKey)
+	if !r.KeepSessions {
+		defer inst.Sessions.Delete(sessionKey)
+	}
+
+	for i, step := range s.Steps {
+		turn := &Turn{}
+		r.mu.Lock()
+		r.turns[sessionKey] = turn
+		r.mu.Unlock()
+
+		reply, err := r.loop.ProcessDirect(ctx, step.User, sessionKey)
+
+		r.mu.Lock()
+		delete(r.turns, sessionKey)
+		r.mu.Unlock()
+
+		if err != nil {
+			result.Err = fmt.Errorf("step %d: %w", i+1, err)
+			return result
+		}
+		turn.Reply = reply
+		result.Reply = reply
+		for _, f := range step.Expect.Check(*turn) {
+			result.Failures = append(result.Failures, fmt.Sprintf("step %d: %s", i+1, f))
+		}
+	}
+	return result
+}
+
+var slugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(name string) string {
+	s := strings.Trim(slugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if s == "" {
+		return "scenario"
+	}
+	return s
+}
+
+// WriteReport prints one line per scenario, the failures of those that did
+// not pass and a summary. It returns the number of failed scenarios.
+func WriteReport(w io.Writer, results []Result) int {
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(w, "✓ %s (%s)\n", r.Scenario.Name, r.Duration.Round(time.Millisecond))
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "✗ %s (%s) [%s]\n", r.Scenario.Name, r.Duration.Round(time.Millisecond), r.Scenario.File)
+		if r.Err != nil {
+			fmt.Fprintf(w, "    error: %v\n", r.Err)
+		}
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "    %s\n", f)
+		}
+		if r.Reply != "" {
+			fmt.Fprintf(w, "    reply: %s\n", utils.Truncate(strings.ReplaceAll(r.Reply, "\n", " "), 200))
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d total\n", len(results)-failed, failed, len(results))
+	return failed
+}
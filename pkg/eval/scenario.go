@@ -0,0 +1,145 @@
+// Package eval runs scripted conversations through the agent loop and checks
+// the replies, tool calls and iteration counts against expectations.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a conversation with one agent, played from a fresh session.
+type Scenario struct {
+	Name  string `yaml:"name"`
+	Agent string `yaml:"agent"` // agent ID, the default agent when empty
+	Steps []Step `yaml:"steps"`
+
+	File string `yaml:"-"` // file the scenario was loaded from
+}
+
+// Step is one user message and what the turn it starts must produce.
+type Step struct {
+	User   string `yaml:"user"`
+	Expect Expect `yaml:"expect"`
+}
+
+// Expect lists the assertions on one turn. Empty fields are not checked.
+type Expect struct {
+	Contains      []string        `yaml:"contains"`
+	NotContains   []string        `yaml:"not_contains"`
+	Regex         []string        `yaml:"regex"`
+	JSON          []JSONAssertion `yaml:"json"`
+	Tools         []ToolAssertion `yaml:"tools"`
+	NoTools       []string        `yaml:"no_tools"` // tools that must not be called, "*" for any
+	MinIterations int             `yaml:"min_iterations"`
+	MaxIterations int             `yaml:"max_iterations"`
+}
+
+// JSONAssertion checks a value in the JSON object or array found in the reply.
+// Path is dotted with optional indexes, e.g. "items[0].name". Without Equals
+// the path only has to exist.
+type JSONAssertion struct {
+	Path   string      `yaml:"path"`
+	Equals interface{} `yaml:"equals"`
+}
+
+// ToolAssertion requires a tool to be called with at least the given
+// arguments. Times, when set, is the exact number of matching calls.
+type ToolAssertion struct {
+	Name  string                 `yaml:"name"`
+	Args  map[string]interface{} `yaml:"args"`
+	Times int                    `yaml:"times"`
+}
+
+// scenarioFile is the layout of a file holding several scenarios.
+type scenarioFile struct {
+	Scenarios []Scenario `yaml:"scenarios"`
+}
+
+// LoadScenarios reads scenarios from YAML or JSON files. Directories are
+// searched for *.yaml, *.yml and *.json files. A file holds either a single
+// scenario or a "scenarios" list.
+func LoadScenarios(paths ...string) ([]Scenario, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					found = append(found, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	var scenarios []Scenario
+	for _, file := range files {
+		loaded, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, loaded...)
+	}
+	return scenarios, nil
+}
+
+func loadFile(path string) ([]Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, so one decoder reads both formats.
+	var multi scenarioFile
+	if err := yaml.Unmarshal(data, &multi); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	scenarios := multi.Scenarios
+	if len(scenarios) == 0 {
+		var single Scenario
+		if err := yaml.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		scenarios = []Scenario{single}
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for i := range scenarios {
+		s := &scenarios[i]
+		s.File = path
+		if s.Name == "" {
+			s.Name = base
+			if len(scenarios) > 1 {
+				s.Name = fmt.Sprintf("%s#%d", base, i+1)
+			}
+		}
+		if len(s.Steps) == 0 {
+			return nil, fmt.Errorf("%s: scenario %q has no steps", path, s.Name)
+		}
+		for j, step := range s.Steps {
+			if strings.TrimSpace(step.User) == "" {
+				return nil, fmt.Errorf("%s: scenario %q step %d has no user message", path, s.Name, j+1)
+			}
+		}
+	}
+	return scenarios, nil
+}
@@ -2,14 +2,20 @@ package gateway
 
 import (
 	"context"
-	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/zhaopengme/mobaiclaw/pkg/agent"
 	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 	"github.com/zhaopengme/mobaiclaw/pkg/channels"
+	"github.com/zhaopengme/mobaiclaw/pkg/i18n"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 	"github.com/zhaopengme/mobaiclaw/pkg/routing"
+	"github.com/zhaopengme/mobaiclaw/pkg/session"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
 )
 
 // ReloadCallback is the function signature for the /reload command handler.
@@ -20,6 +26,7 @@ type CommandGateway struct {
 	channelManager *channels.Manager
 	agentBus       bus.Broker           // for forwarding to agent
 	agentRegistry  *agent.AgentRegistry // to fetch models
+	agentLoop      *agent.AgentLoop     // to control running turns (e.g. /stop)
 	reloadCallback ReloadCallback       // handler for /reload command
 }
 
@@ -33,6 +40,11 @@ func NewCommandGateway(b bus.Broker, agentBus bus.Broker, cm *channels.Manager,
 	}
 }
 
+// SetAgentLoop attaches the agent loop so commands can act on running turns.
+func (g *CommandGateway) SetAgentLoop(al *agent.AgentLoop) {
+	g.agentLoop = al
+}
+
 func (g *CommandGateway) Run(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -58,6 +70,9 @@ func (g *CommandGateway) Run(ctx context.Context) error {
 
 		if strings.HasPrefix(msg.Content, "/") {
 			if response, handled := g.handleCommand(ctx, msg); handled {
+				// Media is handed over to whoever handles the message; the
+				// agent never sees this one
+				utils.RemoveMedia(msg.Media)
 				if response != "" {
 					g.bus.PublishOutbound(bus.OutboundMessage{
 						Channel: msg.Channel,
@@ -94,144 +109,470 @@ func (g *CommandGateway) handleCommand(ctx context.Context, msg bus.InboundMessa
 	cmd := parts[0]
 	args := parts[1:]
 
+	locale := g.locale(msg)
+	t := func(key string, args ...interface{}) string {
+		return i18n.T(locale, key, args...)
+	}
+
 	switch cmd {
 	case "/start":
-		return "Hello! I am MobaiClaw 🦞", true
+		return t("cmd.start"), true
 
 	case "/help":
-		return `/start - Start the bot
-/clear - Clear current session history and summary
-/reload - Reload agent configuration (agents.*, bindings, providers). Note: Active conversations will be reset.
-/help - Show this help message
-/show [model|channel|agents] - Show current configuration
-/list [models|channels|agents] - List available options
-/switch [model|channel] to <name> - Switch current model or channel`, true
+		return t("cmd.help"), true
 
 	case "/clear":
 		if g.agentRegistry == nil {
-			return "agent registry not available", true
-		}
-		// Route to find the correct agent and session key
-		route := g.agentRegistry.ResolveRoute(routing.RouteInput{
-			Channel:   msg.Channel,
-			AccountID: msg.Metadata["account_id"],
-			Peer:      extractPeer(msg),
-			GuildID:   msg.Metadata["guild_id"],
-			TeamID:    msg.Metadata["team_id"],
-		})
-		agentInst, ok := g.agentRegistry.GetAgent(route.AgentID)
-		if !ok {
-			agentInst = g.agentRegistry.GetDefaultAgent()
+			return t("cmd.agent_registry_missing"), true
 		}
+		agentInst, sessionKey := g.resolveAgentSession(msg)
 		if agentInst == nil || agentInst.Sessions == nil {
-			return "sessions not available", true
+			return t("cmd.sessions_missing"), true
 		}
-		sessionKey := route.SessionKey
 		agentInst.Sessions.SetHistory(sessionKey, []providers.Message{})
 		agentInst.Sessions.SetSummary(sessionKey, "")
 		if err := agentInst.Sessions.Save(sessionKey); err != nil {
-			return fmt.Sprintf("failed to save session: %v", err), true
+			return t("cmd.save_failed", err), true
+		}
+		return t("cmd.clear.done"), true
+
+	case "/undo", "/rewind":
+		if g.agentLoop == nil || g.agentRegistry == nil {
+			return t("cmd.undo.unavailable"), true
+		}
+		n := 1
+		if cmd == "/rewind" {
+			if len(args) < 1 {
+				return t("cmd.rewind.usage"), true
+			}
+			parsed, err := strconv.Atoi(args[0])
+			if err != nil || parsed < 1 {
+				return t("cmd.rewind.invalid"), true
+			}
+			n = parsed
+		}
+		agentInst, sessionKey := g.resolveAgentSession(msg)
+		if agentInst == nil || agentInst.Sessions == nil {
+			return t("cmd.sessions_missing"), true
+		}
+		if g.agentLoop.SessionBusy(sessionKey) {
+			return t("cmd.busy"), true
+		}
+		removed := agentInst.Sessions.RewindTurns(sessionKey, n)
+		if len(removed) == 0 {
+			return t("cmd.undo.nothing"), true
+		}
+		if err := agentInst.Sessions.Save(sessionKey); err != nil {
+			return t("cmd.save_failed", err), true
 		}
-		return "🧹 当前会话已清空，我们可以重新开始了。", true
+		if len(removed) == 1 {
+			return t("cmd.undo.removed_one", utils.Truncate(removed[0].Content, 80)), true
+		}
+		return t("cmd.undo.removed_many", len(removed)), true
+
+	case "/retry":
+		if g.agentLoop == nil || g.agentRegistry == nil || g.agentBus == nil {
+			return t("cmd.retry.unavailable"), true
+		}
+		model := ""
+		if len(args) > 0 {
+			model = args[0]
+			if _, err := g.agentLoop.GetConfig().GetModelConfig(model); err != nil {
+				return t("cmd.unknown_model", model), true
+			}
+		}
+		agentInst, sessionKey := g.resolveAgentSession(msg)
+		if agentInst == nil || agentInst.Sessions == nil {
+			return t("cmd.sessions_missing"), true
+		}
+		if g.agentLoop.SessionBusy(sessionKey) {
+			return t("cmd.busy"), true
+		}
+		// The files of the last message are gone once its turn is done, and
+		// replaying it without them would answer a different question
+		if agentInst.Sessions.HasAttachments(sessionKey) {
+			return t("cmd.retry.attachments"), true
+		}
+		removed := agentInst.Sessions.RewindTurns(sessionKey, 1)
+		if len(removed) == 0 {
+			return t("cmd.retry.nothing"), true
+		}
+		if err := agentInst.Sessions.Save(sessionKey); err != nil {
+			return t("cmd.save_failed", err), true
+		}
+
+		// Replay the last user message as a fresh turn
+		retry := msg
+		retry.Content = removed[0].Content
+		retry.Media = nil
+		retry.Metadata = make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			retry.Metadata[k] = v
+		}
+		if model != "" {
+			retry.Metadata["model"] = model
+		}
+		g.agentBus.PublishInbound(retry)
+		return "", true
+
+	case "/stop":
+		if g.agentLoop == nil || g.agentRegistry == nil {
+			return t("cmd.stop.unavailable"), true
+		}
+		_, sessionKey := g.resolveAgentSession(msg)
+		if !g.agentLoop.StopSession(sessionKey) {
+			return t("cmd.stop.nothing"), true
+		}
+		return t("cmd.stop.done"), true
+
+	case "/approve", "/deny":
+		if g.agentLoop == nil {
+			return t("cmd.approve.unavailable"), true
+		}
+		if len(args) < 1 {
+			return t("cmd.approve.usage", cmd), true
+		}
+		approved := cmd == "/approve"
+		if !g.agentLoop.ResolveApproval(msg.Channel, msg.ChatID, msg.SenderID, args[0], approved) {
+			return t("cmd.approve.unknown", args[0]), true
+		}
+		if approved {
+			return t("cmd.approve.approved"), true
+		}
+		return t("cmd.approve.denied"), true
+
+	case "/usage":
+		if g.agentLoop == nil || g.agentRegistry == nil {
+			return t("cmd.usage.unavailable"), true
+		}
+		period := ""
+		if len(args) > 0 {
+			period = args[0]
+		}
+		agentInst, sessionKey := g.resolveAgentSession(msg)
+		return g.agentLoop.UsageReport(agentInst, sessionKey, period, g.locale(msg)), true
+
+	case "/export":
+		if g.agentRegistry == nil || g.bus == nil {
+			return t("cmd.registry_missing"), true
+		}
+		format, opts := session.FormatMarkdown, session.ExportOptions{}
+		for _, arg := range args {
+			if arg == "redact" {
+				opts.RedactToolOutput = true
+				continue
+			}
+			f, err := session.ParseExportFormat(arg)
+			if err != nil {
+				return t("cmd.export.usage"), true
+			}
+			format = f
+		}
+		agentInst, sessionKey := g.resolveAgentSession(msg)
+		if agentInst == nil || agentInst.Sessions == nil {
+			return t("cmd.sessions_missing"), true
+		}
+		snap := agentInst.Sessions.Snapshot(sessionKey)
+		if snap == nil || (len(snap.Messages) == 0 && snap.Summary == "") {
+			return t("cmd.export.empty"), true
+		}
+		path, err := exportSession(snap, format, opts)
+		if err != nil {
+			return t("cmd.export.failed", err), true
+		}
+		g.bus.PublishOutbound(bus.OutboundMessage{
+			Channel:  msg.Channel,
+			ChatID:   msg.ChatID,
+			Content:  t("cmd.export.caption", format),
+			Media:    []string{path},
+			Metadata: map[string]string{"temporary_media": "true"},
+		})
+		return "", true
+
+	case "/sessions":
+		if !g.isAdmin(msg) {
+			return t("cmd.admin_only"), true
+		}
+		if g.agentRegistry == nil {
+			return t("cmd.registry_missing"), true
+		}
+		agentInst, _ := g.resolveAgentSession(msg)
+		if agentInst == nil || agentInst.Sessions == nil {
+			return t("cmd.sessions_missing"), true
+		}
+		infos, err := agentInst.Sessions.List()
+		if err != nil {
+			return t("cmd.sessions.failed", err), true
+		}
+		var filter session.ListFilter
+		if len(args) > 0 {
+			filter.Channel = args[0]
+		}
+		return formatSessionList(infos, filter, t), true
+
+	case "/think":
+		if g.agentRegistry == nil {
+			return t("cmd.registry_missing"), true
+		}
+		agentInst, sessionKey := g.resolveAgentSession(msg)
+		if agentInst == nil || agentInst.Sessions == nil {
+			return t("cmd.sessions_missing"), true
+		}
+		if len(args) == 0 {
+			if effort := agentInst.Sessions.GetThinking(sessionKey); effort != "" {
+				return t("cmd.think.chat", effort), true
+			}
+			switch {
+			case agentInst.ThinkingBudget > 0:
+				return t("cmd.think.agent_budget", agentInst.ThinkingBudget), true
+			case agentInst.ReasoningEffort != "":
+				return t("cmd.think.agent", agentInst.ReasoningEffort), true
+			}
+			return t("cmd.think.model"), true
+		}
+		effort := strings.ToLower(args[0])
+		switch effort {
+		case "low", "medium", "high":
+		case "default":
+			effort = ""
+		default:
+			return t("cmd.think.usage"), true
+		}
+		agentInst.Sessions.SetThinking(sessionKey, effort)
+		if err := agentInst.Sessions.Save(sessionKey); err != nil {
+			return t("cmd.save_failed", err), true
+		}
+		if effort == "" {
+			return t("cmd.think.reset"), true
+		}
+		return t("cmd.think.set", effort), true
 
 	case "/reload":
 		if g.reloadCallback == nil {
-			return "Reload not available", true
+			return t("cmd.reload.unavailable"), true
 		}
 		// Call reload callback
 		response, err := g.reloadCallback(ctx, msg)
 		if err != nil {
-			return fmt.Sprintf("Reload failed: %v", err), true
+			return t("cmd.reload.failed", err), true
 		}
 		return response, true
 
 	case "/show":
 		if len(args) < 1 {
-			return "Usage: /show [model|channel|agents]", true
+			return t("cmd.show.usage"), true
 		}
 		switch args[0] {
 		case "model":
 			if g.agentRegistry == nil {
-				return "No registry available", true
+				return t("cmd.registry_missing"), true
 			}
-			defaultAgent := g.agentRegistry.GetDefaultAgent()
-			if defaultAgent == nil {
-				return "No default agent configured", true
+			agentInst, sessionKey := g.resolveAgentSession(msg)
+			if agentInst == nil {
+				return t("cmd.no_default_agent"), true
 			}
-			return fmt.Sprintf("Current model: %s", defaultAgent.Model), true
+			if agentInst.Sessions != nil {
+				if override := agentInst.Sessions.GetModel(sessionKey); override != "" {
+					return t("cmd.show.model_chat", override, agentInst.Model), true
+				}
+			}
+			return t("cmd.show.model", agentInst.Model), true
 		case "channel":
-			return fmt.Sprintf("Current channel: %s", msg.Channel), true
+			return t("cmd.show.channel", msg.Channel), true
 		case "agents":
 			if g.agentRegistry == nil {
-				return "No registry available", true
+				return t("cmd.registry_missing"), true
 			}
 			agentIDs := g.agentRegistry.ListAgentIDs()
-			return fmt.Sprintf("Registered agents: %s", strings.Join(agentIDs, ", ")), true
+			return t("cmd.agents", strings.Join(agentIDs, ", ")), true
 		default:
-			return fmt.Sprintf("Unknown show target: %s", args[0]), true
+			return t("cmd.show.unknown", args[0]), true
 		}
 
 	case "/list":
 		if len(args) < 1 {
-			return "Usage: /list [models|channels|agents]", true
+			return t("cmd.list.usage"), true
 		}
 		switch args[0] {
 		case "models":
-			return "Available models: configured in config.json per agent", true
+			return t("cmd.list.models"), true
 		case "channels":
 			if g.channelManager == nil {
-				return "Channel manager not initialized", true
+				return t("cmd.channels_missing"), true
 			}
 			channels := g.channelManager.GetEnabledChannels()
 			if len(channels) == 0 {
-				return "No channels enabled", true
+				return t("cmd.list.no_channels"), true
 			}
-			return fmt.Sprintf("Enabled channels: %s", strings.Join(channels, ", ")), true
+			return t("cmd.list.channels", strings.Join(channels, ", ")), true
 		case "agents":
 			if g.agentRegistry == nil {
-				return "No registry available", true
+				return t("cmd.registry_missing"), true
 			}
 			agentIDs := g.agentRegistry.ListAgentIDs()
-			return fmt.Sprintf("Registered agents: %s", strings.Join(agentIDs, ", ")), true
+			return t("cmd.agents", strings.Join(agentIDs, ", ")), true
 		default:
-			return fmt.Sprintf("Unknown list target: %s", args[0]), true
+			return t("cmd.list.unknown", args[0]), true
 		}
 
 	case "/switch":
 		if len(args) < 3 || args[1] != "to" {
-			return "Usage: /switch [model|channel] to <name>", true
+			return t("cmd.switch.usage"), true
 		}
 		target := args[0]
 		value := args[2]
 
 		switch target {
 		case "model":
-			if g.agentRegistry == nil {
-				return "No registry available", true
+			if g.agentLoop == nil || g.agentRegistry == nil {
+				return t("cmd.registry_missing"), true
+			}
+			agentInst, sessionKey := g.resolveAgentSession(msg)
+			if agentInst == nil || agentInst.Sessions == nil {
+				return t("cmd.sessions_missing"), true
 			}
-			defaultAgent := g.agentRegistry.GetDefaultAgent()
-			if defaultAgent == nil {
-				return "No default agent configured", true
+			oldModel := agentInst.Sessions.GetModel(sessionKey)
+			if oldModel == "" {
+				oldModel = agentInst.Model
 			}
-			oldModel := defaultAgent.Model
-			defaultAgent.Model = value
-			return fmt.Sprintf("Switched model from %s to %s", oldModel, value), true
+			newModel := value
+			if value == "default" {
+				value, newModel = "", agentInst.Model
+			} else if _, err := g.agentLoop.GetConfig().GetModelConfig(value); err != nil {
+				return t("cmd.unknown_model", value), true
+			}
+			agentInst.Sessions.SetModel(sessionKey, value)
+			if err := agentInst.Sessions.Save(sessionKey); err != nil {
+				return t("cmd.save_failed", err), true
+			}
+			return t("cmd.switch.model", oldModel, newModel), true
 		case "channel":
 			if g.channelManager == nil {
-				return "Channel manager not initialized", true
+				return t("cmd.channels_missing"), true
 			}
 			if _, exists := g.channelManager.GetChannel(value); !exists && value != "cli" {
-				return fmt.Sprintf("Channel '%s' not found or not enabled", value), true
+				return t("cmd.switch.no_channel", value), true
 			}
-			return fmt.Sprintf("Switched target channel to %s", value), true
+			return t("cmd.switch.channel", value), true
 		default:
-			return fmt.Sprintf("Unknown switch target: %s", target), true
+			return t("cmd.switch.unknown", target), true
 		}
 	}
 
 	return "", false
 }
 
+// exportSession writes an export of s to a private temporary file and
+// returns its path. The file is sent as temporary media, so the channel
+// manager removes it once it has been delivered.
+func exportSession(s *session.Session, format string, opts session.ExportOptions) (string, error) {
+	if err := os.MkdirAll(utils.MediaDir(), 0700); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(utils.MediaDir(), "export-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, session.ExportFilename(s.Key, format, time.Now()))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		os.Remove(dir)
+		return "", err
+	}
+	if err = session.Export(f, s, format, opts); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		utils.RemoveMedia([]string{path})
+		return "", err
+	}
+	return path, nil
+}
+
+// sessionListLimit is how many sessions /sessions lists.
+const sessionListLimit = 10
+
+// formatSessionList describes the sessions passing filter, most recently
+// active first.
+func formatSessionList(infos []session.SessionInfo, filter session.ListFilter, t func(string, ...interface{}) string) string {
+	now := time.Now()
+	var matched []session.SessionInfo
+	var size int64
+	for _, info := range infos {
+		if filter.Match(info, now) {
+			matched = append(matched, info)
+			size += info.Size
+		}
+	}
+	if len(matched) == 0 {
+		return t("cmd.sessions.none")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(t("cmd.sessions.header", len(matched), utils.FormatBytes(size)))
+	for i, info := range matched {
+		if i == sessionListLimit {
+			sb.WriteString("\n" + t("cmd.sessions.more", len(matched)-i))
+			break
+		}
+		summary := ""
+		if info.HasSummary {
+			summary = t("cmd.sessions.summarized")
+		}
+		last := "-"
+		if !info.Updated.IsZero() {
+			last = info.Updated.Local().Format("2006-01-02 15:04")
+		}
+		sb.WriteString("\n" + t("cmd.sessions.item", info.Key, info.Messages, summary, last, utils.FormatBytes(info.Size)))
+	}
+	return sb.String()
+}
+
+// isAdmin reports whether the sender of msg may use admin commands. Only the
+// local CLI and the senders listed in gateway.admins may; with no admins
+// configured, nobody on a chat channel can.
+func (g *CommandGateway) isAdmin(msg bus.InboundMessage) bool {
+	if msg.Channel == "cli" {
+		return true
+	}
+	if g.agentLoop == nil || g.agentLoop.GetConfig() == nil {
+		return false
+	}
+	return g.agentLoop.GetConfig().Gateway.IsAdmin(msg.Channel, msg.SenderID)
+}
+
+// resolveAgentSession routes msg to its agent and session key.
+// The agent falls back to the default agent when the routed one is missing.
+func (g *CommandGateway) resolveAgentSession(msg bus.InboundMessage) (*agent.AgentInstance, string) {
+	route := g.resolveRoute(msg)
+	agentInst, ok := g.agentRegistry.GetAgent(route.AgentID)
+	if !ok {
+		agentInst = g.agentRegistry.GetDefaultAgent()
+	}
+	return agentInst, route.SessionKey
+}
+
+// locale returns the language command replies to msg are written in.
+func (g *CommandGateway) locale(msg bus.InboundMessage) string {
+	if g.agentRegistry == nil {
+		return i18n.Default
+	}
+	return g.agentRegistry.Locale(g.resolveRoute(msg))
+}
+
+func (g *CommandGateway) resolveRoute(msg bus.InboundMessage) routing.ResolvedRoute {
+	return g.agentRegistry.ResolveRoute(routing.RouteInput{
+		Channel:    msg.Channel,
+		AccountID:  msg.Metadata["account_id"],
+		Peer:       extractPeer(msg),
+		ParentPeer: extractParentPeer(msg),
+		GuildID:    msg.Metadata["guild_id"],
+		TeamID:     msg.Metadata["team_id"],
+	})
+}
+
 // extractPeer extracts routing peer from inbound message metadata.
 func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	peerKind := msg.Metadata["peer_kind"]
@@ -248,3 +589,13 @@ func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	}
 	return &routing.RoutePeer{Kind: peerKind, ID: peerID}
 }
+
+// extractParentPeer extracts the parent peer (reply-to) from inbound message metadata.
+func extractParentPeer(msg bus.InboundMessage) *routing.RoutePeer {
+	parentKind := msg.Metadata["parent_peer_kind"]
+	parentID := msg.Metadata["parent_peer_id"]
+	if parentKind == "" || parentID == "" {
+		return nil
+	}
+	return &routing.RoutePeer{Kind: parentKind, ID: parentID}
+}
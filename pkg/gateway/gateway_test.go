@@ -2,13 +2,16 @@ package gateway
 
 import (
 	"context"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/zhaopengme/mobaiclaw/pkg/agent"
 	"github.com/zhaopengme/mobaiclaw/pkg/bus"
 	"github.com/zhaopengme/mobaiclaw/pkg/config"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
 )
 
 func TestGatewayRoutesCommand(t *testing.T) {
@@ -157,3 +160,277 @@ func TestHelpCommandIncludesReload(t *testing.T) {
 		t.Error("/help output should include /reload command")
 	}
 }
+
+func newHistoryTestGateway(t *testing.T) (*CommandGateway, *agent.AgentInstance, *bus.MessageBus) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:     t.TempDir(),
+				MaxTokens:     4096,
+				ContextWindow: 32768,
+			},
+		},
+		ModelList: []config.ModelConfig{
+			{ModelName: "cheap", Model: "openai/cheap", APIKey: "sk-test"},
+		},
+	}
+	agentBus := bus.NewMessageBus()
+	al := agent.NewAgentLoop(cfg, agentBus, &nullProvider{})
+	g := &CommandGateway{agentRegistry: al.GetRegistry(), agentBus: agentBus}
+	g.SetAgentLoop(al)
+
+	agentInst := al.GetRegistry().GetDefaultAgent()
+	agentInst.Sessions.GetOrCreate("agent:main:main")
+	agentInst.Sessions.SetHistory("agent:main:main", []providers.Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "answer 1"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "exec"}}},
+		{Role: "tool", Content: "ok", ToolCallID: "call_1"},
+		{Role: "assistant", Content: "answer 2"},
+	})
+	return g, agentInst, agentBus
+}
+
+func historyCommand(content string) bus.InboundMessage {
+	return bus.InboundMessage{
+		Channel:  "telegram",
+		ChatID:   "123456",
+		Content:  content,
+		Metadata: map[string]string{"peer_kind": "direct", "peer_id": "123456"},
+	}
+}
+
+func TestHandleUndoCommand(t *testing.T) {
+	g, agentInst, _ := newHistoryTestGateway(t)
+
+	response, handled := g.handleCommand(context.Background(), historyCommand("/undo"))
+	if !handled || !strings.Contains(response, "second") {
+		t.Fatalf("unexpected /undo response %q", response)
+	}
+	history := agentInst.Sessions.GetHistory("agent:main:main")
+	if len(history) != 2 || history[1].Content != "answer 1" {
+		t.Fatalf("history after /undo = %+v", history)
+	}
+
+	g.handleCommand(context.Background(), historyCommand("/rewind 5"))
+	if history := agentInst.Sessions.GetHistory("agent:main:main"); len(history) != 0 {
+		t.Fatalf("history after /rewind = %+v", history)
+	}
+
+	if response, _ := g.handleCommand(context.Background(), historyCommand("/rewind zero")); !strings.Contains(response, "Usage") {
+		t.Errorf("expected usage for invalid /rewind, got %q", response)
+	}
+}
+
+func TestHandleRetryCommand(t *testing.T) {
+	g, agentInst, agentBus := newHistoryTestGateway(t)
+
+	if response, _ := g.handleCommand(context.Background(), historyCommand("/retry unknown-model")); !strings.Contains(response, "Unknown model") {
+		t.Fatalf("expected unknown model error, got %q", response)
+	}
+	if len(agentInst.Sessions.GetHistory("agent:main:main")) != 6 {
+		t.Fatal("a rejected /retry must not change the history")
+	}
+
+	if _, handled := g.handleCommand(context.Background(), historyCommand("/retry cheap")); !handled {
+		t.Fatal("expected /retry to be handled")
+	}
+	if history := agentInst.Sessions.GetHistory("agent:main:main"); len(history) != 2 {
+		t.Fatalf("history after /retry = %+v", history)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replay, ok := agentBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected the last message to be replayed to the agent")
+	}
+	if replay.Content != "second" || replay.Metadata["model"] != "cheap" || replay.Metadata["peer_kind"] != "direct" {
+		t.Errorf("unexpected replay %+v", replay)
+	}
+}
+
+func TestHandleRetryCommand_Attachments(t *testing.T) {
+	g, agentInst, _ := newHistoryTestGateway(t)
+	agentInst.Sessions.SetAttachments("agent:main:main", true)
+
+	response, handled := g.handleCommand(context.Background(), historyCommand("/retry"))
+	if !handled || !strings.Contains(response, "attachments") {
+		t.Fatalf("unexpected /retry response %q", response)
+	}
+	if len(agentInst.Sessions.GetHistory("agent:main:main")) != 6 {
+		t.Fatal("a refused /retry must not change the history")
+	}
+
+	g.handleCommand(context.Background(), historyCommand("/undo"))
+	if agentInst.Sessions.HasAttachments("agent:main:main") {
+		t.Error("rewinding should drop the attachments mark with the message")
+	}
+}
+
+func TestHandleSwitchModelCommand(t *testing.T) {
+	g, agentInst, _ := newHistoryTestGateway(t)
+	defaultModel := agentInst.Model
+
+	if response, _ := g.handleCommand(context.Background(), historyCommand("/switch model to unknown-model")); !strings.Contains(response, "Unknown model") {
+		t.Fatalf("expected unknown model error, got %q", response)
+	}
+
+	g.handleCommand(context.Background(), historyCommand("/switch model to cheap"))
+	if got := agentInst.Sessions.GetModel("agent:main:main"); got != "cheap" {
+		t.Fatalf("session model = %q, want cheap", got)
+	}
+	if agentInst.Model != defaultModel {
+		t.Errorf("/switch model must not change the agent model, got %q", agentInst.Model)
+	}
+	if response, _ := g.handleCommand(context.Background(), historyCommand("/show model")); !strings.Contains(response, "cheap") {
+		t.Errorf("/show model = %q, want the chat override", response)
+	}
+
+	g.handleCommand(context.Background(), historyCommand("/switch model to default"))
+	if got := agentInst.Sessions.GetModel("agent:main:main"); got != "" {
+		t.Errorf("session model after reset = %q, want empty", got)
+	}
+}
+
+func TestHandleThinkCommand(t *testing.T) {
+	g, agentInst, _ := newHistoryTestGateway(t)
+
+	if response, _ := g.handleCommand(context.Background(), historyCommand("/think extreme")); !strings.Contains(response, "Usage") {
+		t.Fatalf("expected usage for an invalid effort, got %q", response)
+	}
+
+	g.handleCommand(context.Background(), historyCommand("/think high"))
+	if got := agentInst.Sessions.GetThinking("agent:main:main"); got != "high" {
+		t.Fatalf("session thinking = %q, want high", got)
+	}
+	if response, _ := g.handleCommand(context.Background(), historyCommand("/think")); !strings.Contains(response, "high") {
+		t.Errorf("/think = %q, want the chat override", response)
+	}
+
+	g.handleCommand(context.Background(), historyCommand("/think default"))
+	if got := agentInst.Sessions.GetThinking("agent:main:main"); got != "" {
+		t.Errorf("session thinking after reset = %q, want empty", got)
+	}
+}
+
+func TestCommandRepliesUseChannelLocale(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Workspace: t.TempDir()},
+			List:     []config.AgentConfig{{ID: "main", Default: true}},
+		},
+		Locale: config.LocaleConfig{Channels: map[string]string{"telegram": "zh"}},
+	}
+	g := &CommandGateway{agentRegistry: agent.NewAgentRegistry(cfg, &nullProvider{})}
+
+	resp, _ := g.handleCommand(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/show channel"})
+	if resp != "当前频道：telegram" {
+		t.Errorf("telegram reply = %q, want Chinese", resp)
+	}
+	resp, _ = g.handleCommand(context.Background(), bus.InboundMessage{Channel: "discord", ChatID: "1", Content: "/show channel"})
+	if resp != "Current channel: discord" {
+		t.Errorf("discord reply = %q, want English", resp)
+	}
+}
+
+func TestExportCommandSendsFile(t *testing.T) {
+	registry := newTestRegistry(t)
+	agentInst := registry.GetDefaultAgent()
+	agentInst.Sessions.AddMessage("agent:main:main", "user", "hello")
+	agentInst.Sessions.AddMessage("agent:main:main", "assistant", "hi there")
+
+	msgBus := bus.NewMessageBus()
+	g := &CommandGateway{bus: msgBus, agentRegistry: registry}
+
+	resp, handled := g.handleCommand(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/export html"})
+	if !handled || resp != "" {
+		t.Fatalf("handled = %v, response = %q", handled, resp)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || len(out.Media) != 1 || !strings.HasSuffix(out.Media[0], ".html") {
+		t.Fatalf("outbound = %+v", out)
+	}
+	defer utils.RemoveMedia(out.Media)
+	if out.Metadata["temporary_media"] != "true" {
+		t.Errorf("export should be sent as temporary media: %+v", out.Metadata)
+	}
+	info, err := os.Stat(out.Media[0])
+	if err != nil || info.Mode().Perm() != 0600 || strings.Contains(out.Media[0], agentInst.Workspace) {
+		t.Errorf("export file %s: %v %v", out.Media[0], info, err)
+	}
+	data, err := os.ReadFile(out.Media[0])
+	if err != nil || !strings.Contains(string(data), "hi there") {
+		t.Errorf("export file: %v\n%s", err, data)
+	}
+
+	resp, _ = g.handleCommand(context.Background(), bus.InboundMessage{Channel: "telegram", ChatID: "1", Content: "/export pdf"})
+	if !strings.HasPrefix(resp, "Usage: /export") {
+		t.Errorf("bad format reply = %q", resp)
+	}
+}
+
+func TestSessionsCommand(t *testing.T) {
+	registry := newTestRegistry(t)
+	sessions := registry.GetDefaultAgent().Sessions
+	sessions.AddMessage("agent:main:telegram:direct:1", "user", "hello")
+	sessions.SetChat("agent:main:telegram:direct:1", "telegram", "1")
+	sessions.AddMessage("agent:main:discord:direct:2", "user", "hey")
+	sessions.SetChat("agent:main:discord:direct:2", "discord", "2")
+
+	g := &CommandGateway{agentRegistry: registry}
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "1", SenderID: "7|alice", Content: "/sessions telegram"}
+
+	// Without admins configured nobody on a chat channel may list sessions
+	g.agentLoop = agent.NewAgentLoop(&config.Config{}, bus.NewMessageBus(), &nullProvider{})
+	if resp, _ := g.handleCommand(context.Background(), msg); !strings.Contains(resp, "admins") {
+		t.Errorf("reply with no admins = %q", resp)
+	}
+
+	cfg := &config.Config{Gateway: config.GatewayConfig{Admins: config.FlexibleStringSlice{"telegram:7"}}}
+	g.agentLoop = agent.NewAgentLoop(cfg, bus.NewMessageBus(), &nullProvider{})
+	resp, handled := g.handleCommand(context.Background(), msg)
+	if !handled || !strings.Contains(resp, "1 session(s)") || !strings.Contains(resp, "telegram:direct:1") || strings.Contains(resp, "discord") {
+		t.Fatalf("handled = %v, response = %q", handled, resp)
+	}
+	msg.SenderID = "42"
+	if resp, _ := g.handleCommand(context.Background(), msg); !strings.Contains(resp, "admins") {
+		t.Errorf("non-admin reply = %q", resp)
+	}
+}
+
+func TestRun_RemovesMediaOfHandledCommands(t *testing.T) {
+	if err := os.MkdirAll(utils.MediaDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	photo, err := os.CreateTemp(utils.MediaDir(), "photo-*.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo.Close()
+	defer os.Remove(photo.Name())
+
+	msgBus := bus.NewMessageBus()
+	g := NewCommandGateway(msgBus, nil, nil, newTestRegistry(t), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go g.Run(ctx)
+
+	msgBus.PublishInbound(bus.InboundMessage{
+		Channel: "telegram",
+		ChatID:  "123",
+		Content: "/help",
+		Media:   []string{photo.Name()},
+	})
+	if _, ok := msgBus.SubscribeOutbound(ctx); !ok {
+		t.Fatal("expected a reply to /help")
+	}
+	if _, err := os.Stat(photo.Name()); !os.IsNotExist(err) {
+		t.Errorf("media of a handled command still exists: %v", err)
+	}
+}
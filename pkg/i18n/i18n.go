@@ -0,0 +1,68 @@
+// Package i18n holds the catalog of built-in messages shown to users, such
+// as command replies and status notices, in each supported language.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported locales.
+const (
+	English = "en"
+	Chinese = "zh"
+
+	// Default is used when no supported locale is configured.
+	Default = English
+)
+
+var catalogs = map[string]map[string]string{
+	English: en,
+	Chinese: zh,
+}
+
+// T returns the message for key in locale, formatted with args like
+// fmt.Sprintf. Messages missing from locale fall back to English, and
+// unknown keys are returned as is.
+func T(locale, key string, args ...interface{}) string {
+	format, ok := catalogs[Normalize(locale)][key]
+	if !ok {
+		if format, ok = en[key]; !ok {
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Normalize maps a locale or language name ("zh-CN", "zh_Hans", "Chinese",
+// "中文", "en-US") to a supported locale, or "" when it is not supported.
+func Normalize(locale string) string {
+	l := strings.ToLower(strings.TrimSpace(locale))
+	switch l {
+	case "chinese", "中文", "简体中文", "繁體中文":
+		return Chinese
+	case "english":
+		return English
+	}
+	if i := strings.IndexAny(l, "-_"); i > 0 {
+		l = l[:i]
+	}
+	if _, ok := catalogs[l]; ok {
+		return l
+	}
+	return ""
+}
+
+// Resolve returns the first supported locale among candidates, most
+// specific first, or Default when none is supported.
+func Resolve(candidates ...string) string {
+	for _, c := range candidates {
+		if l := Normalize(c); l != "" {
+			return l
+		}
+	}
+	return Default
+}
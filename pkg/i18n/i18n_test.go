@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verb = regexp.MustCompile(`%[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	for locale, catalog := range catalogs {
+		if locale == English {
+			continue
+		}
+		for key, format := range en {
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing key %q", locale, key)
+				continue
+			}
+			want, got := verb.FindAllString(format, -1), verb.FindAllString(translated, -1)
+			if len(want) != len(got) {
+				t.Errorf("%s: %q has verbs %v, want %v", locale, key, got, want)
+			}
+		}
+		for key := range catalog {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s: key %q is not in the English catalog", locale, key)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("en", "cmd.stop.done"); got != "⏹ Stopped." {
+		t.Errorf("en = %q", got)
+	}
+	if got := T("zh-CN", "cmd.show.channel", "telegram"); got != "当前频道：telegram" {
+		t.Errorf("zh = %q", got)
+	}
+	if got := T("fr", "cmd.show.channel", "telegram"); got != "Current channel: telegram" {
+		t.Errorf("unsupported locale should fall back to English, got %q", got)
+	}
+	if got := T("zh", "no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown key = %q", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"zh":      Chinese,
+		"zh-CN":   Chinese,
+		"zh_Hant": Chinese,
+		"Chinese": Chinese,
+		"中文":      Chinese,
+		"en-US":   English,
+		"English": English,
+		"fr":      "",
+		"":        "",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got := Resolve("", "fr", "zh-TW", "en"); got != Chinese {
+		t.Errorf("Resolve = %q, want zh", got)
+	}
+	if got := Resolve("", "fr"); got != Default {
+		t.Errorf("Resolve = %q, want default", got)
+	}
+}
@@ -0,0 +1,139 @@
+package i18n
+
+var en = map[string]string{
+	// Gateway commands
+	"cmd.start": "Hello! I am MobaiClaw 🦞",
+	"cmd.help": `/start - Start the bot
+/clear - Clear current session history and summary
+/undo - Remove the last message and the reply to it
+/retry [model] - Regenerate the last reply, optionally with another model
+/rewind <n> - Remove the last n messages and their replies
+/stop - Stop the reply currently being generated and any subagents it spawned
+/approve <id> - Approve a pending tool call
+/deny <id> - Deny a pending tool call
+/usage [today|month|all] - Show token usage and cost for this chat and agent
+/think [low|medium|high|default] - Show or set the reasoning effort for this chat
+/export [md|html|json] [redact] - Send a transcript of this chat as a file
+/sessions [channel] - List this agent's sessions (admins only)
+/reload - Reload agent configuration (agents.*, bindings, providers). Note: Active conversations will be reset.
+/help - Show this help message
+/show [model|channel|agents] - Show current configuration
+/list [models|channels|agents] - List available options
+/switch [model|channel] to <name> - Switch this chat's model ("default" to reset) or the channel`,
+	"cmd.registry_missing":       "No registry available",
+	"cmd.agent_registry_missing": "agent registry not available",
+	"cmd.sessions_missing":       "sessions not available",
+	"cmd.channels_missing":       "Channel manager not initialized",
+	"cmd.no_default_agent":       "No default agent configured",
+	"cmd.save_failed":            "failed to save session: %v",
+	"cmd.busy":                   "A reply is still being generated. Use /stop first.",
+	"cmd.unknown_model":          "Unknown model: %s",
+	"cmd.clear.done":             "🧹 Session cleared. Let's start fresh.",
+	"cmd.undo.unavailable":       "History editing not available",
+	"cmd.rewind.usage":           "Usage: /rewind <n>",
+	"cmd.rewind.invalid":         "Usage: /rewind <n> (n must be a positive number)",
+	"cmd.undo.nothing":           "Nothing to undo.",
+	"cmd.undo.removed_one":       "↩️ Removed: %s",
+	"cmd.undo.removed_many":      "⏪ Removed the last %d messages and their replies.",
+	"cmd.retry.unavailable":      "Retry not available",
+	"cmd.retry.nothing":          "Nothing to retry.",
+	"cmd.retry.attachments":      "The last message came with attachments, which are no longer available. Please send it again.",
+	"cmd.stop.unavailable":       "Stop not available",
+	"cmd.stop.nothing":           "Nothing is running in this chat.",
+	"cmd.stop.done":              "⏹ Stopped.",
+	"cmd.approve.unavailable":    "Approvals not available",
+	"cmd.approve.usage":          "Usage: %s <id>",
+	"cmd.approve.unknown":        "No pending approval %s in this chat.",
+	"cmd.approve.approved":       "✅ Approved.",
+	"cmd.approve.denied":         "🚫 Denied.",
+	"cmd.usage.unavailable":      "Usage not available",
+	"cmd.export.usage":           "Usage: /export [md|html|json] [redact]",
+	"cmd.export.empty":           "Nothing to export yet.",
+	"cmd.export.failed":          "Export failed: %v",
+	"cmd.export.caption":         "📄 Transcript of this chat (%s)",
+	"cmd.admin_only":             "This command is only available to gateway admins (gateway.admins in the config).",
+	"cmd.sessions.failed":        "Failed to list sessions: %v",
+	"cmd.sessions.none":          "No sessions.",
+	"cmd.sessions.header":        "🗂 %d session(s), %s on disk",
+	"cmd.sessions.item":          "• %s: %d messages%s, last active %s, %s",
+	"cmd.sessions.summarized":    " (summarized)",
+	"cmd.sessions.more":          "…and %d more",
+	"cmd.think.chat":             "Reasoning effort: %s (this chat)",
+	"cmd.think.agent_budget":     "Reasoning effort: agent default (%d token budget)",
+	"cmd.think.agent":            "Reasoning effort: %s (agent default)",
+	"cmd.think.model":            "Reasoning effort: model default",
+	"cmd.think.usage":            "Usage: /think [low|medium|high|default]",
+	"cmd.think.reset":            "🧠 Reasoning effort reset to the agent default for this chat.",
+	"cmd.think.set":              "🧠 Reasoning effort set to %s for this chat.",
+	"cmd.reload.unavailable":     "Reload not available",
+	"cmd.reload.failed":          "Reload failed: %v",
+	"cmd.reload.load_failed":     "Failed to load config: %v",
+	"cmd.reload.provider_failed": "Failed to create provider: %v",
+	"cmd.reload.registry_failed": "Failed to reload registry: %v",
+	"cmd.reload.done":            "Config reloaded successfully. Provider: %s, %d agent(s) available: %s",
+	"cmd.show.usage":             "Usage: /show [model|channel|agents]",
+	"cmd.show.model_chat":        "Current model: %s (this chat; agent default: %s)",
+	"cmd.show.model":             "Current model: %s",
+	"cmd.show.channel":           "Current channel: %s",
+	"cmd.show.unknown":           "Unknown show target: %s",
+	"cmd.agents":                 "Registered agents: %s",
+	"cmd.list.usage":             "Usage: /list [models|channels|agents]",
+	"cmd.list.models":            "Available models: configured in config.json per agent",
+	"cmd.list.no_channels":       "No channels enabled",
+	"cmd.list.channels":          "Enabled channels: %s",
+	"cmd.list.unknown":           "Unknown list target: %s",
+	"cmd.switch.usage":           "Usage: /switch [model|channel] to <name>",
+	"cmd.switch.model":           "Switched model for this chat from %s to %s",
+	"cmd.switch.no_channel":      "Channel '%s' not found or not enabled",
+	"cmd.switch.channel":         "Switched target channel to %s",
+	"cmd.switch.unknown":         "Unknown switch target: %s",
+
+	// Agent loop
+	"agent.no_response":      "I've completed processing but have no response to give.",
+	"agent.background_done":  "Background task completed.",
+	"agent.error":            "Error processing message: %v",
+	"agent.compressing":      "Context window exceeded. Compressing history and retrying...",
+	"agent.summarizing":      "Memory threshold reached. Optimizing conversation history...",
+	"agent.running":          "⚙️ Running: %s...",
+	"agent.request_blocked":  "⚠️ Request blocked: %s",
+	"agent.response_blocked": "⚠️ Response blocked: %s",
+	"agent.budget_exhausted": "⚠️ Usage budget exhausted (%s). Please try again later.",
+	"agent.approval_request": "🔐 Approval required [%s]\n%s(%s)\n\nReply /approve %s or /deny %s (expires in %s).",
+	"agent.approval_expired": "⌛ Approval %s expired, %s was not executed.",
+	"agent.loop_stopped":     "⚠️ I stopped because I kept repeating the same tool calls without making progress.\n\nWhat I tried:",
+	"agent.loop_more":        "… and %d more",
+	"agent.loop_hint":        "Please rephrase the request or give me more details.",
+	"agent.iteration_limit":  "(Stopped after %d tool iterations. Reply \"continue\" to keep going.)",
+	"budget.daily_tokens":    "daily token budget of %d reached",
+	"budget.monthly_tokens":  "monthly token budget of %d reached",
+	"budget.daily_cost":      "daily cost budget of $%.2f reached",
+	"budget.monthly_cost":    "monthly cost budget of $%.2f reached",
+	"usage.unavailable":      "Usage tracking not available",
+	"usage.usage":            "Usage: /usage [today|month|all]",
+	"usage.today":            "today",
+	"usage.month":            "this month",
+	"usage.all":              "all time",
+	"usage.title":            "📊 Token usage (%s)",
+	"usage.chat":             "This chat: %s",
+	"usage.agent":            "Agent %s: %s",
+	"usage.by_model":         "By model:",
+	"usage.budget":           "Budget: %s",
+	"usage.day_tokens":       "%d / %d tokens today",
+	"usage.month_tokens":     "%d / %d tokens this month",
+	"usage.day_cost":         "$%.4f / $%.2f today",
+	"usage.month_cost":       "$%.4f / $%.2f this month",
+	"usage.totals":           "%d tokens (%d in / %d out), %d requests",
+
+	// Device notifications
+	"device.connected":    "🔌 Device Connected",
+	"device.disconnected": "🔌 Device Disconnected",
+	"device.type":         "Type: %s",
+	"device.device":       "Device: %s",
+	"device.capabilities": "Capabilities: %s",
+	"device.serial":       "Serial: %s",
+
+	// Channels
+	"channel.approve":    "✅ Approve",
+	"channel.deny":       "🚫 Deny",
+	"channel.attachment": "[Attachment: %s (file uploads are not supported on this channel)]",
+}
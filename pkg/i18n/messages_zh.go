@@ -0,0 +1,139 @@
+package i18n
+
+var zh = map[string]string{
+	// Gateway commands
+	"cmd.start": "你好！我是 MobaiClaw 🦞",
+	"cmd.help": `/start - 启动机器人
+/clear - 清空当前会话的历史和摘要
+/undo - 撤销上一条消息及其回复
+/retry [model] - 重新生成上一条回复，可指定其他模型
+/rewind <n> - 撤销最近 n 条消息及其回复
+/stop - 停止正在生成的回复及其派生的子代理
+/approve <id> - 批准待确认的工具调用
+/deny <id> - 拒绝待确认的工具调用
+/usage [today|month|all] - 查看本会话和代理的 token 用量与费用
+/think [low|medium|high|default] - 查看或设置本会话的推理强度
+/export [md|html|json] [redact] - 以文件形式发送本会话的记录
+/sessions [channel] - 列出本代理的会话（仅限管理员）
+/reload - 重新加载代理配置（agents.*、bindings、providers）。注意：进行中的对话将被重置。
+/help - 显示本帮助
+/show [model|channel|agents] - 查看当前配置
+/list [models|channels|agents] - 列出可用选项
+/switch [model|channel] to <name> - 切换本会话的模型（"default" 恢复默认）或目标频道`,
+	"cmd.registry_missing":       "代理注册表不可用",
+	"cmd.agent_registry_missing": "代理注册表不可用",
+	"cmd.sessions_missing":       "会话不可用",
+	"cmd.channels_missing":       "频道管理器未初始化",
+	"cmd.no_default_agent":       "未配置默认代理",
+	"cmd.save_failed":            "保存会话失败：%v",
+	"cmd.busy":                   "回复仍在生成中，请先使用 /stop。",
+	"cmd.unknown_model":          "未知模型：%s",
+	"cmd.clear.done":             "🧹 当前会话已清空，我们可以重新开始了。",
+	"cmd.undo.unavailable":       "无法编辑历史",
+	"cmd.rewind.usage":           "用法：/rewind <n>",
+	"cmd.rewind.invalid":         "用法：/rewind <n>（n 必须是正整数）",
+	"cmd.undo.nothing":           "没有可撤销的内容。",
+	"cmd.undo.removed_one":       "↩️ 已撤销：%s",
+	"cmd.undo.removed_many":      "⏪ 已撤销最近 %d 条消息及其回复。",
+	"cmd.retry.unavailable":      "无法重试",
+	"cmd.retry.nothing":          "没有可重试的内容。",
+	"cmd.retry.attachments":      "上一条消息带有附件，附件已不可用，请重新发送。",
+	"cmd.stop.unavailable":       "无法停止",
+	"cmd.stop.nothing":           "当前会话没有正在运行的任务。",
+	"cmd.stop.done":              "⏹ 已停止。",
+	"cmd.approve.unavailable":    "审批功能不可用",
+	"cmd.approve.usage":          "用法：%s <id>",
+	"cmd.approve.unknown":        "本会话中没有待确认的审批 %s。",
+	"cmd.approve.approved":       "✅ 已批准。",
+	"cmd.approve.denied":         "🚫 已拒绝。",
+	"cmd.usage.unavailable":      "用量统计不可用",
+	"cmd.export.usage":           "用法：/export [md|html|json] [redact]",
+	"cmd.export.empty":           "暂无可导出的内容。",
+	"cmd.export.failed":          "导出失败：%v",
+	"cmd.export.caption":         "📄 本会话的记录（%s）",
+	"cmd.admin_only":             "此命令仅限网关管理员使用（见配置中的 gateway.admins）。",
+	"cmd.sessions.failed":        "列出会话失败：%v",
+	"cmd.sessions.none":          "暂无会话。",
+	"cmd.sessions.header":        "🗂 共 %d 个会话，占用磁盘 %s",
+	"cmd.sessions.item":          "• %s：%d 条消息%s，最后活动于 %s，%s",
+	"cmd.sessions.summarized":    "（已摘要）",
+	"cmd.sessions.more":          "……还有 %d 个",
+	"cmd.think.chat":             "推理强度：%s（本会话）",
+	"cmd.think.agent_budget":     "推理强度：代理默认（%d token 预算）",
+	"cmd.think.agent":            "推理强度：%s（代理默认）",
+	"cmd.think.model":            "推理强度：模型默认",
+	"cmd.think.usage":            "用法：/think [low|medium|high|default]",
+	"cmd.think.reset":            "🧠 本会话的推理强度已恢复为代理默认值。",
+	"cmd.think.set":              "🧠 本会话的推理强度已设为 %s。",
+	"cmd.reload.unavailable":     "无法重新加载",
+	"cmd.reload.failed":          "重新加载失败：%v",
+	"cmd.reload.load_failed":     "加载配置失败：%v",
+	"cmd.reload.provider_failed": "创建模型服务失败：%v",
+	"cmd.reload.registry_failed": "重新加载代理失败：%v",
+	"cmd.reload.done":            "配置已重新加载。模型：%s，可用代理 %d 个：%s",
+	"cmd.show.usage":             "用法：/show [model|channel|agents]",
+	"cmd.show.model_chat":        "当前模型：%s（本会话；代理默认：%s）",
+	"cmd.show.model":             "当前模型：%s",
+	"cmd.show.channel":           "当前频道：%s",
+	"cmd.show.unknown":           "未知的查看目标：%s",
+	"cmd.agents":                 "已注册的代理：%s",
+	"cmd.list.usage":             "用法：/list [models|channels|agents]",
+	"cmd.list.models":            "可用模型：在 config.json 中按代理配置",
+	"cmd.list.no_channels":       "没有启用的频道",
+	"cmd.list.channels":          "已启用的频道：%s",
+	"cmd.list.unknown":           "未知的列表目标：%s",
+	"cmd.switch.usage":           "用法：/switch [model|channel] to <name>",
+	"cmd.switch.model":           "本会话的模型已从 %s 切换为 %s",
+	"cmd.switch.no_channel":      "频道 '%s' 不存在或未启用",
+	"cmd.switch.channel":         "目标频道已切换为 %s",
+	"cmd.switch.unknown":         "未知的切换目标：%s",
+
+	// Agent loop
+	"agent.no_response":      "处理已完成，但没有需要回复的内容。",
+	"agent.background_done":  "后台任务已完成。",
+	"agent.error":            "处理消息时出错：%v",
+	"agent.compressing":      "上下文超出限制，正在压缩历史并重试……",
+	"agent.summarizing":      "记忆已达上限，正在整理对话历史……",
+	"agent.running":          "⚙️ 正在执行: %s...",
+	"agent.request_blocked":  "⚠️ 请求已被拦截：%s",
+	"agent.response_blocked": "⚠️ 回复已被拦截：%s",
+	"agent.budget_exhausted": "⚠️ 用量预算已用完（%s），请稍后再试。",
+	"agent.approval_request": "🔐 需要审批 [%s]\n%s(%s)\n\n回复 /approve %s 批准或 /deny %s 拒绝（%s 后过期）。",
+	"agent.approval_expired": "⌛ 审批 %s 已过期，%s 未执行。",
+	"agent.loop_stopped":     "⚠️ 我一直在重复相同的工具调用且没有进展，因此停了下来。\n\n我尝试过：",
+	"agent.loop_more":        "…… 以及其他 %d 项",
+	"agent.loop_hint":        "请换个说法或提供更多细节。",
+	"agent.iteration_limit":  "（已执行 %d 轮工具调用后暂停。回复“继续”即可接着处理。）",
+	"budget.daily_tokens":    "已达到每日 %d token 的预算",
+	"budget.monthly_tokens":  "已达到每月 %d token 的预算",
+	"budget.daily_cost":      "已达到每日 $%.2f 的费用预算",
+	"budget.monthly_cost":    "已达到每月 $%.2f 的费用预算",
+	"usage.unavailable":      "用量统计不可用",
+	"usage.usage":            "用法：/usage [today|month|all]",
+	"usage.today":            "今天",
+	"usage.month":            "本月",
+	"usage.all":              "全部",
+	"usage.title":            "📊 Token 用量（%s）",
+	"usage.chat":             "本会话：%s",
+	"usage.agent":            "代理 %s：%s",
+	"usage.by_model":         "按模型：",
+	"usage.budget":           "预算：%s",
+	"usage.day_tokens":       "今天 %d / %d token",
+	"usage.month_tokens":     "本月 %d / %d token",
+	"usage.day_cost":         "今天 $%.4f / $%.2f",
+	"usage.month_cost":       "本月 $%.4f / $%.2f",
+	"usage.totals":           "%d token（输入 %d / 输出 %d），%d 次请求",
+
+	// Device notifications
+	"device.connected":    "🔌 设备已连接",
+	"device.disconnected": "🔌 设备已断开",
+	"device.type":         "类型：%s",
+	"device.device":       "设备：%s",
+	"device.capabilities": "功能：%s",
+	"device.serial":       "序列号：%s",
+
+	// Channels
+	"channel.approve":    "✅ 批准",
+	"channel.deny":       "🚫 拒绝",
+	"channel.attachment": "[附件：%s（此频道不支持上传文件）]",
+}
@@ -19,9 +19,13 @@ type UsageInfo = protocoltypes.UsageInfo
 type Message = protocoltypes.Message
 type ToolDefinition = protocoltypes.ToolDefinition
 type ToolFunctionDefinition = protocoltypes.ToolFunctionDefinition
+type ThinkingBlock = protocoltypes.ThinkingBlock
 
 const defaultBaseURL = "https://api.anthropic.com"
 
+// minThinkingBudget is the smallest budget_tokens the API accepts.
+const minThinkingBudget = 1024
+
 type Provider struct {
 	client      *anthropic.Client
 	tokenSource func() (string, error)
@@ -84,6 +88,43 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 	return parseResponse(resp), nil
 }
 
+// ChatStream is like Chat but uses the streaming Messages API, calling onDelta
+// for every text delta while the full message is accumulated.
+func (p *Provider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta protocoltypes.StreamCallback) (*LLMResponse, error) {
+	var opts []option.RequestOption
+	if p.tokenSource != nil {
+		tok, err := p.tokenSource()
+		if err != nil {
+			return nil, fmt.Errorf("refreshing token: %w", err)
+		}
+		opts = append(opts, option.WithAuthToken(tok))
+	}
+
+	params, err := buildParams(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := p.client.Messages.NewStreaming(ctx, params, opts...)
+	defer stream.Close()
+
+	var resp anthropic.Message
+	for stream.Next() {
+		event := stream.Current()
+		if err := resp.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("claude stream: %w", err)
+		}
+		if onDelta != nil && event.Type == "content_block_delta" && event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+			onDelta(event.Delta.Text)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("claude API call: %w", err)
+	}
+
+	return parseResponse(&resp), nil
+}
+
 func (p *Provider) GetDefaultModel() string {
 	return "claude-sonnet-4.6"
 }
@@ -105,6 +146,10 @@ func buildParams(messages []Message, tools []ToolDefinition, model string, optio
 				anthropicMessages = append(anthropicMessages,
 					anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
 				)
+			} else if len(msg.ContentParts) > 0 {
+				anthropicMessages = append(anthropicMessages,
+					anthropic.NewUserMessage(translateContentParts(msg.ContentParts)...),
+				)
 			} else {
 				anthropicMessages = append(anthropicMessages,
 					anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)),
@@ -113,6 +158,16 @@ func buildParams(messages []Message, tools []ToolDefinition, model string, optio
 		case "assistant":
 			if len(msg.ToolCalls) > 0 {
 				var blocks []anthropic.ContentBlockParamUnion
+				// With extended thinking, a tool use turn must resend its
+				// thinking blocks unchanged and in their original order
+				for _, tb := range msg.ThinkingBlocks {
+					switch tb.Type {
+					case "thinking":
+						blocks = append(blocks, anthropic.NewThinkingBlock(tb.Signature, tb.Thinking))
+					case "redacted_thinking":
+						blocks = append(blocks, anthropic.NewRedactedThinkingBlock(tb.Data))
+					}
+				}
 				if msg.Content != "" {
 					blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
 				}
@@ -147,7 +202,19 @@ func buildParams(messages []Message, tools []ToolDefinition, model string, optio
 		params.System = system
 	}
 
-	if temp, ok := options["temperature"].(float64); ok {
+	// Extended thinking needs max_tokens above the budget and does not
+	// accept a custom temperature.
+	effort, budget := protocoltypes.ReasoningOptions(options)
+	if budget == 0 {
+		budget = protocoltypes.ThinkingBudgetFor(effort)
+	}
+	if budget > 0 {
+		budget = max(budget, minThinkingBudget)
+		if params.MaxTokens <= int64(budget) {
+			params.MaxTokens += int64(budget)
+		}
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(int64(budget))
+	} else if temp, ok := options["temperature"].(float64); ok {
 		params.Temperature = anthropic.Float(temp)
 	}
 
@@ -155,9 +222,37 @@ func buildParams(messages []Message, tools []ToolDefinition, model string, optio
 		params.Tools = translateTools(tools)
 	}
 
+	addCacheBreakpoints(&params)
+
 	return params, nil
 }
 
+// addCacheBreakpoints marks the end of the system prompt, the tool list and
+// the conversation so far as prompt cache breakpoints. The agent loop resends
+// all three on every iteration, so each call after the first reads them from
+// the cache and only pays full price for what was appended since.
+func addCacheBreakpoints(params *anthropic.MessageNewParams) {
+	if n := len(params.System); n > 0 && params.System[n-1].Text != "" {
+		params.System[n-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+	if n := len(params.Tools); n > 0 && params.Tools[n-1].OfTool != nil {
+		params.Tools[n-1].OfTool.CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+	if n := len(params.Messages); n > 0 {
+		blocks := params.Messages[n-1].Content
+		if len(blocks) == 0 {
+			return
+		}
+		last := blocks[len(blocks)-1]
+		if last.OfText != nil && last.OfText.Text == "" {
+			return
+		}
+		if cc := last.GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+		}
+	}
+}
+
 func translateTools(tools []ToolDefinition) []anthropic.ToolUnionParam {
 	result := make([]anthropic.ToolUnionParam, 0, len(tools))
 	for _, t := range tools {
@@ -184,8 +279,22 @@ func translateTools(tools []ToolDefinition) []anthropic.ToolUnionParam {
 	return result
 }
 
+func translateContentParts(parts []protocoltypes.ContentPart) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+		case "image":
+			blocks = append(blocks, anthropic.NewImageBlockBase64(part.MediaType, part.Data))
+		}
+	}
+	return blocks
+}
+
 func parseResponse(resp *anthropic.Message) *LLMResponse {
-	var content string
+	var content, reasoning string
+	var thinking []ThinkingBlock
 	var toolCalls []ToolCall
 
 	for _, block := range resp.Content {
@@ -193,6 +302,12 @@ func parseResponse(resp *anthropic.Message) *LLMResponse {
 		case "text":
 			tb := block.AsText()
 			content += tb.Text
+		case "thinking":
+			tb := block.AsThinking()
+			reasoning += tb.Thinking
+			thinking = append(thinking, ThinkingBlock{Type: "thinking", Thinking: tb.Thinking, Signature: tb.Signature})
+		case "redacted_thinking":
+			thinking = append(thinking, ThinkingBlock{Type: "redacted_thinking", Data: block.AsRedactedThinking().Data})
 		case "tool_use":
 			tu := block.AsToolUse()
 			var args map[string]interface{}
@@ -219,14 +334,25 @@ func parseResponse(resp *anthropic.Message) *LLMResponse {
 	}
 
 	return &LLMResponse{
-		Content:      content,
-		ToolCalls:    toolCalls,
-		FinishReason: finishReason,
-		Usage: &UsageInfo{
-			PromptTokens:     int(resp.Usage.InputTokens),
-			CompletionTokens: int(resp.Usage.OutputTokens),
-			TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
-		},
+		Content:          content,
+		ReasoningContent: reasoning,
+		ThinkingBlocks:   thinking,
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
+		Usage:            parseUsage(resp.Usage),
+	}
+}
+
+// parseUsage converts the API usage. Anthropic reports cached input
+// separately from input_tokens; PromptTokens counts all of it.
+func parseUsage(u anthropic.Usage) *UsageInfo {
+	prompt := int(u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens)
+	return &UsageInfo{
+		PromptTokens:     prompt,
+		CompletionTokens: int(u.OutputTokens),
+		TotalTokens:      prompt + int(u.OutputTokens),
+		CacheReadTokens:  int(u.CacheReadInputTokens),
+		CacheWriteTokens: int(u.CacheCreationInputTokens),
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers/protocoltypes"
 )
 
 func TestBuildParams_BasicMessage(t *testing.T) {
@@ -32,6 +33,36 @@ func TestBuildParams_BasicMessage(t *testing.T) {
 	}
 }
 
+func TestBuildParams_ImageContentParts(t *testing.T) {
+	messages := []Message{
+		{
+			Role:    "user",
+			Content: "What is this?",
+			ContentParts: []protocoltypes.ContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image", MediaType: "image/png", Data: "aGVsbG8="},
+			},
+		},
+	}
+	params, err := buildParams(messages, nil, "claude-sonnet-4.6", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+	blocks := params.Messages[0].Content
+	if len(blocks) != 2 {
+		t.Fatalf("len(Content) = %d, want 2", len(blocks))
+	}
+	if blocks[0].OfText == nil || blocks[0].OfText.Text != "What is this?" {
+		t.Errorf("Content[0] is not the text block")
+	}
+	if blocks[1].OfImage == nil || blocks[1].OfImage.Source.OfBase64 == nil {
+		t.Fatalf("Content[1] is not a base64 image block")
+	}
+	if got := blocks[1].OfImage.Source.OfBase64.Data; got != "aGVsbG8=" {
+		t.Errorf("image data = %q, want %q", got, "aGVsbG8=")
+	}
+}
+
 func TestBuildParams_SystemMessage(t *testing.T) {
 	messages := []Message{
 		{Role: "system", Content: "You are helpful"},
@@ -103,6 +134,155 @@ func TestBuildParams_WithTools(t *testing.T) {
 	}
 }
 
+func TestBuildParams_CacheBreakpoints(t *testing.T) {
+	tools := []ToolDefinition{
+		{Type: "function", Function: ToolFunctionDefinition{Name: "read_file", Parameters: map[string]interface{}{}}},
+		{Type: "function", Function: ToolFunctionDefinition{Name: "exec", Parameters: map[string]interface{}{}}},
+	}
+	messages := []Message{
+		{Role: "system", Content: "You are helpful"},
+		{Role: "user", Content: "Run ls"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "exec", Arguments: map[string]interface{}{"command": "ls"}}}},
+		{Role: "tool", Content: "file.txt", ToolCallID: "call_1"},
+	}
+	params, err := buildParams(messages, tools, "claude-sonnet-4.6", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+
+	if params.System[0].CacheControl.Type != "ephemeral" {
+		t.Error("system prompt should end with a cache breakpoint")
+	}
+	if params.Tools[0].OfTool.CacheControl.Type != "" || params.Tools[1].OfTool.CacheControl.Type != "ephemeral" {
+		t.Error("only the last tool should carry a cache breakpoint")
+	}
+	last := params.Messages[len(params.Messages)-1].Content[0]
+	if last.GetCacheControl().Type != "ephemeral" {
+		t.Error("the last history message should carry a cache breakpoint")
+	}
+	if params.Messages[0].Content[0].GetCacheControl().Type != "" {
+		t.Error("earlier messages should not carry a cache breakpoint")
+	}
+}
+
+func TestParseResponse_CacheUsage(t *testing.T) {
+	resp := &anthropic.Message{
+		Usage: anthropic.Usage{
+			InputTokens:              5,
+			CacheReadInputTokens:     100,
+			CacheCreationInputTokens: 20,
+			OutputTokens:             10,
+		},
+	}
+	usage := parseResponse(resp).Usage
+	if usage.PromptTokens != 125 || usage.TotalTokens != 135 {
+		t.Errorf("PromptTokens = %d, TotalTokens = %d, want 125 and 135", usage.PromptTokens, usage.TotalTokens)
+	}
+	if usage.CacheReadTokens != 100 || usage.CacheWriteTokens != 20 {
+		t.Errorf("cache read/write = %d/%d, want 100/20", usage.CacheReadTokens, usage.CacheWriteTokens)
+	}
+}
+
+func TestBuildParams_ExtendedThinking(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "Run ls"},
+		{
+			Role:             "assistant",
+			ReasoningContent: "I should list the files",
+			ThinkingBlocks:   []ThinkingBlock{{Type: "thinking", Thinking: "I should list the files", Signature: "sig"}},
+			ToolCalls:        []ToolCall{{ID: "call_1", Name: "exec", Arguments: map[string]interface{}{"command": "ls"}}},
+		},
+		{Role: "tool", Content: "file.txt", ToolCallID: "call_1"},
+	}
+	params, err := buildParams(messages, nil, "claude-sonnet-4.6", map[string]interface{}{
+		"max_tokens":                        4096,
+		"temperature":                       0.7,
+		protocoltypes.OptionReasoningEffort: "medium",
+	})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+
+	if params.Thinking.OfEnabled == nil || params.Thinking.OfEnabled.BudgetTokens != 8192 {
+		t.Fatalf("Thinking = %+v, want an 8192 token budget", params.Thinking)
+	}
+	if params.MaxTokens <= 8192 {
+		t.Errorf("MaxTokens = %d, must exceed the thinking budget", params.MaxTokens)
+	}
+	if params.Temperature.Valid() {
+		t.Error("temperature must not be sent with extended thinking")
+	}
+	first := params.Messages[1].Content[0]
+	if first.OfThinking == nil || first.OfThinking.Signature != "sig" {
+		t.Errorf("tool use turn should start with its thinking block, got %+v", first)
+	}
+}
+
+func TestParseResponse_Thinking(t *testing.T) {
+	var resp anthropic.Message
+	body := `{"content":[{"type":"thinking","thinking":"Let me think","signature":"sig"},{"type":"text","text":"Done"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":2}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	result := parseResponse(&resp)
+	if result.Content != "Done" || result.ReasoningContent != "Let me think" {
+		t.Errorf("unexpected response %+v", result)
+	}
+	if len(result.ThinkingBlocks) != 1 || result.ThinkingBlocks[0].Signature != "sig" {
+		t.Errorf("ThinkingBlocks = %+v, want one block signed %q", result.ThinkingBlocks, "sig")
+	}
+}
+
+func TestParseResponse_ThinkingBlocksRoundTrip(t *testing.T) {
+	var resp anthropic.Message
+	body := `{"content":[` +
+		`{"type":"thinking","thinking":"First ","signature":"sig1"},` +
+		`{"type":"redacted_thinking","data":"opaque"},` +
+		`{"type":"thinking","thinking":"second","signature":"sig2"},` +
+		`{"type":"tool_use","id":"call_1","name":"exec","input":{"command":"ls"}}` +
+		`],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":2}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	result := parseResponse(&resp)
+	if result.ReasoningContent != "First second" {
+		t.Errorf("ReasoningContent = %q, want %q", result.ReasoningContent, "First second")
+	}
+
+	messages := []Message{
+		{Role: "user", Content: "Run ls"},
+		{
+			Role:             "assistant",
+			ReasoningContent: result.ReasoningContent,
+			ThinkingBlocks:   result.ThinkingBlocks,
+			ToolCalls:        result.ToolCalls,
+		},
+		{Role: "tool", Content: "file.txt", ToolCallID: "call_1"},
+	}
+	params, err := buildParams(messages, nil, "claude-sonnet-4.6", map[string]interface{}{
+		protocoltypes.OptionReasoningEffort: "medium",
+	})
+	if err != nil {
+		t.Fatalf("buildParams() error: %v", err)
+	}
+	blocks := params.Messages[1].Content
+	if len(blocks) != 4 {
+		t.Fatalf("assistant turn has %d blocks, want 4", len(blocks))
+	}
+	if b := blocks[0].OfThinking; b == nil || b.Thinking != "First " || b.Signature != "sig1" {
+		t.Errorf("block 0 = %+v, want thinking signed sig1", blocks[0])
+	}
+	if b := blocks[1].OfRedactedThinking; b == nil || b.Data != "opaque" {
+		t.Errorf("block 1 = %+v, want redacted_thinking", blocks[1])
+	}
+	if b := blocks[2].OfThinking; b == nil || b.Thinking != "second" || b.Signature != "sig2" {
+		t.Errorf("block 2 = %+v, want thinking signed sig2", blocks[2])
+	}
+	if blocks[3].OfToolUse == nil {
+		t.Errorf("block 3 = %+v, want tool_use", blocks[3])
+	}
+}
+
 func TestParseResponse_TextOnly(t *testing.T) {
 	resp := &anthropic.Message{
 		Content: []anthropic.ContentBlockUnion{},
@@ -152,6 +152,12 @@ type part struct {
 	ThoughtSignatureSnake string                `json:"thought_signature,omitempty"`
 	FunctionCall          *functionCall         `json:"functionCall,omitempty"`
 	FunctionResponse      *functionResponse     `json:"functionResponse,omitempty"`
+	InlineData            *inlineData           `json:"inlineData,omitempty"`
+}
+
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64-encoded bytes
 }
 
 type functionCall struct {
@@ -179,8 +185,28 @@ type systemPrompt struct {
 }
 
 type genConfig struct {
-	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
-	Temperature     float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int             `json:"maxOutputTokens,omitempty"`
+	Temperature     float64         `json:"temperature,omitempty"`
+	ThinkingConfig  *thinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type thinkingConfig struct {
+	ThinkingBudget int `json:"thinkingBudget"`
+}
+
+// translateContentParts encodes multimodal parts as Gemini parts, images as
+// inline data.
+func translateContentParts(parts []protocoltypes.ContentPart) []part {
+	out := make([]part, 0, len(parts))
+	for _, cp := range parts {
+		switch cp.Type {
+		case "text":
+			out = append(out, part{Text: cp.Text})
+		case "image":
+			out = append(out, part{InlineData: &inlineData{MimeType: cp.MediaType, Data: cp.Data}})
+		}
+	}
+	return out
 }
 
 func (p *Provider) buildRequest(messages []protocoltypes.Message, tools []protocoltypes.ToolDefinition, model string, options map[string]interface{}) request {
@@ -207,6 +233,11 @@ func (p *Provider) buildRequest(messages []protocoltypes.Message, tools []protoc
 						},
 					}},
 				})
+			} else if len(msg.ContentParts) > 0 {
+				req.Contents = append(req.Contents, content{
+					Role:  "user",
+					Parts: translateContentParts(msg.ContentParts),
+				})
 			} else {
 				req.Contents = append(req.Contents, content{
 					Role:  "user",
@@ -288,7 +319,14 @@ func (p *Provider) buildRequest(messages []protocoltypes.Message, tools []protoc
 	if temp, ok := options["temperature"].(float64); ok {
 		config.Temperature = temp
 	}
-	if config.MaxOutputTokens > 0 || config.Temperature > 0 {
+	effort, budget := protocoltypes.ReasoningOptions(options)
+	if budget == 0 {
+		budget = protocoltypes.ThinkingBudgetFor(effort)
+	}
+	if budget > 0 {
+		config.ThinkingConfig = &thinkingConfig{ThinkingBudget: budget}
+	}
+	if config.MaxOutputTokens > 0 || config.Temperature > 0 || config.ThinkingConfig != nil {
 		req.Config = config
 	}
 
@@ -57,6 +57,30 @@ func TestBuildRequestUsesFunctionFieldsWhenToolCallNameMissing(t *testing.T) {
 	}
 }
 
+func TestBuildRequestSendsImagesAsInlineData(t *testing.T) {
+	p := &Provider{}
+	messages := []protocoltypes.Message{{
+		Role:    "user",
+		Content: "what is this?",
+		ContentParts: []protocoltypes.ContentPart{
+			{Type: "text", Text: "what is this?"},
+			{Type: "image", MediaType: "image/png", Data: "aGVsbG8="},
+		},
+	}}
+
+	req := p.buildRequest(messages, nil, "", nil)
+	if len(req.Contents) != 1 || len(req.Contents[0].Parts) != 2 {
+		t.Fatalf("contents = %+v", req.Contents)
+	}
+	parts := req.Contents[0].Parts
+	if parts[0].Text != "what is this?" {
+		t.Errorf("text part = %+v", parts[0])
+	}
+	if parts[1].InlineData == nil || parts[1].InlineData.MimeType != "image/png" || parts[1].InlineData.Data != "aGVsbG8=" {
+		t.Errorf("image part = %+v", parts[1])
+	}
+}
+
 func TestResolveToolResponseNameInfersNameFromGeneratedCallID(t *testing.T) {
 	got := resolveToolResponseName("call_search_docs_999", map[string]string{})
 	if got != "search_docs" {
@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/utils"
+)
+
+// Cassette modes.
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// cassetteEntry is one line of a cassette file: an LLM request and what the
+// provider answered.
+type cassetteEntry struct {
+	Hash     string          `json:"hash"`
+	Model    string          `json:"model"`
+	Request  cassetteRequest `json:"request"`
+	Response *LLMResponse    `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type cassetteRequest struct {
+	Messages []Message              `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// Cassette records LLM requests and responses to a JSONL file, or replays
+// them from one. Requests are matched by a hash of the model, tools and
+// non-system messages; system prompts are left out because they embed the
+// current time and workspace path. Identical requests replay in recorded order.
+type Cassette struct {
+	mode string
+	path string
+
+	mu       sync.Mutex
+	file     *os.File                   // record mode
+	pending  map[string][]cassetteEntry // replay mode: hash -> unplayed entries
+	unplayed int
+}
+
+// OpenCassette opens a cassette file. Recording appends to the file;
+// replaying loads all of it up front.
+func OpenCassette(mode, path string) (*Cassette, error) {
+	c := &Cassette{mode: mode, path: path}
+	switch mode {
+	case CassetteRecord:
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cassette: %w", err)
+		}
+		c.file = f
+	case CassetteReplay:
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q (want %q or %q)", mode, CassetteRecord, CassetteReplay)
+	}
+	return c, nil
+}
+
+func (c *Cassette) load() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+
+	c.pending = make(map[string][]cassetteEntry)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("cassette %s line %d: %w", c.path, line, err)
+		}
+		c.pending[entry.Hash] = append(c.pending[entry.Hash], entry)
+		c.unplayed++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read cassette: %w", err)
+	}
+	return nil
+}
+
+// Mode returns CassetteRecord or CassetteReplay.
+func (c *Cassette) Mode() string {
+	return c.mode
+}
+
+// Unplayed returns how many recorded entries have not been replayed yet.
+func (c *Cassette) Unplayed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unplayed
+}
+
+// Close closes the cassette file.
+func (c *Cassette) Close() error {
+	if c.file != nil {
+		return c.file.Close()
+	}
+	return nil
+}
+
+// Wrap returns a provider that records the calls made to inner, or that
+// answers from the cassette without calling inner when replaying.
+func (c *Cassette) Wrap(inner LLMProvider) LLMProvider {
+	if c.mode == CassetteReplay {
+		return &replayProvider{cassette: c, inner: inner}
+	}
+	if sp, ok := inner.(StreamingProvider); ok {
+		return &recordingStreamProvider{recordingProvider{cassette: c, inner: inner}, sp}
+	}
+	return &recordingProvider{cassette: c, inner: inner}
+}
+
+func (c *Cassette) record(entry cassetteEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cassette: failed to encode entry: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("cassette: failed to write entry: %w", err)
+	}
+	return nil
+}
+
+func (c *Cassette) replay(hash, model string, messages []Message) (*LLMResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queue := c.pending[hash]
+	if len(queue) == 0 {
+		last := ""
+		if len(messages) > 0 {
+			m := messages[len(messages)-1]
+			last = fmt.Sprintf(" after %s message %q", m.Role, utils.Truncate(m.Content, 80))
+		}
+		return nil, fmt.Errorf("cassette %s: no recorded response for request %s (model %s%s); %d recorded requests left unplayed",
+			c.path, hash[:12], model, last, c.unplayed)
+	}
+	entry := queue[0]
+	c.pending[hash] = queue[1:]
+	c.unplayed--
+
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	return entry.Response, nil
+}
+
+// cassetteHash identifies a request independently of the system prompt and
+// of the order of the tools.
+func cassetteHash(messages []Message, tools []ToolDefinition, model string) string {
+	tools = slices.Clone(tools)
+	slices.SortFunc(tools, func(a, b ToolDefinition) int {
+		return strings.Compare(a.Function.Name, b.Function.Name)
+	})
+
+	var conversation []Message
+	for _, m := range messages {
+		if m.Role != "system" {
+			conversation = append(conversation, m)
+		}
+	}
+	data, _ := json.Marshal(struct {
+		Model    string           `json:"model"`
+		Tools    []ToolDefinition `json:"tools"`
+		Messages []Message        `json:"messages"`
+	}{model, tools, conversation})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type recordingProvider struct {
+	cassette *Cassette
+	inner    LLMProvider
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	return resp, p.save(messages, tools, model, options, resp, err)
+}
+
+func (p *recordingProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// save records a call and passes its error through. Cancelled calls are not
+// recorded since they say nothing about the provider.
+func (p *recordingProvider) save(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, resp *LLMResponse, callErr error) error {
+	if errors.Is(callErr, context.Canceled) {
+		return callErr
+	}
+	entry := cassetteEntry{
+		Hash:     cassetteHash(messages, tools, model),
+		Model:    model,
+		Request:  cassetteRequest{Messages: messages, Tools: tools, Options: options},
+		Response: resp,
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+	if err := p.cassette.record(entry); err != nil && callErr == nil {
+		return err
+	}
+	return callErr
+}
+
+type recordingStreamProvider struct {
+	recordingProvider
+	stream StreamingProvider
+}
+
+func (p *recordingStreamProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	resp, err := p.stream.ChatStream(ctx, messages, tools, model, options, onDelta)
+	return resp, p.save(messages, tools, model, options, resp, err)
+}
+
+type replayProvider struct {
+	cassette *Cassette
+	inner    LLMProvider // may be nil; only asked for its default model
+}
+
+func (p *replayProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.cassette.replay(cassetteHash(messages, tools, model), model, messages)
+}
+
+func (p *replayProvider) GetDefaultModel() string {
+	if p.inner != nil {
+		return p.inner.GetDefaultModel()
+	}
+	return ""
+}
+
+// activeCassette, when set, wraps every provider created from config.
+var activeCassette atomic.Pointer[Cassette]
+
+// UseCassette makes CreateProvider and CreateProviderFromConfig record to or
+// replay from c. Replaying providers never contact the real backend. Pass nil
+// to stop.
+func UseCassette(c *Cassette) {
+	activeCassette.Store(c)
+}
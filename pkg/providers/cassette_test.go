@@ -0,0 +1,125 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+)
+
+// countingProvider answers with the number of calls made so far.
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (p *countingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &LLMResponse{Content: strings.Repeat("a", p.calls), FinishReason: "stop"}, nil
+}
+
+func (p *countingProvider) GetDefaultModel() string {
+	return "counting"
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	question := []Message{{Role: "system", Content: "time: 10:00"}, {Role: "user", Content: "hi"}}
+	ctx := context.Background()
+
+	recorder, err := OpenCassette(CassetteRecord, path)
+	if err != nil {
+		t.Fatalf("OpenCassette(record): %v", err)
+	}
+	inner := &countingProvider{}
+	p := recorder.Wrap(inner)
+	p.Chat(ctx, question, nil, "gpt", nil)
+	p.Chat(ctx, question, nil, "gpt", nil)
+	inner.err = errors.New("rate limited")
+	p.Chat(ctx, []Message{{Role: "user", Content: "again"}}, nil, "gpt", nil)
+	recorder.Close()
+
+	player, err := OpenCassette(CassetteReplay, path)
+	if err != nil {
+		t.Fatalf("OpenCassette(replay): %v", err)
+	}
+	replay := player.Wrap(nil)
+
+	// The system prompt differs, the conversation does not
+	later := []Message{{Role: "system", Content: "time: 11:00"}, {Role: "user", Content: "hi"}}
+	for _, want := range []string{"a", "aa"} {
+		resp, err := replay.Chat(ctx, later, nil, "gpt", nil)
+		if err != nil || resp.Content != want {
+			t.Fatalf("replay = %v, %v; want %q", resp, err, want)
+		}
+	}
+	if _, err := replay.Chat(ctx, []Message{{Role: "user", Content: "again"}}, nil, "gpt", nil); err == nil || err.Error() != "rate limited" {
+		t.Errorf("recorded error not replayed, got %v", err)
+	}
+	if player.Unplayed() != 0 {
+		t.Errorf("Unplayed = %d, want 0", player.Unplayed())
+	}
+}
+
+func TestCassette_ReplayMismatchFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	recorder, _ := OpenCassette(CassetteRecord, path)
+	recorder.Wrap(&countingProvider{}).Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt", nil)
+	recorder.Close()
+
+	player, _ := OpenCassette(CassetteReplay, path)
+	_, err := player.Wrap(nil).Chat(context.Background(), []Message{{Role: "user", Content: "bye"}}, nil, "gpt", nil)
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") || !strings.Contains(err.Error(), "bye") {
+		t.Errorf("expected a mismatch error naming the request, got %v", err)
+	}
+}
+
+func TestCassette_ReplayIgnoresToolOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	question := []Message{{Role: "user", Content: "hi"}}
+	read := ToolDefinition{Type: "function", Function: ToolFunctionDefinition{Name: "read_file"}}
+	exec := ToolDefinition{Type: "function", Function: ToolFunctionDefinition{Name: "exec"}}
+
+	recorder, _ := OpenCassette(CassetteRecord, path)
+	recorder.Wrap(&countingProvider{}).Chat(context.Background(), question, []ToolDefinition{read, exec}, "gpt", nil)
+	recorder.Close()
+
+	player, _ := OpenCassette(CassetteReplay, path)
+	resp, err := player.Wrap(nil).Chat(context.Background(), question, []ToolDefinition{exec, read}, "gpt", nil)
+	if err != nil || resp.Content != "a" {
+		t.Fatalf("replay with reordered tools = %v, %v; want %q", resp, err, "a")
+	}
+}
+
+func TestCassette_WrapsProvidersFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	recorder, _ := OpenCassette(CassetteRecord, path)
+	recorder.Close()
+	player, err := OpenCassette(CassetteReplay, path)
+	if err != nil {
+		t.Fatalf("OpenCassette(replay): %v", err)
+	}
+	UseCassette(player)
+	t.Cleanup(func() { UseCassette(nil) })
+
+	// Replaying needs no credentials and never builds the real provider
+	p, modelID, err := CreateProviderFromConfig(&config.ModelConfig{ModelName: "m", Model: "anthropic/claude-x"})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig: %v", err)
+	}
+	if _, ok := p.(*replayProvider); !ok || modelID != "claude-x" {
+		t.Errorf("got %T with model %q, want a replay provider for claude-x", p, modelID)
+	}
+}
+
+func TestOpenCassette_UnknownMode(t *testing.T) {
+	if _, err := OpenCassette("rewind", filepath.Join(t.TempDir(), "x.jsonl")); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
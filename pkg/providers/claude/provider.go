@@ -54,6 +54,10 @@ func (p *Provider) Chat(ctx context.Context, messages []protocoltypes.Message, t
 	return resp, nil
 }
 
+func (p *Provider) ChatStream(ctx context.Context, messages []protocoltypes.Message, tools []protocoltypes.ToolDefinition, model string, options map[string]interface{}, onDelta protocoltypes.StreamCallback) (*protocoltypes.LLMResponse, error) {
+	return p.delegate.ChatStream(ctx, messages, tools, model, options, onDelta)
+}
+
 func (p *Provider) GetDefaultModel() string {
 	return p.delegate.GetDefaultModel()
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
 	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers/protocoltypes"
 )
@@ -54,6 +55,16 @@ func NewProviderWithTokenSource(token, accountID string, tokenSource func() (str
 }
 
 func (p *Provider) Chat(ctx context.Context, messages []protocoltypes.Message, tools []protocoltypes.ToolDefinition, model string, options map[string]interface{}) (*protocoltypes.LLMResponse, error) {
+	return p.chat(ctx, messages, tools, model, options, nil)
+}
+
+// ChatStream forwards output text deltas to onDelta as they arrive. The Codex
+// backend always streams, so this differs from Chat only in the callback.
+func (p *Provider) ChatStream(ctx context.Context, messages []protocoltypes.Message, tools []protocoltypes.ToolDefinition, model string, options map[string]interface{}, onDelta protocoltypes.StreamCallback) (*protocoltypes.LLMResponse, error) {
+	return p.chat(ctx, messages, tools, model, options, onDelta)
+}
+
+func (p *Provider) chat(ctx context.Context, messages []protocoltypes.Message, tools []protocoltypes.ToolDefinition, model string, options map[string]interface{}, onDelta protocoltypes.StreamCallback) (*protocoltypes.LLMResponse, error) {
 	var opts []option.RequestOption
 	accountID := p.accountID
 	resolvedModel, fallbackReason := resolveModel(model)
@@ -91,6 +102,9 @@ func (p *Provider) Chat(ctx context.Context, messages []protocoltypes.Message, t
 	var resp *responses.Response
 	for stream.Next() {
 		evt := stream.Current()
+		if onDelta != nil && evt.Type == "response.output_text.delta" && evt.Delta != "" {
+			onDelta(evt.Delta)
+		}
 		if evt.Type == "response.completed" || evt.Type == "response.failed" || evt.Type == "response.incomplete" {
 			evtResp := evt.Response
 			if evtResp.ID != "" {
@@ -203,6 +217,13 @@ func buildParams(messages []protocoltypes.Message, tools []protocoltypes.ToolDef
 						Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
 					},
 				})
+			} else if len(msg.ContentParts) > 0 {
+				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
+					OfMessage: &responses.EasyInputMessageParam{
+						Role:    responses.EasyInputMessageRoleUser,
+						Content: responses.EasyInputMessageContentUnionParam{OfInputItemContentList: translateContentParts(msg.ContentParts)},
+					},
+				})
 			} else {
 				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
 					OfMessage: &responses.EasyInputMessageParam{
@@ -273,6 +294,14 @@ func buildParams(messages []protocoltypes.Message, tools []protocoltypes.ToolDef
 		params.Tools = translateTools(tools, enableWebSearch)
 	}
 
+	effort, budget := protocoltypes.ReasoningOptions(options)
+	if effort == "" {
+		effort = protocoltypes.ReasoningEffortFor(budget)
+	}
+	if effort != "" {
+		params.Reasoning = shared.ReasoningParam{Effort: shared.ReasoningEffort(effort)}
+	}
+
 	return params
 }
 
@@ -300,6 +329,24 @@ func resolveToolCall(tc protocoltypes.ToolCall) (name string, arguments string,
 	return name, "{}", true
 }
 
+func translateContentParts(parts []protocoltypes.ContentPart) responses.ResponseInputMessageContentListParam {
+	content := make(responses.ResponseInputMessageContentListParam, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			content = append(content, responses.ResponseInputContentParamOfInputText(part.Text))
+		case "image":
+			content = append(content, responses.ResponseInputContentUnionParam{
+				OfInputImage: &responses.ResponseInputImageParam{
+					Detail:   responses.ResponseInputImageDetailAuto,
+					ImageURL: openai.Opt(part.DataURL()),
+				},
+			})
+		}
+	}
+	return content
+}
+
 func translateTools(tools []protocoltypes.ToolDefinition, enableWebSearch bool) []responses.ToolUnionParam {
 	capHint := len(tools)
 	if enableWebSearch {
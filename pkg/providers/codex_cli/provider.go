@@ -210,6 +210,7 @@ func (p *Provider) parseJSONLEvents(output string) (*protocoltypes.LLMResponse,
 					PromptTokens:     promptTokens,
 					CompletionTokens: event.Usage.OutputTokens,
 					TotalTokens:      promptTokens + event.Usage.OutputTokens,
+					CacheReadTokens:  event.Usage.CachedInputTokens,
 				}
 			}
 			// Ensure usage is always non-nil after turn.completed
@@ -62,7 +62,24 @@ func ExtractProtocol(model string) (protocol, modelID string) {
 // It uses the protocol prefix in the Model field to determine which provider to create.
 // Supported protocols: openai, anthropic, antigravity, claude-cli, codex-cli, github-copilot
 // Returns the provider, the model ID (without protocol prefix), and any error.
+// Providers are wrapped by the cassette set with UseCassette, if any.
 func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
+	cassette := activeCassette.Load()
+	if cassette == nil {
+		return createProviderFromConfig(cfg)
+	}
+	if cassette.Mode() == CassetteReplay && cfg != nil && cfg.Model != "" {
+		_, modelID := ExtractProtocol(cfg.Model)
+		return cassette.Wrap(nil), modelID, nil
+	}
+	provider, modelID, err := createProviderFromConfig(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return cassette.Wrap(provider), modelID, nil
+}
+
+func createProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
 	if cfg == nil {
 		return nil, "", fmt.Errorf("config is nil")
 	}
@@ -10,8 +10,10 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers/protocoltypes"
 )
 
@@ -30,6 +32,9 @@ type Provider struct {
 	apiBase        string
 	maxTokensField string // Field name for max tokens (e.g., "max_completion_tokens" for o1/glm models)
 	httpClient     *http.Client
+
+	// noStreamUsage is set once the backend has rejected stream_options.
+	noStreamUsage atomic.Bool
 }
 
 func NewProvider(apiKey, apiBase, proxy string) *Provider {
@@ -66,10 +71,88 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 	}
 
 	model = normalizeModel(model, p.apiBase)
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+
+	resp, start, err := p.post(ctx, requestBody, model)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("[DEBUG] LLM Response: URL=%s, Status=%d, Error=%s, Duration=%dms", resp.Request.URL.String(), resp.StatusCode, err.Error(), time.Since(start).Milliseconds())
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("[DEBUG] LLM Response: URL=%s, Status=%d, Duration=%dms", resp.Request.URL.String(), resp.StatusCode, time.Since(start).Milliseconds())
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+
+	log.Printf("[DEBUG] LLM Response: URL=%s, Status=%d, Duration=%dms", resp.Request.URL.String(), resp.StatusCode, time.Since(start).Milliseconds())
+	return parseResponse(body)
+}
+
+// ChatStream sends the request with "stream": true and calls onDelta for every
+// content chunk. Tool calls and usage are accumulated into the returned response.
+func (p *Provider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta protocoltypes.StreamCallback) (*LLMResponse, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+
+	model = normalizeModel(model, p.apiBase)
+	requestBody := p.buildRequestBody(messages, tools, model, options)
+	requestBody["stream"] = true
+	includeUsage := !p.noStreamUsage.Load()
+	if includeUsage {
+		requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	resp, start, err := p.post(ctx, requestBody, model)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusBadRequest && includeUsage {
+		// Some OpenAI-compatible backends reject stream_options. Retry without
+		// it, and leave it out from then on if that works.
+		resp.Body.Close()
+		delete(requestBody, "stream_options")
+		if resp, start, err = p.post(ctx, requestBody, model); err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			p.noStreamUsage.Store(true)
+			logger.WarnCF("provider.openai_compat", "Backend rejected stream_options, streaming without usage", map[string]interface{}{
+				"api_base": p.apiBase,
+			})
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		logger.DebugCF("provider.openai_compat", "LLM stream failed", map[string]interface{}{
+			"url":         resp.Request.URL.String(),
+			"status":      resp.StatusCode,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
 
+	result, err := parseStream(resp.Body, onDelta)
+	logger.DebugCF("provider.openai_compat", "LLM stream finished", map[string]interface{}{
+		"url":         resp.Request.URL.String(),
+		"status":      resp.StatusCode,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+	return result, err
+}
+
+func (p *Provider) buildRequestBody(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) map[string]interface{} {
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": wireMessages(messages),
 	}
 
 	if len(tools) > 0 {
@@ -92,6 +175,28 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 		requestBody[fieldName] = maxTokens
 	}
 
+	effort, budget := protocoltypes.ReasoningOptions(options)
+	if effort == "" {
+		effort = protocoltypes.ReasoningEffortFor(budget)
+	}
+	if effort != "" {
+		lowerModel := strings.ToLower(model)
+		switch {
+		case strings.Contains(lowerModel, "deepseek"):
+			// DeepSeek reasons depending on the model (deepseek-reasoner) and
+			// takes no reasoning parameters; reasoning_content comes back as is.
+		case strings.Contains(lowerModel, "qwen") || strings.Contains(p.apiBase, "dashscope"):
+			// Qwen takes a thinking switch and budget instead of an effort level
+			if budget == 0 {
+				budget = protocoltypes.ThinkingBudgetFor(effort)
+			}
+			requestBody["enable_thinking"] = true
+			requestBody["thinking_budget"] = budget
+		default:
+			requestBody["reasoning_effort"] = effort
+		}
+	}
+
 	if temperature, ok := asFloat(options["temperature"]); ok {
 		lowerModel := strings.ToLower(model)
 		// Kimi k2 models only support temperature=1.
@@ -102,14 +207,19 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 		}
 	}
 
+	return requestBody
+}
+
+// post sends requestBody to the chat completions endpoint. The caller owns the response body.
+func (p *Provider) post(ctx context.Context, requestBody map[string]interface{}, model string) (*http.Response, time.Time, error) {
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, time.Time{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -124,23 +234,40 @@ func (p *Provider) Chat(ctx context.Context, messages []Message, tools []ToolDef
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		log.Printf("[DEBUG] LLM Response: URL=%s, Error=%s, Duration=%dms", req.URL.String(), err.Error(), time.Since(start).Milliseconds())
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, start, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
+	return resp, start, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("[DEBUG] LLM Response: URL=%s, Status=%d, Error=%s, Duration=%dms", req.URL.String(), resp.StatusCode, err.Error(), time.Since(start).Milliseconds())
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+// wireMessages converts messages carrying content parts into the OpenAI
+// multimodal format. Plain messages are passed through unchanged.
+func wireMessages(messages []Message) []interface{} {
+	out := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		msg.ThinkingBlocks = nil // Anthropic only
+		if len(msg.ContentParts) == 0 {
+			out = append(out, msg)
+			continue
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[DEBUG] LLM Response: URL=%s, Status=%d, Duration=%dms", req.URL.String(), resp.StatusCode, time.Since(start).Milliseconds())
-		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+		parts := make([]map[string]interface{}, 0, len(msg.ContentParts))
+		for _, part := range msg.ContentParts {
+			switch part.Type {
+			case "text":
+				parts = append(parts, map[string]interface{}{"type": "text", "text": part.Text})
+			case "image":
+				parts = append(parts, map[string]interface{}{
+					"type":      "image_url",
+					"image_url": map[string]interface{}{"url": part.DataURL()},
+				})
+			}
+		}
+		out = append(out, map[string]interface{}{
+			"role":    msg.Role,
+			"content": parts,
+		})
 	}
-
-	log.Printf("[DEBUG] LLM Response: URL=%s, Status=%d, Duration=%dms", req.URL.String(), resp.StatusCode, time.Since(start).Milliseconds())
-	return parseResponse(body)
+	return out
 }
 
 func parseResponse(body []byte) (*LLMResponse, error) {
@@ -165,7 +292,7 @@ func parseResponse(body []byte) (*LLMResponse, error) {
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
-		Usage *UsageInfo `json:"usage"`
+		Usage *apiUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
@@ -225,10 +352,43 @@ func parseResponse(body []byte) (*LLMResponse, error) {
 		ReasoningContent: choice.Message.ReasoningContent,
 		ToolCalls:        toolCalls,
 		FinishReason:     choice.FinishReason,
-		Usage:            apiResponse.Usage,
+		Usage:            apiResponse.Usage.toUsageInfo(),
 	}, nil
 }
 
+// apiUsage is the usage object of a chat completion. Backends that cache
+// prompts report the cached part in different places: OpenAI in
+// prompt_tokens_details, Moonshot at the top level, DeepSeek as cache hits.
+type apiUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+	CachedTokens         int `json:"cached_tokens,omitempty"`
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens,omitempty"`
+}
+
+func (u *apiUsage) toUsageInfo() *UsageInfo {
+	if u == nil {
+		return nil
+	}
+	info := &UsageInfo{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CacheReadTokens:  u.CachedTokens,
+	}
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		info.CacheReadTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.PromptCacheHitTokens > 0 {
+		info.CacheReadTokens = u.PromptCacheHitTokens
+	}
+	return info
+}
+
 func normalizeModel(model, apiBase string) string {
 	idx := strings.Index(model, "/")
 	if idx == -1 {
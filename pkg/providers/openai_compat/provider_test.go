@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/providers/protocoltypes"
 )
 
 func TestProviderChat_UsesMaxCompletionTokensForGLM(t *testing.T) {
@@ -95,6 +97,53 @@ func TestProviderChat_ParsesToolCalls(t *testing.T) {
 	}
 }
 
+func TestParseResponse_CachedTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage string
+	}{
+		{"openai", `{"prompt_tokens":100,"completion_tokens":5,"total_tokens":105,"prompt_tokens_details":{"cached_tokens":80}}`},
+		{"moonshot", `{"prompt_tokens":100,"completion_tokens":5,"total_tokens":105,"cached_tokens":80}`},
+		{"deepseek", `{"prompt_tokens":100,"completion_tokens":5,"total_tokens":105,"prompt_cache_hit_tokens":80,"prompt_cache_miss_tokens":20}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}],"usage":` + tt.usage + `}`
+			out, err := parseResponse([]byte(body))
+			if err != nil {
+				t.Fatalf("parseResponse() error = %v", err)
+			}
+			if out.Usage.PromptTokens != 100 || out.Usage.CacheReadTokens != 80 {
+				t.Errorf("usage = %+v, want 100 prompt tokens with 80 cached", out.Usage)
+			}
+		})
+	}
+}
+
+func TestBuildRequestBody_ReasoningOptions(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "hi"}}
+
+	p := NewProvider("key", "https://api.openai.com/v1", "")
+	body := p.buildRequestBody(messages, nil, "o3", map[string]interface{}{protocoltypes.OptionThinkingBudget: 8000})
+	if body["reasoning_effort"] != "medium" {
+		t.Errorf("reasoning_effort = %v, want medium", body["reasoning_effort"])
+	}
+
+	qwen := NewProvider("key", "https://dashscope.aliyuncs.com/compatible-mode/v1", "")
+	body = qwen.buildRequestBody(messages, nil, "qwen3-max", map[string]interface{}{protocoltypes.OptionReasoningEffort: "low"})
+	if body["enable_thinking"] != true || body["thinking_budget"] != 2048 {
+		t.Errorf("qwen body = %v, want enable_thinking with a 2048 budget", body)
+	}
+	if _, ok := body["reasoning_effort"]; ok {
+		t.Error("qwen should not receive reasoning_effort")
+	}
+
+	body = p.buildRequestBody(messages, nil, "gpt-4o", map[string]interface{}{})
+	if _, ok := body["reasoning_effort"]; ok {
+		t.Error("reasoning_effort should only be sent when requested")
+	}
+}
+
 func TestProviderChat_HTTPError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
@@ -275,3 +324,144 @@ func TestNormalizeModel_UsesAPIBase(t *testing.T) {
 		t.Fatalf("normalizeModel(openrouter) = %q, want %q", got, "openrouter/auto")
 	}
 }
+
+func TestProviderChatStream_AccumulatesDeltasAndToolCalls(t *testing.T) {
+	var requestBody map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"ci"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ty\":\"SF\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		}
+		for _, c := range chunks {
+			w.Write([]byte("data: " + c + "\n\n"))
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	var deltas []string
+	p := NewProvider("key", server.URL, "")
+	out, err := p.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+
+	if requestBody["stream"] != true {
+		t.Fatalf("stream = %v, want true", requestBody["stream"])
+	}
+	if len(deltas) != 2 || deltas[0] != "Hel" || deltas[1] != "lo" {
+		t.Fatalf("deltas = %v, want [Hel lo]", deltas)
+	}
+	if out.Content != "Hello" {
+		t.Fatalf("Content = %q, want %q", out.Content, "Hello")
+	}
+	if out.FinishReason != "tool_calls" {
+		t.Fatalf("FinishReason = %q, want %q", out.FinishReason, "tool_calls")
+	}
+	if len(out.ToolCalls) != 1 || out.ToolCalls[0].Name != "get_weather" || out.ToolCalls[0].Arguments["city"] != "SF" {
+		t.Fatalf("ToolCalls = %+v", out.ToolCalls)
+	}
+	if out.Usage == nil || out.Usage.TotalTokens != 15 {
+		t.Fatalf("Usage = %+v", out.Usage)
+	}
+}
+
+func TestProviderChat_SendsImageContentParts(t *testing.T) {
+	var requestBody map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"content": "a cat"}, "finish_reason": "stop"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	_, err := p.Chat(t.Context(), []Message{
+		{Role: "system", Content: "sys"},
+		{
+			Role:    "user",
+			Content: "what is this?",
+			ContentParts: []protocoltypes.ContentPart{
+				{Type: "text", Text: "what is this?"},
+				{Type: "image", MediaType: "image/png", Data: "aGVsbG8="},
+			},
+		},
+	}, nil, "gpt-4o", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	messages := requestBody["messages"].([]interface{})
+	if content, _ := messages[0].(map[string]interface{})["content"].(string); content != "sys" {
+		t.Fatalf("system content = %v, want plain string", messages[0])
+	}
+	parts, ok := messages[1].(map[string]interface{})["content"].([]interface{})
+	if !ok || len(parts) != 2 {
+		t.Fatalf("user content = %v, want 2 parts", messages[1])
+	}
+	image := parts[1].(map[string]interface{})
+	if image["type"] != "image_url" {
+		t.Fatalf("part type = %v, want image_url", image["type"])
+	}
+	url := image["image_url"].(map[string]interface{})["url"]
+	if url != "data:image/png;base64,aGVsbG8=" {
+		t.Fatalf("image url = %v", url)
+	}
+}
+
+func TestProviderChatStream_RetriesWithoutStreamOptions(t *testing.T) {
+	var withOptions, withoutOptions int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := requestBody["stream_options"]; ok {
+			withOptions++
+			http.Error(w, `{"error":"Unrecognized request argument supplied: stream_options"}`, http.StatusBadRequest)
+			return
+		}
+		withoutOptions++
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewProvider("key", server.URL, "")
+	for i := 0; i < 2; i++ {
+		out, err := p.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil, func(string) {})
+		if err != nil {
+			t.Fatalf("ChatStream() error = %v", err)
+		}
+		if out.Content != "ok" {
+			t.Fatalf("Content = %q, want %q", out.Content, "ok")
+		}
+	}
+
+	if withOptions != 1 || withoutOptions != 2 {
+		t.Errorf("requests with stream_options = %d, without = %d; want 1 and 2", withOptions, withoutOptions)
+	}
+}
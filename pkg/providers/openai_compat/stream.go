@@ -0,0 +1,138 @@
+package openai_compat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/providers/protocoltypes"
+)
+
+type streamToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function *struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function,omitempty"`
+	ExtraContent *struct {
+		Google *struct {
+			ThoughtSignature string `json:"thought_signature"`
+		} `json:"google"`
+	} `json:"extra_content,omitempty"`
+}
+
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Index int `json:"index"`
+				streamToolCall
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *apiUsage `json:"usage"`
+}
+
+// parseStream reads an SSE chat completion stream and assembles the complete
+// response. Tool call fragments are merged by index, the way the API sends them.
+func parseStream(r io.Reader, onDelta protocoltypes.StreamCallback) (*LLMResponse, error) {
+	var content, reasoning strings.Builder
+	var finishReason string
+	var usage *apiUsage
+	toolCalls := make(map[int]*streamToolCall)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			for _, tc := range choice.Delta.ToolCalls {
+				acc, ok := toolCalls[tc.Index]
+				if !ok {
+					acc = &streamToolCall{}
+					toolCalls[tc.Index] = acc
+				}
+				if tc.ID != "" {
+					acc.ID = tc.ID
+				}
+				if tc.Type != "" {
+					acc.Type = tc.Type
+				}
+				if tc.ExtraContent != nil {
+					acc.ExtraContent = tc.ExtraContent
+				}
+				if tc.Function != nil {
+					if acc.Function == nil {
+						acc.Function = tc.Function
+						continue
+					}
+					acc.Function.Name += tc.Function.Name
+					acc.Function.Arguments += tc.Function.Arguments
+				}
+			}
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				finishReason = *choice.FinishReason
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	indexes := make([]int, 0, len(toolCalls))
+	for idx := range toolCalls {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	ordered := make([]*streamToolCall, 0, len(indexes))
+	for _, idx := range indexes {
+		ordered = append(ordered, toolCalls[idx])
+	}
+
+	// Re-encode as a regular completion so tool call decoding stays in one place.
+	assembled := map[string]interface{}{
+		"choices": []map[string]interface{}{{
+			"message": map[string]interface{}{
+				"content":           content.String(),
+				"reasoning_content": reasoning.String(),
+				"tool_calls":        ordered,
+			},
+			"finish_reason": finishReason,
+		}},
+		"usage": usage,
+	}
+	body, err := json.Marshal(assembled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble stream response: %w", err)
+	}
+	return parseResponse(body)
+}
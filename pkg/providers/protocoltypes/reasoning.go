@@ -0,0 +1,48 @@
+package protocoltypes
+
+// Chat option keys requesting extended reasoning. The agent sets at most one
+// of them per call; providers derive the other with ThinkingBudgetFor or
+// ReasoningEffortFor when their API wants it.
+const (
+	OptionReasoningEffort = "reasoning_effort" // "low", "medium" or "high"
+	OptionThinkingBudget  = "thinking_budget"  // reasoning tokens
+)
+
+// ReasoningOptions returns the reasoning effort and thinking budget requested
+// in a Chat options map. Both are zero when reasoning was not requested.
+func ReasoningOptions(options map[string]interface{}) (effort string, budget int) {
+	effort, _ = options[OptionReasoningEffort].(string)
+	switch v := options[OptionThinkingBudget].(type) {
+	case int:
+		budget = v
+	case float64:
+		budget = int(v)
+	}
+	return effort, budget
+}
+
+// ThinkingBudgetFor maps a reasoning effort to a thinking token budget.
+func ThinkingBudgetFor(effort string) int {
+	switch effort {
+	case "low":
+		return 2048
+	case "medium":
+		return 8192
+	case "high":
+		return 24576
+	}
+	return 0
+}
+
+// ReasoningEffortFor maps a thinking token budget to the nearest effort.
+func ReasoningEffortFor(budget int) string {
+	switch {
+	case budget <= 0:
+		return ""
+	case budget <= ThinkingBudgetFor("low"):
+		return "low"
+	case budget <= ThinkingBudgetFor("medium"):
+		return "medium"
+	}
+	return "high"
+}
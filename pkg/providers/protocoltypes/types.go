@@ -30,20 +30,62 @@ type LLMResponse struct {
 	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
 	FinishReason     string     `json:"finish_reason"`
 	Usage            *UsageInfo `json:"usage,omitempty"`
+
+	// ThinkingBlocks are the Anthropic thinking blocks behind
+	// ReasoningContent, which must be sent back unchanged with tool results.
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+}
+
+// ThinkingBlock is an Anthropic thinking or redacted_thinking block.
+type ThinkingBlock struct {
+	Type      string `json:"type"`                // "thinking" or "redacted_thinking"
+	Thinking  string `json:"thinking,omitempty"`  // for "thinking"
+	Signature string `json:"signature,omitempty"` // for "thinking"
+	Data      string `json:"data,omitempty"`      // for "redacted_thinking", encrypted
 }
 
 type UsageInfo struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	// CacheReadTokens and CacheWriteTokens are the part of PromptTokens read
+	// from or written to the provider's prompt cache, when it reports them.
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
 }
 
 type Message struct {
-	Role             string     `json:"role"`
-	Content          string     `json:"content"`
-	ReasoningContent string     `json:"reasoning_content,omitempty"`
-	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID       string     `json:"tool_call_id,omitempty"`
+	Role             string          `json:"role"`
+	Content          string          `json:"content"`
+	ContentParts     []ContentPart   `json:"content_parts,omitempty"` // multimodal content; providers send it instead of Content when set
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ThinkingBlocks   []ThinkingBlock `json:"thinking_blocks,omitempty"` // Anthropic only, resent with tool use turns
+	ToolCalls        []ToolCall      `json:"tool_calls,omitempty"`
+	ToolCallID       string          `json:"tool_call_id,omitempty"`
+}
+
+// ContentPart is one piece of a multimodal message. Each provider translates
+// parts into its own wire format.
+type ContentPart struct {
+	Type      string `json:"type"`                 // "text" or "image"
+	Text      string `json:"text,omitempty"`       // for "text"
+	MediaType string `json:"media_type,omitempty"` // for "image", e.g. "image/png"
+	Data      string `json:"data,omitempty"`       // for "image", base64-encoded bytes
+}
+
+// DataURL returns an image part as a data: URL.
+func (p ContentPart) DataURL() string {
+	return "data:" + p.MediaType + ";base64," + p.Data
+}
+
+// HasImages reports whether the message carries any image parts.
+func (m Message) HasImages() bool {
+	for _, part := range m.ContentParts {
+		if part.Type == "image" {
+			return true
+		}
+	}
+	return false
 }
 
 type ToolDefinition struct {
@@ -56,3 +98,6 @@ type ToolFunctionDefinition struct {
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters"`
 }
+
+// StreamCallback receives text deltas as a streaming response is generated.
+type StreamCallback func(delta string)
@@ -16,12 +16,29 @@ type ToolDefinition = protocoltypes.ToolDefinition
 type ToolFunctionDefinition = protocoltypes.ToolFunctionDefinition
 type ExtraContent = protocoltypes.ExtraContent
 type GoogleExtra = protocoltypes.GoogleExtra
+type StreamCallback = protocoltypes.StreamCallback
+type ContentPart = protocoltypes.ContentPart
+type ThinkingBlock = protocoltypes.ThinkingBlock
+
+// Chat option keys requesting extended reasoning, see protocoltypes.
+const (
+	OptionReasoningEffort = protocoltypes.OptionReasoningEffort
+	OptionThinkingBudget  = protocoltypes.OptionThinkingBudget
+)
 
 type LLMProvider interface {
 	Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error)
 	GetDefaultModel() string
 }
 
+// StreamingProvider is implemented by providers that can deliver text
+// incrementally. ChatStream calls onDelta for each chunk of assistant text
+// and returns the same complete response Chat would.
+type StreamingProvider interface {
+	LLMProvider
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error)
+}
+
 // FailoverReason classifies why an LLM request failed for fallback decisions.
 type FailoverReason string
 
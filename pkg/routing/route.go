@@ -24,6 +24,10 @@ type ResolvedRoute struct {
 	SessionKey     string
 	MainSessionKey string
 	MatchedBy      string // "binding.peer", "binding.peer.parent", "binding.guild", "binding.team", "binding.account", "binding.channel", "default"
+	Locale         string // locale of the matched binding, or else of the channel; may be unsupported
+	// Reset is the session reset policy of the matched binding, or else of
+	// the channel; nil when sessions are never reset.
+	Reset *config.SessionResetConfig
 }
 
 // RouteResolver determines which agent handles a message based on config bindings.
@@ -52,7 +56,13 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 
 	bindings := r.filterBindings(channel, accountID)
 
-	choose := func(agentID string, matchedBy string) ResolvedRoute {
+	// choose builds the route for a matched binding, or for the default
+	// agent when match is nil.
+	choose := func(match *config.AgentBinding, matchedBy string) ResolvedRoute {
+		agentID := r.resolveDefaultAgentID()
+		if match != nil {
+			agentID = match.AgentID
+		}
 		resolvedAgentID := r.pickAgentID(agentID)
 		sessionKey := strings.ToLower(BuildAgentPeerSessionKey(SessionKeyParams{
 			AgentID:       resolvedAgentID,
@@ -63,7 +73,7 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 			IdentityLinks: identityLinks,
 		}))
 		mainSessionKey := strings.ToLower(BuildAgentMainSessionKey(resolvedAgentID))
-		return ResolvedRoute{
+		route := ResolvedRoute{
 			AgentID:        resolvedAgentID,
 			Channel:        channel,
 			AccountID:      accountID,
@@ -71,12 +81,22 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 			MainSessionKey: mainSessionKey,
 			MatchedBy:      matchedBy,
 		}
+		if match != nil {
+			route.Locale, route.Reset = match.Locale, match.Reset
+		}
+		if route.Locale == "" {
+			route.Locale = r.cfg.Locale.For(channel)
+		}
+		if route.Reset == nil {
+			route.Reset = r.cfg.Session.ResetFor(channel)
+		}
+		return route
 	}
 
 	// Priority 1: Peer binding
 	if peer != nil && strings.TrimSpace(peer.ID) != "" {
 		if match := r.findPeerMatch(bindings, peer); match != nil {
-			return choose(match.AgentID, "binding.peer")
+			return choose(match, "binding.peer")
 		}
 	}
 
@@ -84,7 +104,7 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 	parentPeer := input.ParentPeer
 	if parentPeer != nil && strings.TrimSpace(parentPeer.ID) != "" {
 		if match := r.findPeerMatch(bindings, parentPeer); match != nil {
-			return choose(match.AgentID, "binding.peer.parent")
+			return choose(match, "binding.peer.parent")
 		}
 	}
 
@@ -92,7 +112,7 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 	guildID := strings.TrimSpace(input.GuildID)
 	if guildID != "" {
 		if match := r.findGuildMatch(bindings, guildID); match != nil {
-			return choose(match.AgentID, "binding.guild")
+			return choose(match, "binding.guild")
 		}
 	}
 
@@ -100,22 +120,22 @@ func (r *RouteResolver) ResolveRoute(input RouteInput) ResolvedRoute {
 	teamID := strings.TrimSpace(input.TeamID)
 	if teamID != "" {
 		if match := r.findTeamMatch(bindings, teamID); match != nil {
-			return choose(match.AgentID, "binding.team")
+			return choose(match, "binding.team")
 		}
 	}
 
 	// Priority 5: Account binding
 	if match := r.findAccountMatch(bindings); match != nil {
-		return choose(match.AgentID, "binding.account")
+		return choose(match, "binding.account")
 	}
 
 	// Priority 6: Channel wildcard binding
 	if match := r.findChannelWildcardMatch(bindings); match != nil {
-		return choose(match.AgentID, "binding.channel")
+		return choose(match, "binding.channel")
 	}
 
 	// Priority 7: Default agent
-	return choose(r.resolveDefaultAgentID(), "default")
+	return choose(nil, "default")
 }
 
 func (r *RouteResolver) filterBindings(channel, accountID string) []config.AgentBinding {
@@ -295,3 +295,63 @@ func TestResolveRoute_NoDefaultUsesFirst(t *testing.T) {
 		t.Errorf("AgentID = %q, want 'alpha' (first in list)", route.AgentID)
 	}
 }
+
+func TestResolveRoute_Locale(t *testing.T) {
+	agents := []config.AgentConfig{{ID: "main", Default: true}, {ID: "support"}}
+	bindings := []config.AgentBinding{
+		{
+			AgentID: "support",
+			Match:   config.BindingMatch{Channel: "telegram", Peer: &config.PeerMatch{Kind: "direct", ID: "user1"}},
+			Locale:  "en",
+		},
+	}
+	cfg := testConfig(agents, bindings)
+	cfg.Locale = config.LocaleConfig{Default: "en", Channels: map[string]string{"telegram": "zh"}}
+	r := NewRouteResolver(cfg)
+
+	tests := []struct {
+		channel string
+		peer    string
+		want    string
+	}{
+		{"telegram", "user1", "en"}, // binding beats channel
+		{"telegram", "user2", "zh"}, // channel
+		{"discord", "user2", "en"},  // default
+	}
+	for _, tt := range tests {
+		route := r.ResolveRoute(RouteInput{
+			Channel: tt.channel,
+			Peer:    &RoutePeer{Kind: "direct", ID: tt.peer},
+		})
+		if route.Locale != tt.want {
+			t.Errorf("%s/%s: Locale = %q, want %q", tt.channel, tt.peer, route.Locale, tt.want)
+		}
+	}
+}
+
+func TestResolveRoute_ResetPolicy(t *testing.T) {
+	bindings := []config.AgentBinding{
+		{
+			AgentID: "main",
+			Match:   config.BindingMatch{Channel: "telegram", Peer: &config.PeerMatch{Kind: "direct", ID: "user1"}},
+			Reset:   &config.SessionResetConfig{DailyAt: "04:00"},
+		},
+	}
+	cfg := testConfig(nil, bindings)
+	cfg.Session.Reset = &config.SessionResetConfig{IdleMinutes: 60}
+	cfg.Session.ResetChannels = map[string]config.SessionResetConfig{"telegram": {IdleMinutes: 30}}
+	r := NewRouteResolver(cfg)
+
+	resolve := func(channel, peer string) *config.SessionResetConfig {
+		return r.ResolveRoute(RouteInput{Channel: channel, Peer: &RoutePeer{Kind: "direct", ID: peer}}).Reset
+	}
+	if got := resolve("telegram", "user1"); got == nil || got.DailyAt != "04:00" {
+		t.Errorf("binding policy = %+v", got)
+	}
+	if got := resolve("telegram", "user2"); got == nil || got.IdleMinutes != 30 {
+		t.Errorf("channel policy = %+v", got)
+	}
+	if got := resolve("discord", "user2"); got == nil || got.IdleMinutes != 60 {
+		t.Errorf("default policy = %+v", got)
+	}
+}
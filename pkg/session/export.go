@@ -0,0 +1,269 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// Export formats. Each is also the file extension of its exports.
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// ExportOptions controls what an export contains.
+type ExportOptions struct {
+	// RedactToolOutput replaces tool results with a placeholder. Tool
+	// calls and their arguments are kept.
+	RedactToolOutput bool
+}
+
+// ParseExportFormat normalizes an export format name.
+func ParseExportFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "md", "markdown":
+		return FormatMarkdown, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (want md, html or json)", format)
+}
+
+// ExportFilename names the export of a session made at t.
+func ExportFilename(key, format string, t time.Time) string {
+	name := strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(key)
+	return name + "-" + t.Format("20060102-150405") + "." + format
+}
+
+// Export writes a transcript of s to w in the given format. Markdown and
+// HTML transcripts show tool calls and results as collapsible blocks.
+func Export(w io.Writer, s *Session, format string, opts ExportOptions) error {
+	format, err := ParseExportFormat(format)
+	if err != nil {
+		return err
+	}
+	if opts.RedactToolOutput {
+		s = redactToolOutput(s)
+	}
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case FormatHTML:
+		return htmlTranscript.Execute(w, newTranscript(s))
+	}
+	return writeMarkdown(w, newTranscript(s))
+}
+
+// redactedOutput stands in for a tool result in redacted exports.
+const redactedOutput = "[tool output redacted]"
+
+func redactToolOutput(s *Session) *Session {
+	redacted := snapshot(s)
+	for i, msg := range redacted.Messages {
+		if msg.Role == "tool" {
+			redacted.Messages[i].Content = redactedOutput
+			redacted.Messages[i].ContentParts = nil
+		}
+	}
+	return redacted
+}
+
+// transcript is a session prepared for rendering.
+type transcript struct {
+	Key     string
+	Created string
+	Updated string
+	Summary string
+	Entries []transcriptEntry
+}
+
+type transcriptEntry struct {
+	Role     string // user, assistant, system or tool
+	Label    string
+	Content  string
+	Images   int
+	Calls    []transcriptCall
+	ToolName string // for tool results
+}
+
+type transcriptCall struct {
+	Name string
+	Args string
+}
+
+func newTranscript(s *Session) transcript {
+	t := transcript{
+		Key:     s.Key,
+		Created: formatExportTime(s.Created),
+		Updated: formatExportTime(s.Updated),
+		Summary: s.Summary,
+	}
+	toolNames := make(map[string]string) // tool call ID -> tool name
+	for _, msg := range s.Messages {
+		entry := transcriptEntry{Role: msg.Role, Content: msg.Content}
+		for _, part := range msg.ContentParts {
+			switch part.Type {
+			case "image":
+				entry.Images++
+			case "text":
+				if entry.Content == "" {
+					entry.Content = part.Text
+				}
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			call := exportToolCall(tc)
+			toolNames[tc.ID] = call.Name
+			entry.Calls = append(entry.Calls, call)
+		}
+		switch msg.Role {
+		case "user":
+			entry.Label = "🧑 User"
+		case "assistant":
+			entry.Label = "🤖 Assistant"
+		case "system":
+			entry.Label = "⚙️ System"
+		case "tool":
+			entry.ToolName = toolNames[msg.ToolCallID]
+			if entry.ToolName == "" {
+				entry.ToolName = "tool"
+			}
+			entry.Label = "📄 Result of " + entry.ToolName
+		default:
+			entry.Label = msg.Role
+		}
+		t.Entries = append(t.Entries, entry)
+	}
+	return t
+}
+
+func exportToolCall(tc providers.ToolCall) transcriptCall {
+	name, args := tc.Name, ""
+	if tc.Arguments != nil {
+		data, _ := json.MarshalIndent(tc.Arguments, "", "  ")
+		args = string(data)
+	}
+	if tc.Function != nil {
+		if name == "" {
+			name = tc.Function.Name
+		}
+		if args == "" {
+			args = tc.Function.Arguments
+		}
+	}
+	return transcriptCall{Name: name, Args: args}
+}
+
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+func writeMarkdown(w io.Writer, t transcript) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Conversation %s\n\n", t.Key)
+	if t.Created != "" {
+		fmt.Fprintf(&sb, "- Started: %s\n", t.Created)
+	}
+	if t.Updated != "" {
+		fmt.Fprintf(&sb, "- Last activity: %s\n", t.Updated)
+	}
+	fmt.Fprintf(&sb, "- Messages: %d\n", len(t.Entries))
+
+	if t.Summary != "" {
+		fmt.Fprintf(&sb, "\n## Summary\n\n%s\n", t.Summary)
+	}
+
+	sb.WriteString("\n## Transcript\n")
+	for _, e := range t.Entries {
+		if e.Role == "tool" {
+			writeMarkdownDetails(&sb, e.Label, "", e.Content)
+			continue
+		}
+		fmt.Fprintf(&sb, "\n### %s\n", e.Label)
+		if e.Content != "" {
+			fmt.Fprintf(&sb, "\n%s\n", e.Content)
+		}
+		if e.Images > 0 {
+			fmt.Fprintf(&sb, "\n_[%d image(s)]_\n", e.Images)
+		}
+		for _, call := range e.Calls {
+			writeMarkdownDetails(&sb, "🔧 "+call.Name, "json", call.Args)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeMarkdownDetails writes a collapsible block holding a code block.
+func writeMarkdownDetails(sb *strings.Builder, summary, lang, body string) {
+	fence := markdownFence(body)
+	fmt.Fprintf(sb, "\n<details>\n<summary>%s</summary>\n\n%s%s\n%s\n%s\n\n</details>\n",
+		template.HTMLEscapeString(summary), fence, lang, body, fence)
+}
+
+// markdownFence returns a backtick fence longer than any run of backticks
+// in body.
+func markdownFence(body string) string {
+	longest, run := 0, 0
+	for _, r := range body {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conversation {{.Key}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #222; }
+.meta { color: #666; font-size: 0.9em; }
+.summary { background: #f6f8fa; border-left: 4px solid #8a9; padding: 0.5em 1em; }
+.msg { margin: 1em 0; padding: 0.6em 1em; border-radius: 6px; }
+.user { background: #eef4ff; }
+.assistant { background: #f7f7f7; }
+.system { background: #fff8e6; }
+.role { font-weight: bold; margin-bottom: 0.3em; }
+.content { white-space: pre-wrap; }
+details { margin: 0.4em 0; }
+summary { cursor: pointer; color: #555; }
+pre { background: #272822; color: #f8f8f2; padding: 0.6em; overflow-x: auto; border-radius: 4px; }
+</style>
+</head>
+<body>
+<h1>Conversation {{.Key}}</h1>
+<p class="meta">{{if .Created}}Started: {{.Created}}<br>{{end}}{{if .Updated}}Last activity: {{.Updated}}<br>{{end}}Messages: {{len .Entries}}</p>
+{{if .Summary}}<h2>Summary</h2>
+<div class="summary content">{{.Summary}}</div>
+{{end}}<h2>Transcript</h2>
+{{range .Entries}}{{if eq .Role "tool"}}<details><summary>{{.Label}}</summary><pre>{{.Content}}</pre></details>
+{{else}}<div class="msg {{.Role}}">
+<div class="role">{{.Label}}</div>
+{{if .Content}}<div class="content">{{.Content}}</div>
+{{end}}{{if .Images}}<div class="meta">[{{.Images}} image(s)]</div>
+{{end}}{{range .Calls}}<details><summary>🔧 {{.Name}}</summary><pre>{{.Args}}</pre></details>
+{{end}}</div>
+{{end}}{{end}}</body>
+</html>
+`))
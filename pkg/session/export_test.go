@@ -0,0 +1,100 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+func exportFixture() *Session {
+	return &Session{
+		Key:     "agent:main:telegram:direct:1",
+		Summary: "Talked about the weather.",
+		Created: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Messages: []providers.Message{
+			{Role: "user", Content: "List the <files>"},
+			{Role: "assistant", ToolCalls: []providers.ToolCall{
+				{ID: "call_1", Name: "exec", Arguments: map[string]interface{}{"command": "ls"}},
+			}},
+			{Role: "tool", ToolCallID: "call_1", Content: "secret.txt\n```\nnotes.md"},
+			{Role: "assistant", Content: "There are two files."},
+		},
+	}
+}
+
+func TestExport_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportFixture(), "markdown", ExportOptions{}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Conversation agent:main:telegram:direct:1",
+		"## Summary\n\nTalked about the weather.",
+		"### 🧑 User\n\nList the <files>",
+		"<summary>🔧 exec</summary>",
+		`"command": "ls"`,
+		"<summary>📄 Result of exec</summary>\n\n````\nsecret.txt",
+		"There are two files.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown export lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestExport_HTMLEscapesContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportFixture(), FormatHTML, ExportOptions{}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<files>") || !strings.Contains(out, "List the &lt;files&gt;") {
+		t.Errorf("user content not escaped:\n%s", out)
+	}
+	if !strings.Contains(out, "<details><summary>📄 Result of exec</summary>") {
+		t.Errorf("tool result not collapsible:\n%s", out)
+	}
+}
+
+func TestExport_RedactsToolOutput(t *testing.T) {
+	s := exportFixture()
+	for _, format := range []string{FormatMarkdown, FormatHTML, FormatJSON} {
+		var buf bytes.Buffer
+		if err := Export(&buf, s, format, ExportOptions{RedactToolOutput: true}); err != nil {
+			t.Fatalf("Export %s: %v", format, err)
+		}
+		if strings.Contains(buf.String(), "secret.txt") {
+			t.Errorf("%s export leaks tool output", format)
+		}
+		if !strings.Contains(buf.String(), redactedOutput) {
+			t.Errorf("%s export lacks the redaction placeholder", format)
+		}
+	}
+	if s.Messages[2].Content == redactedOutput {
+		t.Error("redaction modified the exported session")
+	}
+
+	var buf bytes.Buffer
+	Export(&buf, s, FormatJSON, ExportOptions{RedactToolOutput: true})
+	var decoded Session
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON export does not decode: %v", err)
+	}
+	if decoded.Key != s.Key || len(decoded.Messages) != 4 || decoded.Messages[1].ToolCalls[0].Name != "exec" {
+		t.Errorf("decoded JSON export = %+v", decoded)
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	if _, err := ParseExportFormat("pdf"); err == nil {
+		t.Error("expected an error for pdf")
+	}
+	if f, _ := ParseExportFormat(""); f != FormatMarkdown {
+		t.Errorf("default format = %q", f)
+	}
+}
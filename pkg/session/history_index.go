@@ -0,0 +1,472 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// IndexDir is the directory, inside a sessions directory, holding the
+// history index.
+const IndexDir = "index"
+
+const indexFile = "history.jsonl"
+
+// HistoryEntry is one indexed user or assistant message.
+type HistoryEntry struct {
+	SessionKey string    `json:"key"`
+	Channel    string    `json:"channel,omitempty"`
+	ChatID     string    `json:"chat_id,omitempty"`
+	Role       string    `json:"role"`
+	Content    string    `json:"content"`
+	Time       time.Time `json:"time,omitzero"` // when the message was added; zero when unknown
+}
+
+// indexRecord is one line of the log: an entry, or the header that rewrites
+// start the log with once it holds every stored message.
+type indexRecord struct {
+	HistoryEntry
+	Built bool `json:"built,omitempty"`
+}
+
+// HistoryHit is a search result.
+type HistoryHit struct {
+	HistoryEntry
+	Snippet string
+}
+
+// HistoryIndex is a full-text index of the user and assistant messages of
+// every saved session. Entries are only ever added, so messages dropped from
+// a session by summarization or a reset stay searchable; deleting a session
+// removes its entries. The index is an append-only log that saves append to
+// without reading it; it is only loaded into memory by the first search.
+// The first load of a log that was never built also indexes the messages
+// the stored sessions and their archives held before, undated since
+// sessions do not record when each message was added.
+type HistoryIndex struct {
+	dir   string
+	store SessionStore
+
+	mu       sync.Mutex
+	loaded   bool
+	entries  []HistoryEntry
+	postings map[string][]int // token -> indexes of the entries holding it
+}
+
+// NewHistoryIndex creates the index of the sessions stored in dir.
+func NewHistoryIndex(dir string, store SessionStore) *HistoryIndex {
+	return &HistoryIndex{dir: dir, store: store}
+}
+
+func (idx *HistoryIndex) path() string {
+	return filepath.Join(idx.dir, IndexDir, indexFile)
+}
+
+// load reads the index log, and builds it when it was never built. The
+// caller holds idx.mu.
+func (idx *HistoryIndex) load() error {
+	if idx.loaded {
+		return nil
+	}
+	idx.entries = nil
+	idx.postings = make(map[string][]int)
+
+	built := false
+	data, err := os.ReadFile(idx.path())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line, err := crypt.OpenLine(scanner.Bytes())
+		if errors.Is(err, crypt.ErrNoKey) {
+			return err
+		}
+		var rec indexRecord
+		if err != nil || json.Unmarshal(line, &rec) != nil {
+			continue // torn write
+		}
+		if rec.Built {
+			built = true
+		} else if rec.SessionKey != "" {
+			idx.insert(rec.HistoryEntry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !built {
+		if err := idx.build(); err != nil {
+			return err
+		}
+	}
+	idx.loaded = true
+	return nil
+}
+
+// build adds the messages of the archived and stored sessions missing from
+// the entries loaded from the log, and writes a new log. The caller holds
+// idx.mu.
+func (idx *HistoryIndex) build() error {
+	var sessions []*Session
+	archives, _ := filepath.Glob(filepath.Join(idx.dir, ArchiveDir, "*.json"))
+	for _, path := range archives {
+		data, err := crypt.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var s Session
+		if json.Unmarshal(data, &s) == nil && s.Key != "" {
+			sessions = append(sessions, &s)
+		}
+	}
+	if idx.store != nil {
+		keys, err := idx.store.Keys()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for _, key := range keys {
+			if s, err := idx.store.Load(key); err == nil && s != nil {
+				sessions = append(sessions, s)
+			}
+		}
+	}
+	// Oldest first, so the entries keep the order the messages were sent in.
+	slices.SortStableFunc(sessions, func(a, b *Session) int { return a.Updated.Compare(b.Updated) })
+
+	// The logged entries were added after the stored messages, so they go
+	// last.
+	logged := idx.entries
+	idx.entries = nil
+	idx.postings = make(map[string][]int)
+	indexed := make(map[[32]byte]int)
+	for _, entry := range logged {
+		indexed[entryHash(entry)]++
+	}
+	for _, s := range sessions {
+		idx.collect(s, indexed)
+	}
+	for _, entry := range logged {
+		idx.insert(entry)
+	}
+	return idx.rewrite()
+}
+
+// collect indexes the messages of a stored session, undated. A message
+// kept across a reset is in both the archive and the live session, so a
+// message is only indexed when the session holds it more times than the
+// index already does; indexed counts the entries per entryHash. The caller
+// holds idx.mu.
+func (idx *HistoryIndex) collect(s *Session, indexed map[[32]byte]int) {
+	held := make(map[[32]byte]int)
+	for _, msg := range s.Messages {
+		entry, ok := indexEntry(s.Key, msg, time.Time{})
+		if !ok {
+			continue
+		}
+		entry.Channel = s.Channel
+		entry.ChatID = s.ChatID
+		h := entryHash(entry)
+		if held[h]++; held[h] <= indexed[h] {
+			continue
+		}
+		indexed[h]++
+		idx.insert(entry)
+	}
+}
+
+// indexEntry returns the index entry of a message, or false when the
+// message is not indexed: only user and assistant text is.
+func indexEntry(key string, msg providers.Message, at time.Time) (HistoryEntry, bool) {
+	if msg.Role != "user" && msg.Role != "assistant" {
+		return HistoryEntry{}, false
+	}
+	content := strings.TrimSpace(messageText(msg))
+	if content == "" {
+		return HistoryEntry{}, false
+	}
+	return HistoryEntry{SessionKey: key, Role: msg.Role, Content: content, Time: at}, true
+}
+
+// insert adds an entry to the in-memory index. The caller holds idx.mu.
+func (idx *HistoryIndex) insert(entry HistoryEntry) {
+	n := len(idx.entries)
+	idx.entries = append(idx.entries, entry)
+	for _, token := range uniqueTokens(entry.Content) {
+		idx.postings[token] = append(idx.postings[token], n)
+	}
+}
+
+// entryHash identifies the text of a message within its session.
+func entryHash(e HistoryEntry) [32]byte {
+	return sha256.Sum256([]byte(e.SessionKey + "\x00" + e.Role + "\x00" + e.Content))
+}
+
+func messageText(msg providers.Message) string {
+	if msg.Content != "" {
+		return msg.Content
+	}
+	var parts []string
+	for _, part := range msg.ContentParts {
+		if part.Type == "text" && part.Text != "" {
+			parts = append(parts, part.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Add indexes messages newly saved in their sessions. It appends them to the
+// log without loading it.
+func (idx *HistoryIndex) Add(entries []HistoryEntry) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.loaded {
+		for _, entry := range entries {
+			idx.insert(entry)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		writeLine(&buf, line)
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path()), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(idx.path(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Remove drops the entries of a session from the index.
+func (idx *HistoryIndex) Remove(key string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.load(); err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(idx.entries), func(e HistoryEntry) bool { return e.SessionKey == key })
+	if len(kept) == len(idx.entries) {
+		return nil
+	}
+	idx.entries = nil
+	idx.postings = make(map[string][]int)
+	for _, entry := range kept {
+		idx.insert(entry)
+	}
+	return idx.rewrite()
+}
+
+// rewrite replaces the log with the entries in memory. The caller holds
+// idx.mu.
+func (idx *HistoryIndex) rewrite() error {
+	var buf bytes.Buffer
+	writeLine(&buf, []byte(`{"built":true}`))
+	for _, entry := range idx.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		writeLine(&buf, line)
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path()), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(idx.path(), buf.Bytes())
+}
+
+// Search returns up to limit entries accepted by match that contain the
+// terms of query. Entries holding the most terms win; among them, entries
+// holding the whole query come first, then those whose terms are rarest,
+// then the most recent.
+func (idx *HistoryIndex) Search(query string, match func(HistoryEntry) bool, limit int) ([]HistoryHit, error) {
+	terms := uniqueTokens(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := idx.load(); err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		entry   int
+		matched int
+		score   float64
+		phrase  bool
+	}
+	candidates := make(map[int]*candidate)
+	total := float64(len(idx.entries))
+	for _, term := range terms {
+		postings := idx.postings[term]
+		idf := math.Log(1 + total/float64(len(postings)+1))
+		for _, n := range postings {
+			c := candidates[n]
+			if c == nil {
+				if match != nil && !match(idx.entries[n]) {
+					continue
+				}
+				c = &candidate{entry: n}
+				candidates[n] = c
+			}
+			c.matched++
+			c.score += idf
+		}
+	}
+
+	best := 0
+	for _, c := range candidates {
+		best = max(best, c.matched)
+	}
+	phrase := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	var ranked []*candidate
+	for _, c := range candidates {
+		if c.matched == best {
+			c.phrase = strings.Contains(strings.ToLower(idx.entries[c.entry].Content), phrase)
+			ranked = append(ranked, c)
+		}
+	}
+	slices.SortFunc(ranked, func(a, b *candidate) int {
+		if a.phrase != b.phrase {
+			if a.phrase {
+				return -1
+			}
+			return 1
+		}
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		return b.entry - a.entry
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	hits := make([]HistoryHit, 0, len(ranked))
+	for _, c := range ranked {
+		entry := idx.entries[c.entry]
+		hits = append(hits, HistoryHit{HistoryEntry: entry, Snippet: snippet(entry.Content, phrase, terms)})
+	}
+	return hits, nil
+}
+
+// isCJK reports whether r belongs to a script written without spaces, whose
+// characters are indexed one by one.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// uniqueTokens splits text into lower-case words, and CJK text into single
+// characters, each returned once.
+func uniqueTokens(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	emit := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	var word strings.Builder
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			emit(word.String())
+			word.Reset()
+			emit(string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			emit(word.String())
+			word.Reset()
+		}
+	}
+	emit(word.String())
+	return tokens
+}
+
+// snippetRadius is how many characters of context a snippet keeps around
+// its match.
+const snippetRadius = 80
+
+// snippet cuts the part of content around the first match of phrase or,
+// failing that, of any term.
+func snippet(content, phrase string, terms []string) string {
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	at := indexRunes(lower, []rune(phrase))
+	if at < 0 {
+		for _, term := range terms {
+			if i := indexRunes(lower, []rune(term)); i >= 0 && (at < 0 || i < at) {
+				at = i
+			}
+		}
+	}
+	at = max(at, 0)
+
+	start, end := max(at-snippetRadius, 0), min(at+snippetRadius, len(runes))
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+func indexRunes(s, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if slices.Equal(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// logIndexError reports a failure to update the index without failing the
+// save that triggered it.
+func logIndexError(key string, err error) {
+	logger.WarnCF("session", "Failed to update history index",
+		map[string]interface{}{
+			"session_key": key,
+			"error":       err.Error(),
+		})
+}
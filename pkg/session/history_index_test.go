@@ -0,0 +1,155 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryIndex_KeepsMessagesDroppedFromHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewJSONStore(dir)
+	sm := NewSessionManagerWithStore(store, 0)
+	sm.SetIndex(NewHistoryIndex(dir, store))
+	key := "agent:main:telegram:direct:1"
+
+	sm.AddMessage(key, "user", "My locker code is 4821, please remember it.")
+	sm.AddMessage(key, "assistant", "Noted.")
+	sm.SetChat(key, "telegram", "1")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	sm.TruncateHistory(key, 0)
+	sm.AddMessage(key, "user", "What was the code?")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A fresh index reads the log written by the first one.
+	hits, err := NewHistoryIndex(dir, store).Search("locker code", nil, 5)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || !strings.Contains(hits[0].Snippet, "4821") || hits[0].Channel != "telegram" || hits[0].Time.IsZero() {
+		t.Fatalf("hits = %+v", hits)
+	}
+
+	// Saving again does not index the same messages twice.
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if hits, _ := sm.Index().Search("code", nil, 10); len(hits) != 2 {
+		t.Errorf("hits for 'code' = %+v, want the two distinct messages", hits)
+	}
+
+	if err := sm.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if hits, _ := NewHistoryIndex(dir, store).Search("locker", nil, 5); len(hits) != 0 {
+		t.Errorf("hits after delete = %+v", hits)
+	}
+}
+
+func TestHistoryIndex_BuildsFromSessionsAndArchives(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("telegram:1", "user", "我的护照号码是 E12345678")
+	if err := sm.Reset("telegram:1", false, ""); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	sm.AddMessage("discord:2", "user", "the meeting moved to friday")
+	if err := sm.Save("discord:2"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, IndexDir)); !os.IsNotExist(err) {
+		t.Fatal("index should not exist before it is first used")
+	}
+
+	idx := NewHistoryIndex(dir, NewJSONStore(dir))
+	hits, err := idx.Search("护照", nil, 5)
+	if err != nil || len(hits) != 1 || hits[0].SessionKey != "telegram:1" {
+		t.Fatalf("CJK search = %+v, %v", hits, err)
+	}
+	onlyDiscord := func(e HistoryEntry) bool { return strings.HasPrefix(e.SessionKey, "discord:") }
+	if hits, _ := idx.Search("meeting", onlyDiscord, 5); len(hits) != 1 {
+		t.Errorf("scoped search = %+v", hits)
+	}
+	if hits, _ := idx.Search("passport", onlyDiscord, 5); len(hits) != 0 {
+		t.Errorf("search outside scope = %+v", hits)
+	}
+}
+
+func TestHistoryIndex_DatesMessagesWhenAdded(t *testing.T) {
+	dir := t.TempDir()
+	store := NewJSONLStore(dir)
+	old := NewSessionManagerWithStore(store, 0)
+	old.AddMessage("k", "user", "my old passport number is X1")
+	if err := old.Save("k"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Messages saved before the index existed are found, but undated.
+	sm := NewSessionManagerWithStore(store, 0)
+	idx := NewHistoryIndex(dir, store)
+	sm.SetIndex(idx)
+	start := time.Now()
+	sm.AddMessage("k", "user", "ping")
+	sm.AddMessage("k", "assistant", "pong")
+	if err := sm.Save("k"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	sm.AddMessage("k", "user", "ping")
+	if err := sm.Save("k"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if idx.loaded {
+		t.Fatal("saving should not load the index")
+	}
+
+	hits, err := idx.Search("ping", nil, 5)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("hits = %+v, %v, want both pings", hits, err)
+	}
+	for _, hit := range hits {
+		if hit.Time.Before(start) {
+			t.Errorf("hit dated %v, want when it was added", hit.Time)
+		}
+	}
+	hits, _ = idx.Search("passport", nil, 5)
+	if len(hits) != 1 || !hits[0].Time.IsZero() {
+		t.Errorf("hits = %+v, want one undated message", hits)
+	}
+}
+
+func TestHistoryIndex_RanksPhraseMatchesFirst(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManagerWithStore(NewJSONStore(dir), 0)
+	sm.SetIndex(NewHistoryIndex(dir, nil))
+	sm.AddMessage("k", "user", "the blue car is parked at home")
+	sm.AddMessage("k", "user", "my car is blue and old")
+	sm.AddMessage("k", "user", "the sky is blue")
+	if err := sm.Save("k"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	hits, err := sm.Index().Search("Blue Car", nil, 5)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 2 || !strings.Contains(hits[0].Content, "blue car") {
+		t.Errorf("hits = %+v, want both car messages with the phrase match first", hits)
+	}
+}
+
+func TestSnippet_CutsAroundMatch(t *testing.T) {
+	content := strings.Repeat("filler ", 50) + "the answer is 42" + strings.Repeat(" filler", 50)
+	got := snippet(content, "answer", []string{"answer"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "answer is 42") {
+		t.Errorf("snippet = %q", got)
+	}
+	if got := snippet("short text", "missing", []string{"missing"}); got != "short text" {
+		t.Errorf("snippet without match = %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// SessionInfo describes a session without its messages.
+type SessionInfo struct {
+	Key        string
+	Channel    string // channel of the last chat served, if known
+	ChatID     string
+	Messages   int
+	HasSummary bool
+	Created    time.Time
+	Updated    time.Time
+	Size       int64 // bytes on disk, 0 when not saved
+}
+
+func describe(s *Session) SessionInfo {
+	channel := s.Channel
+	if channel == "" {
+		channel = channelFromKey(s.Key)
+	}
+	return SessionInfo{
+		Key:        s.Key,
+		Channel:    channel,
+		ChatID:     s.ChatID,
+		Messages:   len(s.Messages),
+		HasSummary: s.Summary != "",
+		Created:    s.Created,
+		Updated:    s.Updated,
+	}
+}
+
+// channelFromKey guesses the channel of a session saved before sessions
+// recorded it, from keys such as "agent:main:telegram:group:42" or
+// "telegram:42". It returns "" when the key names no channel.
+func channelFromKey(key string) string {
+	parts := strings.Split(key, ":")
+	if len(parts) > 2 && parts[0] == "agent" {
+		parts = parts[2:]
+	} else if len(parts) < 2 {
+		return ""
+	}
+	switch parts[0] {
+	case "main", "direct", "subagent":
+		return ""
+	}
+	return parts[0]
+}
+
+// List describes every session in memory or in the store, most recently
+// active first. Stored sessions are read without being kept in memory.
+func (sm *SessionManager) List() ([]SessionInfo, error) {
+	sm.mu.Lock()
+	infos := make(map[string]SessionInfo, len(sm.sessions))
+	for key, elem := range sm.sessions {
+		infos[key] = describe(elem.Value.(*Session))
+	}
+	for key, evicted := range sm.evicting {
+		infos[key] = describe(evicted.session)
+	}
+	sm.mu.Unlock()
+
+	if sm.store != nil {
+		keys, err := sm.store.Keys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			info, ok := infos[key]
+			if !ok {
+				stored, err := sm.store.Load(key)
+				if err != nil || stored == nil {
+					continue
+				}
+				info = describe(stored)
+			}
+			info.Size, _ = sm.store.Size(key)
+			infos[key] = info
+		}
+	}
+
+	list := make([]SessionInfo, 0, len(infos))
+	for _, info := range infos {
+		list = append(list, info)
+	}
+	slices.SortFunc(list, func(a, b SessionInfo) int {
+		if c := b.Updated.Compare(a.Updated); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return list, nil
+}
+
+// ListFilter selects sessions by channel and last activity. Zero fields
+// match every session.
+type ListFilter struct {
+	Channel   string
+	OlderThan time.Duration // inactive for at least this long
+	NewerThan time.Duration // active within this long
+}
+
+// Match reports whether info passes the filter at time now.
+func (f ListFilter) Match(info SessionInfo, now time.Time) bool {
+	if f.Channel != "" && !strings.EqualFold(info.Channel, f.Channel) {
+		return false
+	}
+	idle := now.Sub(info.Updated)
+	if f.OlderThan > 0 && idle < f.OlderThan {
+		return false
+	}
+	if f.NewerThan > 0 && idle > f.NewerThan {
+		return false
+	}
+	return true
+}
+
+// ParseAge parses a duration that may also be given in days, such as "30d",
+// "12h" or "90m".
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		d, err := time.ParseDuration(days + "h")
+		return d * 24, err
+	}
+	return time.ParseDuration(s)
+}
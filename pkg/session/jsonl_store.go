@@ -0,0 +1,349 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// compactAfter is how many superseded metadata records a log may collect
+// before it is rewritten.
+const compactAfter = 64
+
+// jsonlRecord is one line of a session log: either the session metadata or
+// one message. Metadata records replace earlier ones; messages accumulate.
+type jsonlRecord struct {
+	Meta    json.RawMessage    `json:"meta,omitempty"`
+	Message *providers.Message `json:"message,omitempty"`
+}
+
+// jsonlMeta encodes a session without its messages.
+type jsonlMeta struct {
+	*Session
+	Messages []providers.Message `json:"messages,omitempty"` // shadows Session.Messages
+}
+
+// jsonlLog describes what a session log on disk holds, so a save can tell
+// whether it only needs to append.
+type jsonlLog struct {
+	messages int      // messages in the log
+	last     [32]byte // hash of the last of them
+	meta     []byte   // latest metadata record
+	records  int      // lines in the log
+}
+
+// JSONLStore keeps each session as an append-only log. Saves append the new
+// messages and, when it changed, the metadata; a history that was rewritten
+// (cleared, truncated, summarized or rewound) and a log that has collected
+// too many superseded records are compacted into a fresh file. Sessions
+// stored by JSONStore in the same directory are read and migrated on their
+// next save.
+type JSONLStore struct {
+	dir  string
+	mu   sync.Mutex
+	logs map[string]*jsonlLog
+}
+
+// NewJSONLStore creates a JSONL store in dir.
+func NewJSONLStore(dir string) *JSONLStore {
+	os.MkdirAll(dir, 0755)
+	return &JSONLStore{dir: dir, logs: make(map[string]*jsonlLog)}
+}
+
+func (s *JSONLStore) Load(key string) (*Session, error) {
+	path, err := sessionPath(s.dir, key, ".jsonl")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		legacy, _ := sessionPath(s.dir, key, ".json")
+		return readJSONSession(legacy, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	session := &Session{Messages: []providers.Message{}}
+	log := &jsonlLog{}
+	torn := false
+	var sealErr error // first line that failed to decrypt
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line, err := crypt.OpenLine(scanner.Bytes())
+		if errors.Is(err, crypt.ErrNoKey) {
+			return nil, fmt.Errorf("session %s: %w", filepath.Base(path), err)
+		}
+		if err != nil || sealErr != nil {
+			// Only the last line may be torn; a line that does not
+			// decrypt before others do means a wrong key or corruption.
+			if sealErr != nil {
+				return nil, fmt.Errorf("session %s: %w", filepath.Base(path), sealErr)
+			}
+			sealErr = err
+			continue
+		}
+		if len(line) == 0 {
+			continue
+		}
+		var rec jsonlRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// A partial last line from an interrupted write; the next save
+			// compacts it away.
+			torn = true
+			continue
+		}
+		log.records++
+		switch {
+		case rec.Message != nil:
+			session.Messages = append(session.Messages, *rec.Message)
+			log.last = sha256.Sum256(line)
+		case rec.Meta != nil:
+			var meta Session
+			if err := json.Unmarshal(rec.Meta, &jsonlMeta{Session: &meta}); err != nil {
+				torn = true
+				continue
+			}
+			meta.Messages = session.Messages
+			*session = meta
+			log.meta = rec.Meta
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("session %s: %w", filepath.Base(path), err)
+	}
+	if sealErr != nil {
+		if log.records == 0 {
+			return nil, fmt.Errorf("session %s: %w", filepath.Base(path), sealErr)
+		}
+		torn = true
+	}
+	if session.Key != key {
+		return nil, nil
+	}
+
+	log.messages = len(session.Messages)
+	if torn {
+		delete(s.logs, key)
+	} else {
+		s.logs[key] = log
+	}
+	return session, nil
+}
+
+func (s *JSONLStore) Save(session *Session) error {
+	path, err := sessionPath(s.dir, session.Key, ".jsonl")
+	if err != nil {
+		return err
+	}
+	meta, err := json.Marshal(jsonlMeta{Session: session})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.logs[session.Key]
+	if log == nil || !log.extendedBy(session.Messages) || log.records-log.messages > compactAfter {
+		return s.compact(path, session, meta)
+	}
+
+	var buf bytes.Buffer
+	next := *log
+	for _, msg := range session.Messages[log.messages:] {
+		line, err := json.Marshal(jsonlRecord{Message: &msg})
+		if err != nil {
+			return err
+		}
+		writeLine(&buf, line)
+		next.messages++
+		next.records++
+		next.last = sha256.Sum256(line)
+	}
+	if !bytes.Equal(meta, log.meta) {
+		line, _ := json.Marshal(jsonlRecord{Meta: meta})
+		writeLine(&buf, line)
+		next.meta = meta
+		next.records++
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		delete(s.logs, session.Key)
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// The log may now end in a partial record; rewrite it next time.
+		delete(s.logs, session.Key)
+		return err
+	}
+	s.logs[session.Key] = &next
+	return nil
+}
+
+// writeLine adds a record to buf, encrypted when encryption is on.
+func writeLine(buf *bytes.Buffer, line []byte) {
+	buf.Write(crypt.SealLine(line))
+	buf.WriteByte('\n')
+}
+
+// extendedBy reports whether messages start with the messages in the log.
+// Only the count and the last logged message are compared: rewriting history
+// always shortens it or replaces its tail.
+func (l *jsonlLog) extendedBy(messages []providers.Message) bool {
+	if len(messages) < l.messages {
+		return false
+	}
+	if l.messages == 0 {
+		return true
+	}
+	line, err := json.Marshal(jsonlRecord{Message: &messages[l.messages-1]})
+	return err == nil && sha256.Sum256(line) == l.last
+}
+
+// compact replaces the log of a session with its current state. The caller
+// holds s.mu.
+func (s *JSONLStore) compact(path string, session *Session, meta []byte) error {
+	log := &jsonlLog{meta: meta}
+	var buf bytes.Buffer
+	line, _ := json.Marshal(jsonlRecord{Meta: meta})
+	writeLine(&buf, line)
+	log.records++
+	for _, msg := range session.Messages {
+		line, err := json.Marshal(jsonlRecord{Message: &msg})
+		if err != nil {
+			return err
+		}
+		writeLine(&buf, line)
+		log.messages++
+		log.records++
+		log.last = sha256.Sum256(line)
+	}
+
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		delete(s.logs, session.Key)
+		return err
+	}
+	s.logs[session.Key] = log
+
+	// The session now lives in the log; drop its JSONStore copy.
+	if legacy, err := sessionPath(s.dir, session.Key, ".json"); err == nil {
+		os.Remove(legacy)
+	}
+	return nil
+}
+
+func (s *JSONLStore) Size(key string) (int64, error) {
+	path, err := sessionPath(s.dir, key, ".jsonl")
+	if err != nil {
+		return 0, err
+	}
+	legacy, _ := sessionPath(s.dir, key, ".json")
+	return fileSize(path, legacy)
+}
+
+func (s *JSONLStore) Archive(session *Session, at time.Time) error {
+	return archiveSession(s.dir, session, at)
+}
+
+func (s *JSONLStore) Delete(key string) error {
+	path, err := sessionPath(s.dir, key, ".jsonl")
+	if err != nil {
+		return err
+	}
+	legacy, _ := sessionPath(s.dir, key, ".json")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, key)
+	for _, p := range []string{path, legacy} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONLStore) Keys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(key string) {
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	legacy := NewJSONStore(s.dir)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+		add(readJSONLKey(filepath.Join(s.dir, entry.Name())))
+	}
+	legacyKeys, err := legacy.Keys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range legacyKeys {
+		add(key)
+	}
+	return keys, nil
+}
+
+// readJSONLKey returns the session key from the first record of a log,
+// which compaction always writes as metadata.
+func readJSONLKey(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return ""
+	}
+	line, err = crypt.OpenLine(bytes.TrimSuffix(line, []byte("\n")))
+	if err != nil {
+		return ""
+	}
+	var rec jsonlRecord
+	if json.Unmarshal(line, &rec) != nil || rec.Meta == nil {
+		return ""
+	}
+	var head struct {
+		Key string `json:"key"`
+	}
+	json.Unmarshal(rec.Meta, &head)
+	return head.Key
+}
@@ -1,64 +1,251 @@
 package session
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
-	"strings"
+	"container/list"
 	"sync"
 	"time"
 
+	"github.com/zhaopengme/mobaiclaw/pkg/logger"
 	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 )
 
 type Session struct {
-	Key      string              `json:"key"`
-	Messages []providers.Message `json:"messages"`
-	Summary  string              `json:"summary,omitempty"`
-	Created  time.Time           `json:"created"`
-	Updated  time.Time           `json:"updated"`
+	Key        string              `json:"key"`
+	Messages   []providers.Message `json:"messages"`
+	Summary    string              `json:"summary,omitempty"`
+	Model      string              `json:"model,omitempty"`      // per-chat model_list override
+	Thinking   string              `json:"thinking,omitempty"`   // per-chat reasoning effort override
+	Unfinished bool                `json:"unfinished,omitempty"` // last turn stopped at the iteration limit
+	Channel    string              `json:"channel,omitempty"`    // channel of the last chat served
+	ChatID     string              `json:"chat_id,omitempty"`    // ID of the last chat served
+	Created    time.Time           `json:"created"`
+	Updated    time.Time           `json:"updated"`
+
+	// Attachments marks that the last user message came with media files,
+	// which are removed once its turn is done.
+	Attachments bool `json:"attachments,omitempty"`
 }
 
+// SessionManager keeps sessions in memory and persists them to a
+// SessionStore. Sessions are loaded from the store when first used, and when
+// more than maxCached are in memory the least recently used are saved and
+// dropped from memory.
 type SessionManager struct {
-	sessions map[string]*Session
-	mu       sync.RWMutex
-	storage  string
+	sessions  map[string]*list.Element // key -> element of recent holding *Session
+	recent    *list.List               // most recently used first
+	mu        sync.Mutex
+	store     SessionStore // nil keeps sessions in memory only
+	maxCached int          // 0 for no limit
+	index     *HistoryIndex
+
+	// unindexed holds, per session, the messages added since it was last
+	// saved, dated when they were added, until they go in the index.
+	unindexed map[string][]HistoryEntry
+
+	// evicting holds the sessions dropped from memory whose last changes are
+	// still to be saved. They are saved once sm.mu is released, and a lookup
+	// in the meantime takes them back instead of reading a stale file.
+	evicting map[string]*eviction
+	unsaved  []string   // keys of evicted sessions nobody is saving yet
+	saveMu   sync.Mutex // orders the snapshots and writes of saves
 }
 
+// NewSessionManager creates a manager storing sessions as JSON files in
+// storage, or in memory only when storage is empty.
 func NewSessionManager(storage string) *SessionManager {
-	sm := &SessionManager{
-		sessions: make(map[string]*Session),
-		storage:  storage,
+	if storage == "" {
+		return NewSessionManagerWithStore(nil, 0)
 	}
+	return NewSessionManagerWithStore(NewJSONStore(storage), 0)
+}
 
-	if storage != "" {
-		os.MkdirAll(storage, 0755)
-		sm.loadSessions()
+// NewSessionManagerWithStore creates a manager persisting sessions to store
+// that keeps at most maxCached sessions in memory (0 for no limit).
+func NewSessionManagerWithStore(store SessionStore, maxCached int) *SessionManager {
+	return &SessionManager{
+		sessions:  make(map[string]*list.Element),
+		recent:    list.New(),
+		store:     store,
+		maxCached: maxCached,
+		unindexed: make(map[string][]HistoryEntry),
+		evicting:  make(map[string]*eviction),
 	}
+}
 
-	return sm
+// SetIndex makes the manager add the messages of every session it saves to
+// idx, and remove deleted sessions from it.
+func (sm *SessionManager) SetIndex(idx *HistoryIndex) {
+	sm.index = idx
 }
 
-func (sm *SessionManager) GetOrCreate(key string) *Session {
+// Index returns the history index of the manager, or nil when it has none.
+func (sm *SessionManager) Index() *HistoryIndex {
+	return sm.index
+}
+
+// eviction is a session dropped from memory that has yet to be saved. Each
+// eviction of a session is a new one, so a save can tell whether the session
+// was taken back, changed and evicted again while it was writing.
+type eviction struct {
+	session *Session
+}
+
+// unlock releases sm.mu, then saves the sessions evicted while it was held.
+func (sm *SessionManager) unlock() {
+	keys := sm.unsaved
+	sm.unsaved = nil
+	sm.mu.Unlock()
+
+	for _, key := range keys {
+		sm.saveEvicted(key)
+	}
+}
+
+// saveEvicted saves a session evicted from memory. When the save fails the
+// session goes back in memory rather than lose unsaved messages.
+func (sm *SessionManager) saveEvicted(key string) {
+	sm.saveMu.Lock()
+	defer sm.saveMu.Unlock()
+
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
+	evicted, ok := sm.evicting[key]
+	if !ok {
+		// Already saved, or taken back by a lookup
+		sm.mu.Unlock()
+		return
+	}
+	snap := snapshot(evicted.session)
+	entries := sm.takeUnindexed(snap)
+	sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
-	if ok {
-		return session
+	err := sm.store.Save(snap)
+
+	sm.mu.Lock()
+	if sm.evicting[key] == evicted {
+		delete(sm.evicting, key)
+		if err != nil {
+			sm.sessions[key] = sm.recent.PushBack(evicted.session)
+		}
+	}
+	sm.mu.Unlock()
+
+	if err != nil {
+		sm.requeueUnindexed(key, entries)
+		logger.WarnCF("session", "Failed to save evicted session",
+			map[string]interface{}{
+				"session_key": key,
+				"error":       err.Error(),
+			})
+		return
+	}
+	sm.addToIndex(key, entries)
+}
+
+// takeUnindexed removes and returns the messages of a session waiting for
+// the index, attributed to the chat the session serves. The caller holds
+// sm.mu.
+func (sm *SessionManager) takeUnindexed(s *Session) []HistoryEntry {
+	entries := sm.unindexed[s.Key]
+	delete(sm.unindexed, s.Key)
+	for i := range entries {
+		entries[i].Channel = s.Channel
+		entries[i].ChatID = s.ChatID
+	}
+	return entries
+}
+
+// requeueUnindexed puts back the messages of a session that failed to save,
+// ahead of those added since.
+func (sm *SessionManager) requeueUnindexed(key string, entries []HistoryEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	sm.mu.Lock()
+	sm.unindexed[key] = append(entries, sm.unindexed[key]...)
+	sm.mu.Unlock()
+}
+
+func (sm *SessionManager) addToIndex(key string, entries []HistoryEntry) {
+	if sm.index == nil || len(entries) == 0 {
+		return
+	}
+	if err := sm.index.Add(entries); err != nil {
+		logIndexError(key, err)
+	}
+}
+
+// lookup returns the session for key, loading it from the store when it is
+// not in memory. The caller holds sm.mu.
+func (sm *SessionManager) lookup(key string) (*Session, bool) {
+	if elem, ok := sm.sessions[key]; ok {
+		sm.recent.MoveToFront(elem)
+		return elem.Value.(*Session), true
+	}
+	if evicted, ok := sm.evicting[key]; ok {
+		delete(sm.evicting, key)
+		sm.add(evicted.session)
+		return evicted.session, true
 	}
+	if sm.store == nil {
+		return nil, false
+	}
+	session, err := sm.store.Load(key)
+	if err != nil {
+		logger.WarnCF("session", "Failed to load session",
+			map[string]interface{}{
+				"session_key": key,
+				"error":       err.Error(),
+			})
+		return nil, false
+	}
+	if session == nil {
+		return nil, false
+	}
+	sm.add(session)
+	return session, true
+}
 
-	session = &Session{
+// lookupOrCreate returns the session for key, creating an empty one when
+// none exists. The caller holds sm.mu.
+func (sm *SessionManager) lookupOrCreate(key string) *Session {
+	if session, ok := sm.lookup(key); ok {
+		return session
+	}
+	session := &Session{
 		Key:      key,
 		Messages: []providers.Message{},
 		Created:  time.Now(),
 		Updated:  time.Now(),
 	}
-	sm.sessions[key] = session
-
+	sm.add(session)
 	return session
 }
 
+// add puts a session in memory and evicts the least recently used ones
+// beyond maxCached. Evicted sessions are saved by unlock. The caller holds
+// sm.mu and releases it with unlock.
+func (sm *SessionManager) add(session *Session) {
+	sm.sessions[session.Key] = sm.recent.PushFront(session)
+	if sm.store == nil || sm.maxCached <= 0 {
+		return
+	}
+	for sm.recent.Len() > sm.maxCached {
+		elem := sm.recent.Back()
+		evicted := elem.Value.(*Session)
+		sm.recent.Remove(elem)
+		delete(sm.sessions, evicted.Key)
+		sm.evicting[evicted.Key] = &eviction{session: evicted}
+		sm.unsaved = append(sm.unsaved, evicted.Key)
+	}
+}
+
+func (sm *SessionManager) GetOrCreate(key string) *Session {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	return sm.lookupOrCreate(key)
+}
+
 func (sm *SessionManager) AddMessage(sessionKey, role, content string) {
 	sm.AddFullMessage(sessionKey, providers.Message{
 		Role:    role,
@@ -70,27 +257,23 @@ func (sm *SessionManager) AddMessage(sessionKey, role, content string) {
 // This is used to save the full conversation flow including tool calls and tool results.
 func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Message) {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	session, ok := sm.sessions[sessionKey]
-	if !ok {
-		session = &Session{
-			Key:      sessionKey,
-			Messages: []providers.Message{},
-			Created:  time.Now(),
-		}
-		sm.sessions[sessionKey] = session
-	}
+	defer sm.unlock()
 
+	session := sm.lookupOrCreate(sessionKey)
 	session.Messages = append(session.Messages, msg)
 	session.Updated = time.Now()
+	if sm.index != nil {
+		if entry, ok := indexEntry(sessionKey, msg, session.Updated); ok {
+			sm.unindexed[sessionKey] = append(sm.unindexed[sessionKey], entry)
+		}
+	}
 }
 
 func (sm *SessionManager) GetHistory(key string) []providers.Message {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return []providers.Message{}
 	}
@@ -101,10 +284,10 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 }
 
 func (sm *SessionManager) GetSummary(key string) string {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return ""
 	}
@@ -113,20 +296,121 @@ func (sm *SessionManager) GetSummary(key string) string {
 
 func (sm *SessionManager) SetSummary(key string, summary string) {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
+	defer sm.unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		session.Summary = summary
 		session.Updated = time.Now()
 	}
 }
 
+// GetModel returns the model override of a session, or "" when it uses the
+// agent's model.
+func (sm *SessionManager) GetModel(key string) string {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		return ""
+	}
+	return session.Model
+}
+
+// SetModel sets the model override of a session, creating the session if
+// needed. An empty model clears the override.
+func (sm *SessionManager) SetModel(key string, model string) {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session := sm.lookupOrCreate(key)
+	session.Model = model
+	session.Updated = time.Now()
+}
+
+// GetThinking returns the reasoning effort override of a session, or "" when
+// it uses the agent's setting.
+func (sm *SessionManager) GetThinking(key string) string {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		return ""
+	}
+	return session.Thinking
+}
+
+// SetThinking sets the reasoning effort override of a session, creating the
+// session if needed. An empty effort clears the override.
+func (sm *SessionManager) SetThinking(key string, effort string) {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session := sm.lookupOrCreate(key)
+	session.Thinking = effort
+	session.Updated = time.Now()
+}
+
+// SetChat records the chat a session last served, creating the session if
+// needed.
+func (sm *SessionManager) SetChat(key, channel, chatID string) {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session := sm.lookupOrCreate(key)
+	session.Channel = channel
+	session.ChatID = chatID
+}
+
+// IsUnfinished reports whether the last turn of a session stopped at the
+// iteration limit before completing its task.
+func (sm *SessionManager) IsUnfinished(key string) bool {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session, ok := sm.lookup(key)
+	return ok && session.Unfinished
+}
+
+// SetUnfinished marks whether the last turn of an existing session stopped
+// at the iteration limit.
+func (sm *SessionManager) SetUnfinished(key string, unfinished bool) {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	if session, ok := sm.lookup(key); ok {
+		session.Unfinished = unfinished
+	}
+}
+
+// HasAttachments reports whether the last user message of a session came
+// with media files.
+func (sm *SessionManager) HasAttachments(key string) bool {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	session, ok := sm.lookup(key)
+	return ok && session.Attachments
+}
+
+// SetAttachments marks whether the last user message of an existing session
+// came with media files.
+func (sm *SessionManager) SetAttachments(key string, attachments bool) {
+	sm.mu.Lock()
+	defer sm.unlock()
+
+	if session, ok := sm.lookup(key); ok {
+		session.Attachments = attachments
+	}
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
+	defer sm.unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return
 	}
@@ -145,132 +429,95 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	session.Updated = time.Now()
 }
 
-// sanitizeFilename converts a session key into a cross-platform safe filename.
-// Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
-// volume separator on Windows, so filepath.Base would misinterpret the key.
-// We replace it with '_'. The original key is preserved inside the JSON file,
-// so loadSessions still maps back to the right in-memory key.
-func sanitizeFilename(key string) string {
-	return strings.ReplaceAll(key, ":", "_")
-}
+// RewindTurns removes the last n user turns from a session: each of the last
+// n user messages together with every assistant and tool message after it.
+// Turns are never split, so assistant tool calls always stay paired with
+// their tool results. It returns the removed user messages, oldest first.
+func (sm *SessionManager) RewindTurns(key string, n int) []providers.Message {
+	sm.mu.Lock()
+	defer sm.unlock()
 
-func (sm *SessionManager) Save(key string) error {
-	if sm.storage == "" {
+	session, ok := sm.lookup(key)
+	if !ok || n <= 0 {
 		return nil
 	}
 
-	filename := sanitizeFilename(key)
-
-	// filepath.IsLocal rejects empty names, "..", absolute paths, and
-	// OS-reserved device names (NUL, COM1 … on Windows).
-	// The extra checks reject "." and any directory separators so that
-	// the session file is always written directly inside sm.storage.
-	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
-		return os.ErrInvalid
+	cut := -1
+	var removed []providers.Message
+	for i := len(session.Messages) - 1; i >= 0 && len(removed) < n; i-- {
+		if session.Messages[i].Role == "user" {
+			cut = i
+			removed = append([]providers.Message{session.Messages[i]}, removed...)
+		}
 	}
-
-	// Snapshot under read lock, then perform slow file I/O after unlock.
-	sm.mu.RLock()
-	stored, ok := sm.sessions[key]
-	if !ok {
-		sm.mu.RUnlock()
+	if cut < 0 {
 		return nil
 	}
 
-	snapshot := Session{
-		Key:     stored.Key,
-		Summary: stored.Summary,
-		Created: stored.Created,
-		Updated: stored.Updated,
-	}
-	if len(stored.Messages) > 0 {
-		snapshot.Messages = make([]providers.Message, len(stored.Messages))
-		copy(snapshot.Messages, stored.Messages)
-	} else {
-		snapshot.Messages = []providers.Message{}
-	}
-	sm.mu.RUnlock()
-
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return err
-	}
+	session.Messages = session.Messages[:cut:cut]
+	session.Attachments = false
+	session.Updated = time.Now()
+	return removed
+}
 
-	sessionPath := filepath.Join(sm.storage, filename+".json")
-	tmpFile, err := os.CreateTemp(sm.storage, "session-*.tmp")
-	if err != nil {
-		return err
+// Save persists a session to the store. Sessions that are not in memory
+// are already saved, or being saved after their eviction.
+func (sm *SessionManager) Save(key string) error {
+	if sm.store == nil {
+		return nil
 	}
+	sm.saveMu.Lock()
+	defer sm.saveMu.Unlock()
 
-	tmpPath := tmpFile.Name()
-	cleanup := true
-	defer func() {
-		if cleanup {
-			_ = os.Remove(tmpPath)
-		}
-	}()
-
-	if _, err := tmpFile.Write(data); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Chmod(0644); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Sync(); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Close(); err != nil {
-		return err
+	// Snapshot under lock, then perform slow file I/O after unlock.
+	sm.mu.Lock()
+	elem, ok := sm.sessions[key]
+	if !ok {
+		sm.mu.Unlock()
+		return nil
 	}
+	snap := snapshot(elem.Value.(*Session))
+	entries := sm.takeUnindexed(snap)
+	sm.mu.Unlock()
 
-	if err := os.Rename(tmpPath, sessionPath); err != nil {
+	if err := sm.store.Save(snap); err != nil {
+		sm.requeueUnindexed(key, entries)
 		return err
 	}
-	cleanup = false
+	sm.addToIndex(key, entries)
 	return nil
 }
 
-func (sm *SessionManager) loadSessions() error {
-	files, err := os.ReadDir(sm.storage)
-	if err != nil {
-		return err
-	}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		if filepath.Ext(file.Name()) != ".json" {
-			continue
-		}
-
-		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
-		if err != nil {
-			continue
-		}
-
-		var session Session
-		if err := json.Unmarshal(data, &session); err != nil {
-			continue
-		}
+// Snapshot returns a copy of a session, or nil when it does not exist.
+func (sm *SessionManager) Snapshot(key string) *Session {
+	sm.mu.Lock()
+	defer sm.unlock()
 
-		sm.sessions[session.Key] = &session
+	session, ok := sm.lookup(key)
+	if !ok {
+		return nil
 	}
+	return snapshot(session)
+}
 
-	return nil
+// snapshot copies a session so it can be written without holding the lock.
+func snapshot(stored *Session) *Session {
+	snap := *stored
+	if len(stored.Messages) > 0 {
+		snap.Messages = make([]providers.Message, len(stored.Messages))
+		copy(snap.Messages, stored.Messages)
+	} else {
+		snap.Messages = []providers.Message{}
+	}
+	return &snap
 }
 
 // SetHistory updates the messages of a session.
 func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
+	defer sm.unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		// Create a deep copy to strictly isolate internal state
 		// from the caller's slice.
@@ -280,3 +527,62 @@ func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 		session.Updated = time.Now()
 	}
 }
+
+// Reset archives a session and starts it afresh. The summary is kept when
+// carrySummary is set, and a non-empty note starts the new history as a
+// system message. Per-chat overrides such as the model are kept. Resetting a
+// session that does not exist does nothing.
+func (sm *SessionManager) Reset(key string, carrySummary bool, note string) error {
+	sm.mu.Lock()
+	session, ok := sm.lookup(key)
+	if !ok {
+		sm.unlock()
+		return nil
+	}
+	old := snapshot(session)
+	now := time.Now()
+	if !carrySummary {
+		session.Summary = ""
+	}
+	session.Messages = []providers.Message{}
+	if note != "" {
+		session.Messages = append(session.Messages, providers.Message{Role: "system", Content: note})
+	}
+	session.Unfinished = false
+	session.Attachments = false
+	session.Created = now
+	session.Updated = now
+	sm.unlock()
+
+	if sm.store == nil {
+		return nil
+	}
+	if err := sm.store.Archive(old, now); err != nil {
+		return err
+	}
+	return sm.Save(key)
+}
+
+// Delete removes a session from memory and disk. Deleting a session that
+// does not exist is not an error.
+func (sm *SessionManager) Delete(key string) error {
+	sm.mu.Lock()
+	if elem, ok := sm.sessions[key]; ok {
+		sm.recent.Remove(elem)
+		delete(sm.sessions, key)
+	}
+	delete(sm.evicting, key)
+	delete(sm.unindexed, key)
+	sm.mu.Unlock()
+
+	if sm.store == nil {
+		return nil
+	}
+	if err := sm.store.Delete(key); err != nil {
+		return err
+	}
+	if sm.index != nil {
+		return sm.index.Remove(key)
+	}
+	return nil
+}
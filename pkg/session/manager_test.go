@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -72,3 +75,182 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestRewindTurns_RemovesWholeTurns(t *testing.T) {
+	sm := NewSessionManager("")
+	key := "telegram:1"
+	sm.GetOrCreate(key)
+	sm.AddMessage(key, "user", "first")
+	sm.AddMessage(key, "assistant", "answer 1")
+	sm.AddMessage(key, "user", "second")
+	sm.AddFullMessage(key, providers.Message{
+		Role:      "assistant",
+		ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "exec"}},
+	})
+	sm.AddFullMessage(key, providers.Message{Role: "tool", Content: "ok", ToolCallID: "call_1"})
+	sm.AddMessage(key, "assistant", "answer 2")
+
+	removed := sm.RewindTurns(key, 1)
+	if len(removed) != 1 || removed[0].Content != "second" {
+		t.Fatalf("removed = %+v, want the second user message", removed)
+	}
+	history := sm.GetHistory(key)
+	if len(history) != 2 || history[1].Content != "answer 1" {
+		t.Fatalf("history after rewind = %+v", history)
+	}
+
+	sm.AddMessage(key, "user", "third")
+	removed = sm.RewindTurns(key, 5)
+	if len(removed) != 2 || removed[0].Content != "first" || removed[1].Content != "third" {
+		t.Fatalf("removed = %+v, want first and third", removed)
+	}
+	if history := sm.GetHistory(key); len(history) != 0 {
+		t.Fatalf("expected empty history, got %+v", history)
+	}
+
+	if removed := sm.RewindTurns(key, 1); removed != nil {
+		t.Errorf("rewinding an empty session removed %+v", removed)
+	}
+}
+
+func TestChatOverrides_PersistAcrossReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	sm.SetModel("telegram:1", "cheap")
+	sm.SetThinking("telegram:1", "high")
+	if err := sm.Save("telegram:1"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reloaded := NewSessionManager(tmpDir)
+	if got := reloaded.GetModel("telegram:1"); got != "cheap" {
+		t.Errorf("GetModel after reload = %q, want %q", got, "cheap")
+	}
+	if got := reloaded.GetThinking("telegram:1"); got != "high" {
+		t.Errorf("GetThinking after reload = %q, want %q", got, "high")
+	}
+
+	reloaded.SetModel("telegram:1", "")
+	if got := reloaded.GetModel("telegram:1"); got != "" {
+		t.Errorf("GetModel after clearing = %q, want empty", got)
+	}
+}
+
+func TestDelete_RemovesSessionFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	sm.AddMessage("agent:main:eval", "user", "hi")
+	if err := sm.Save("agent:main:eval"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := sm.Delete("agent:main:eval"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := sm.Delete("agent:main:eval"); err != nil {
+		t.Errorf("deleting a missing session: %v", err)
+	}
+
+	if got := NewSessionManager(tmpDir).GetHistory("agent:main:eval"); len(got) != 0 {
+		t.Errorf("deleted session reloaded with %d messages", len(got))
+	}
+}
+
+func TestReset_ArchivesAndStartsAfresh(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	key := "telegram:1"
+	sm.AddMessage(key, "user", "old question")
+	sm.SetSummary(key, "old summary")
+	sm.SetModel(key, "cheap")
+
+	if err := sm.Reset(key, false, "new conversation"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	history := sm.GetHistory(key)
+	if len(history) != 1 || history[0].Role != "system" || history[0].Content != "new conversation" {
+		t.Errorf("history after reset = %+v", history)
+	}
+	if got := sm.GetSummary(key); got != "" {
+		t.Errorf("summary after reset = %q, want it dropped", got)
+	}
+	if got := sm.GetModel(key); got != "cheap" {
+		t.Errorf("model override after reset = %q, want it kept", got)
+	}
+
+	archived, _ := filepath.Glob(filepath.Join(tmpDir, ArchiveDir, "telegram_1-*.json"))
+	if len(archived) != 1 {
+		t.Fatalf("archives = %v, want one", archived)
+	}
+	if got := NewSessionManager(tmpDir).GetHistory(key); len(got) != 1 {
+		t.Errorf("reset session not saved: %+v", got)
+	}
+}
+
+func TestList_DescribesStoredAndCachedSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	old := NewSessionManager(tmpDir)
+	old.AddMessage("agent:main:telegram:direct:1", "user", "hi")
+	old.SetSummary("agent:main:telegram:direct:1", "greetings")
+	if err := old.Save("agent:main:telegram:direct:1"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("agent:main:main", "user", "one")
+	sm.AddMessage("agent:main:main", "assistant", "two")
+	sm.SetChat("agent:main:main", "cli", "direct")
+
+	infos, err := sm.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("List = %+v, want two sessions", infos)
+	}
+	// Most recently active first.
+	cached, stored := infos[0], infos[1]
+	if cached.Key != "agent:main:main" || cached.Messages != 2 || cached.Channel != "cli" || cached.Size != 0 {
+		t.Errorf("cached session = %+v", cached)
+	}
+	if stored.Messages != 1 || !stored.HasSummary || stored.Channel != "telegram" || stored.Size == 0 {
+		t.Errorf("stored session = %+v", stored)
+	}
+
+	sm.mu.Lock()
+	_, loaded := sm.sessions[stored.Key]
+	sm.mu.Unlock()
+	if loaded {
+		t.Error("List should not keep stored sessions in memory")
+	}
+}
+
+func TestListFilter_Match(t *testing.T) {
+	now := time.Now()
+	info := SessionInfo{Key: "telegram:1", Channel: "telegram", Updated: now.Add(-48 * time.Hour)}
+	tests := []struct {
+		filter ListFilter
+		want   bool
+	}{
+		{ListFilter{}, true},
+		{ListFilter{Channel: "Telegram"}, true},
+		{ListFilter{Channel: "discord"}, false},
+		{ListFilter{OlderThan: 24 * time.Hour}, true},
+		{ListFilter{OlderThan: 72 * time.Hour}, false},
+		{ListFilter{NewerThan: 24 * time.Hour}, false},
+		{ListFilter{NewerThan: 72 * time.Hour, Channel: "telegram"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(info, now); got != tt.want {
+			t.Errorf("%+v.Match = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	if d, err := ParseAge("7d"); err != nil || d != 7*24*time.Hour {
+		t.Errorf("ParseAge(7d) = %v, %v", d, err)
+	}
+	if _, err := ParseAge("soon"); err == nil {
+		t.Error("ParseAge should reject malformed ages")
+	}
+}
@@ -0,0 +1,254 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+// Session store backends.
+const (
+	BackendJSON  = "json"  // one JSON document per session, rewritten on save
+	BackendJSONL = "jsonl" // append-only log per session, compacted periodically
+)
+
+// SessionStore persists sessions. Implementations are safe for concurrent use.
+type SessionStore interface {
+	// Load returns the stored session for key, or nil when there is none.
+	Load(key string) (*Session, error)
+	// Save persists a snapshot of a session. The store keeps no reference
+	// to it.
+	Save(s *Session) error
+	// Delete removes a stored session. Deleting a session that does not
+	// exist is not an error.
+	Delete(key string) error
+	// Keys lists the keys of all stored sessions.
+	Keys() ([]string, error)
+	// Size returns the bytes a stored session takes on disk, or 0 when it
+	// is not stored.
+	Size(key string) (int64, error)
+	// Archive keeps a copy of a session, as it was at the given time, apart
+	// from the live sessions. The live session is not changed.
+	Archive(s *Session, at time.Time) error
+}
+
+// ArchiveDir is the directory, inside a store's directory, holding archived
+// sessions as JSON documents.
+const ArchiveDir = "archive"
+
+// NewStore creates a store of the given backend in dir. An empty backend
+// selects BackendJSON.
+func NewStore(backend, dir string) (SessionStore, error) {
+	switch backend {
+	case "", BackendJSON:
+		return NewJSONStore(dir), nil
+	case BackendJSONL:
+		return NewJSONLStore(dir), nil
+	}
+	return nil, fmt.Errorf("unknown session backend %q (want %q or %q)", backend, BackendJSON, BackendJSONL)
+}
+
+// sanitizeFilename converts a session key into a cross-platform safe filename.
+// Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
+// volume separator on Windows, so filepath.Base would misinterpret the key.
+// We replace it with '_'. The original key is preserved inside the file, so
+// stores still map back to the right key.
+func sanitizeFilename(key string) string {
+	return strings.ReplaceAll(key, ":", "_")
+}
+
+// sessionPath returns the file of key with the given extension in dir.
+func sessionPath(dir, key, ext string) (string, error) {
+	filename := sanitizeFilename(key)
+
+	// filepath.IsLocal rejects empty names, "..", absolute paths, and
+	// OS-reserved device names (NUL, COM1 … on Windows).
+	// The extra checks reject "." and any directory separators so that
+	// the session file is always written directly inside dir.
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return "", os.ErrInvalid
+	}
+	return filepath.Join(dir, filename+ext), nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file
+// readable only by its owner.
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "session-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(0600); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
+
+// archiveSession writes an archived copy of s into the archive of dir.
+func archiveSession(dir string, s *Session, at time.Time) error {
+	archive := filepath.Join(dir, ArchiveDir)
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(archive, ExportFilename(s.Key, FormatJSON, at)), crypt.Seal(data))
+}
+
+// fileSize returns the size of the first of paths that exists.
+func fileSize(paths ...string) (int64, error) {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+	return 0, nil
+}
+
+// readJSONSession reads a session stored as a single JSON document. It
+// returns nil when the file does not exist or belongs to another key.
+func readJSONSession(path, key string) (*Session, error) {
+	data, err := crypt.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", filepath.Base(path), err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("session %s: %w", filepath.Base(path), err)
+	}
+	if session.Key != key {
+		return nil, nil
+	}
+	if session.Messages == nil {
+		session.Messages = []providers.Message{}
+	}
+	return &session, nil
+}
+
+// JSONStore keeps each session as one JSON document, rewritten in full on
+// every save.
+type JSONStore struct {
+	dir string
+	mu  sync.Mutex // serializes writes
+}
+
+// NewJSONStore creates a JSON store in dir.
+func NewJSONStore(dir string) *JSONStore {
+	os.MkdirAll(dir, 0755)
+	return &JSONStore{dir: dir}
+}
+
+func (s *JSONStore) Load(key string) (*Session, error) {
+	path, err := sessionPath(s.dir, key, ".json")
+	if err != nil {
+		return nil, err
+	}
+	return readJSONSession(path, key)
+}
+
+func (s *JSONStore) Save(session *Session) error {
+	path, err := sessionPath(s.dir, session.Key, ".json")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFileAtomic(path, crypt.Seal(data))
+}
+
+func (s *JSONStore) Delete(key string) error {
+	path, err := sessionPath(s.dir, key, ".json")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *JSONStore) Keys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := crypt.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var head struct {
+			Key string `json:"key"`
+		}
+		if json.Unmarshal(data, &head) == nil && head.Key != "" {
+			keys = append(keys, head.Key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *JSONStore) Archive(session *Session, at time.Time) error {
+	return archiveSession(s.dir, session, at)
+}
+
+func (s *JSONStore) Size(key string) (int64, error) {
+	path, err := sessionPath(s.dir, key, ".json")
+	if err != nil {
+		return 0, err
+	}
+	return fileSize(path)
+}
@@ -0,0 +1,315 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/crypt"
+	"github.com/zhaopengme/mobaiclaw/pkg/providers"
+)
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestJSONLStore_AppendsAndRewrites(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManagerWithStore(NewJSONLStore(dir), 0)
+	key := "telegram:1"
+	path := filepath.Join(dir, "telegram_1.jsonl")
+
+	sm.AddMessage(key, "user", "first")
+	sm.AddMessage(key, "assistant", "answer 1")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := countLines(t, path); got != 3 {
+		t.Fatalf("log has %d lines, want meta + 2 messages", got)
+	}
+
+	sm.AddMessage(key, "user", "second")
+	sm.SetSummary(key, "greetings")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := countLines(t, path); got != 5 {
+		t.Fatalf("log has %d lines after append, want 5", got)
+	}
+
+	// Rewriting history compacts the log.
+	sm.RewindTurns(key, 1)
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := countLines(t, path); got != 3 {
+		t.Fatalf("log has %d lines after rewind, want 3", got)
+	}
+
+	reloaded := NewSessionManagerWithStore(NewJSONLStore(dir), 0)
+	history := reloaded.GetHistory(key)
+	if len(history) != 2 || history[1].Content != "answer 1" {
+		t.Fatalf("history after reload = %+v", history)
+	}
+	if got := reloaded.GetSummary(key); got != "greetings" {
+		t.Errorf("summary after reload = %q", got)
+	}
+
+	// Appending after a reload continues the same log.
+	reloaded.AddMessage(key, "user", "third")
+	if err := reloaded.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := countLines(t, path); got != 5 {
+		t.Errorf("log has %d lines after appending to a reloaded session, want 5", got)
+	}
+}
+
+func TestJSONLStore_CompactsSupersededMetadata(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManagerWithStore(NewJSONLStore(dir), 0)
+	key := "telegram:1"
+	sm.AddMessage(key, "user", "hi")
+
+	for i := 0; i < compactAfter+5; i++ {
+		sm.SetModel(key, []string{"a", "b"}[i%2])
+		if err := sm.Save(key); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	if got := countLines(t, filepath.Join(dir, "telegram_1.jsonl")); got > compactAfter+2 {
+		t.Errorf("log has %d lines, want it compacted", got)
+	}
+	if got := NewSessionManagerWithStore(NewJSONLStore(dir), 0).GetHistory(key); len(got) != 1 {
+		t.Errorf("history after compaction = %+v", got)
+	}
+}
+
+func TestJSONLStore_RecoversFromTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManagerWithStore(NewJSONLStore(dir), 0)
+	key := "telegram:1"
+	sm.AddMessage(key, "user", "hi")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	path := filepath.Join(dir, "telegram_1.jsonl")
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"message":{"role":"assis`)
+	f.Close()
+
+	reloaded := NewSessionManagerWithStore(NewJSONLStore(dir), 0)
+	reloaded.AddMessage(key, "assistant", "hello")
+	if err := reloaded.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	history := NewSessionManagerWithStore(NewJSONLStore(dir), 0).GetHistory(key)
+	if len(history) != 2 || history[1].Content != "hello" {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestJSONLStore_MigratesJSONSessions(t *testing.T) {
+	dir := t.TempDir()
+	old := NewSessionManager(dir)
+	old.AddMessage("telegram:1", "user", "from json")
+	if err := old.Save("telegram:1"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	store := NewJSONLStore(dir)
+	keys, err := store.Keys()
+	if err != nil || !slices.Equal(keys, []string{"telegram:1"}) {
+		t.Fatalf("Keys = %v, %v", keys, err)
+	}
+
+	sm := NewSessionManagerWithStore(store, 0)
+	if got := sm.GetHistory("telegram:1"); len(got) != 1 || got[0].Content != "from json" {
+		t.Fatalf("history = %+v", got)
+	}
+	sm.AddMessage("telegram:1", "assistant", "now jsonl")
+	if err := sm.Save("telegram:1"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "telegram_1.json")); !os.IsNotExist(err) {
+		t.Error("JSON file should be removed after migration")
+	}
+	if got := NewSessionManagerWithStore(NewJSONLStore(dir), 0).GetHistory("telegram:1"); len(got) != 2 {
+		t.Errorf("history after migration = %+v", got)
+	}
+}
+
+func TestSessionManager_EvictsLeastRecentlyUsed(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendJSONL} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := NewStore(backend, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sm := NewSessionManagerWithStore(store, 2)
+
+			sm.AddMessage("a", "user", "in a")
+			sm.AddMessage("b", "user", "in b")
+			sm.GetHistory("a") // b is now least recently used
+			sm.AddMessage("c", "user", "in c")
+
+			sm.mu.Lock()
+			_, aCached := sm.sessions["a"]
+			_, bCached := sm.sessions["b"]
+			cached := len(sm.sessions)
+			sm.mu.Unlock()
+			if cached != 2 || !aCached || bCached {
+				t.Fatalf("cached %d sessions (a: %v, b: %v), want a and c", cached, aCached, bCached)
+			}
+
+			// The evicted session was saved and loads again on demand.
+			if got := sm.GetHistory("b"); len(got) != 1 || got[0].Content != "in b" {
+				t.Errorf("history of evicted session = %+v", got)
+			}
+
+			keys, err := store.Keys()
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, []string{"a", "b"}) {
+				t.Errorf("stored keys = %v, want a and b", keys)
+			}
+		})
+	}
+}
+
+// gatedStore holds saves of one session until released, or fails them.
+type gatedStore struct {
+	SessionStore
+	key     string
+	started chan struct{}
+	release chan struct{}
+	fail    bool
+}
+
+func (s *gatedStore) Save(session *Session) error {
+	if session.Key == s.key {
+		if s.fail {
+			return errors.New("disk full")
+		}
+		close(s.started)
+		<-s.release
+	}
+	return s.SessionStore.Save(session)
+}
+
+func TestSessionManager_SavesEvictedSessionsWithoutLock(t *testing.T) {
+	store := &gatedStore{
+		SessionStore: NewJSONLStore(t.TempDir()),
+		key:          "a",
+		started:      make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+	sm := NewSessionManagerWithStore(store, 1)
+	sm.AddMessage("a", "user", "in a")
+
+	done := make(chan struct{})
+	go func() {
+		sm.AddMessage("b", "user", "in b") // evicts a
+		close(done)
+	}()
+	<-store.started
+
+	// While a is being written, other sessions stay usable.
+	got := make(chan []providers.Message)
+	go func() { got <- sm.GetHistory("b") }()
+	select {
+	case history := <-got:
+		if len(history) != 1 || history[0].Content != "in b" {
+			t.Errorf("history of b = %+v", history)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetHistory blocked on the save of an evicted session")
+	}
+
+	close(store.release)
+	<-done
+	if loaded, err := store.Load("a"); err != nil || loaded == nil || len(loaded.Messages) != 1 {
+		t.Errorf("evicted session on disk = %+v, %v", loaded, err)
+	}
+}
+
+func TestSessionManager_KeepsSessionWhoseEvictionSaveFails(t *testing.T) {
+	store := &gatedStore{SessionStore: NewJSONLStore(t.TempDir()), key: "a", fail: true}
+	sm := NewSessionManagerWithStore(store, 1)
+	sm.AddMessage("a", "user", "in a")
+	sm.AddMessage("b", "user", "in b")
+
+	if got := sm.GetHistory("a"); len(got) != 1 || got[0].Content != "in a" {
+		t.Errorf("history of a = %+v, want its unsaved message kept", got)
+	}
+}
+
+func TestNewStore_UnknownBackend(t *testing.T) {
+	if _, err := NewStore("sqlite", t.TempDir()); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestStores_EncryptAtRest(t *testing.T) {
+	if err := crypt.Enable(bytes.Repeat([]byte{3}, crypt.KeySize)); err != nil {
+		t.Fatal(err)
+	}
+	defer crypt.Enable(nil)
+
+	for _, backend := range []string{BackendJSON, BackendJSONL} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			store, _ := NewStore(backend, dir)
+			sm := NewSessionManagerWithStore(store, 0)
+			sm.SetIndex(NewHistoryIndex(dir, store))
+			sm.AddMessage("telegram:1", "user", "my pin is 2468")
+			if err := sm.Save("telegram:1"); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			sm.AddMessage("telegram:1", "assistant", "noted")
+			if err := sm.Save("telegram:1"); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if err := sm.Reset("telegram:1", false, ""); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+			top, _ := filepath.Glob(filepath.Join(dir, "*.*"))
+			for _, path := range append(files, top...) {
+				data, _ := os.ReadFile(path)
+				if bytes.Contains(data, []byte("2468")) || bytes.Contains(data, []byte("telegram")) {
+					t.Errorf("%s holds plaintext: %q", filepath.Base(path), data)
+				}
+			}
+
+			keys, err := NewStore(backend, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := keys.Keys(); !slices.Equal(got, []string{"telegram:1"}) {
+				t.Errorf("Keys = %v", got)
+			}
+			if hits, err := NewHistoryIndex(dir, store).Search("pin", nil, 5); err != nil || len(hits) != 1 {
+				t.Errorf("Search = %+v, %v", hits, err)
+			}
+
+			crypt.Enable(nil)
+			defer crypt.Enable(bytes.Repeat([]byte{3}, crypt.KeySize))
+			if _, err := keys.Load("telegram:1"); !errors.Is(err, crypt.ErrNoKey) {
+				t.Errorf("Load without a key = %v, want ErrNoKey", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+)
+
+// ApprovalPolicy decides which tool calls must be approved by the user
+// before they run.
+type ApprovalPolicy struct {
+	workspace string
+	rules     []approvalRule
+}
+
+type approvalRule struct {
+	tool         string
+	args         map[string]*regexp.Regexp // nil pattern: invalid, matches any value
+	allowedPaths []string
+}
+
+// NewApprovalPolicy builds the policy for an agent workspace. It returns nil
+// when approvals are disabled. An invalid argument pattern matches every value,
+// so a broken rule still asks for approval rather than letting calls through.
+func NewApprovalPolicy(cfg config.ApprovalConfig, workspace string) *ApprovalPolicy {
+	if !cfg.Enabled || len(cfg.Rules) == 0 {
+		return nil
+	}
+
+	p := &ApprovalPolicy{workspace: workspace}
+	for _, rule := range cfg.Rules {
+		r := approvalRule{tool: rule.Tool, args: make(map[string]*regexp.Regexp)}
+		for name, pattern := range rule.Args {
+			re, _ := regexp.Compile(pattern)
+			r.args[name] = re
+		}
+		for _, dir := range rule.AllowedPaths {
+			r.allowedPaths = append(r.allowedPaths, p.absolute(dir))
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+// RequiresApproval reports whether a call of the named tool with args needs approval.
+func (p *ApprovalPolicy) RequiresApproval(name string, args map[string]interface{}) bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.rules {
+		if rule.tool == name && p.matches(rule, args) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ApprovalPolicy) matches(rule approvalRule, args map[string]interface{}) bool {
+	for name, re := range rule.args {
+		value, ok := args[name]
+		if !ok {
+			return false
+		}
+		if re != nil && !re.MatchString(fmt.Sprint(value)) {
+			return false
+		}
+	}
+
+	if len(rule.allowedPaths) > 0 {
+		path, _ := args["path"].(string)
+		if path == "" {
+			return true
+		}
+		resolved := p.resolve(path)
+		for _, dir := range rule.allowedPaths {
+			if isWithinWorkspace(resolved, dir) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// absolute makes path absolute, relative to the workspace, following
+// symlinks when it exists.
+func (p *ApprovalPolicy) absolute(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.workspace, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return filepath.Clean(path)
+}
+
+// resolve is like absolute, but for paths that do not exist yet it returns
+// the nearest existing ancestor, so a symlink inside an allowed directory
+// cannot point out of it.
+func (p *ApprovalPolicy) resolve(path string) string {
+	resolved := p.absolute(path)
+	if _, err := os.Lstat(resolved); err == nil {
+		return resolved
+	}
+	if ancestor, err := resolveExistingAncestor(filepath.Dir(resolved)); err == nil {
+		return ancestor
+	}
+	return resolved
+}
@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/config"
+)
+
+func TestApprovalPolicy(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(workspace, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := NewApprovalPolicy(config.ApprovalConfig{
+		Enabled: true,
+		Rules: []config.ApprovalRule{
+			{Tool: "exec"},
+			{Tool: "write_file", AllowedPaths: []string{"."}},
+			{Tool: "i2c", Args: map[string]string{"action": "^write$"}},
+			{Tool: "broken", Args: map[string]string{"x": "("}},
+		},
+	}, workspace)
+
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		want bool
+	}{
+		{"exec always", "exec", map[string]interface{}{"command": "ls"}, true},
+		{"write inside workspace", "write_file", map[string]interface{}{"path": "notes/a.txt"}, false},
+		{"write outside workspace", "write_file", map[string]interface{}{"path": filepath.Join(outside, "a.txt")}, true},
+		{"write through symlink", "write_file", map[string]interface{}{"path": "escape/a.txt"}, true},
+		{"write with traversal", "write_file", map[string]interface{}{"path": "../a.txt"}, true},
+		{"i2c write", "i2c", map[string]interface{}{"action": "write"}, true},
+		{"i2c read", "i2c", map[string]interface{}{"action": "read"}, false},
+		{"invalid pattern fails closed", "broken", map[string]interface{}{"x": "anything"}, true},
+		{"unlisted tool", "read_file", map[string]interface{}{"path": "/etc/passwd"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.RequiresApproval(tt.tool, tt.args); got != tt.want {
+				t.Errorf("RequiresApproval(%s, %v) = %v, want %v", tt.tool, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicy_Disabled(t *testing.T) {
+	policy := NewApprovalPolicy(config.ApprovalConfig{Rules: []config.ApprovalRule{{Tool: "exec"}}}, t.TempDir())
+	if policy != nil {
+		t.Fatal("expected nil policy when approvals are disabled")
+	}
+	if policy.RequiresApproval("exec", nil) {
+		t.Error("nil policy must not require approval")
+	}
+}
@@ -11,7 +11,9 @@ type Tool interface {
 }
 
 // ContextualTool is an optional interface that tools can implement
-// to receive the current message context (channel, chatID, sessionKey)
+// to receive a default message context (channel, chatID, sessionKey).
+// During agent turns the per-call context is carried by ctx (see TurnContextFrom)
+// and takes precedence over the values set here.
 type ContextualTool interface {
 	Tool
 	SetContext(channel, chatID, sessionKey string)
@@ -69,6 +71,14 @@ type AsyncTool interface {
 	SetCallback(cb AsyncCallback)
 }
 
+// SequentialTool is an optional interface for tools that must not run
+// concurrently with other tool calls of the same iteration, for example
+// because they mutate files, run shell commands or drive hardware.
+type SequentialTool interface {
+	Tool
+	Sequential() bool
+}
+
 // ProgressCallback is a function type that tools can use to report real-time progress.
 type ProgressCallback func(content string)
 
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// TurnContext carries the state of a single agent turn (one inbound message
+// being processed). It travels through context.Context so that tools shared
+// by an agent can serve several sessions concurrently without mutating
+// their own fields.
+type TurnContext struct {
+	Channel    string
+	ChatID     string
+	SessionKey string
+
+	messageSent atomic.Bool
+}
+
+// NewTurnContext creates a TurnContext for the given origin.
+func NewTurnContext(channel, chatID, sessionKey string) *TurnContext {
+	return &TurnContext{
+		Channel:    channel,
+		ChatID:     chatID,
+		SessionKey: sessionKey,
+	}
+}
+
+// MarkMessageSent records that the message tool delivered a message to the user in this turn.
+func (tc *TurnContext) MarkMessageSent() {
+	tc.messageSent.Store(true)
+}
+
+// MessageSent returns true if the message tool delivered a message in this turn.
+func (tc *TurnContext) MessageSent() bool {
+	return tc.messageSent.Load()
+}
+
+type turnContextKey struct{}
+
+type callbacksKey struct{}
+
+type toolCallbacks struct {
+	async    AsyncCallback
+	progress ProgressCallback
+}
+
+// WithTurnContext returns a copy of ctx carrying tc.
+func WithTurnContext(ctx context.Context, tc *TurnContext) context.Context {
+	return context.WithValue(ctx, turnContextKey{}, tc)
+}
+
+// TurnContextFrom returns the TurnContext carried by ctx, if any.
+func TurnContextFrom(ctx context.Context) (*TurnContext, bool) {
+	tc, ok := ctx.Value(turnContextKey{}).(*TurnContext)
+	return tc, ok && tc != nil
+}
+
+// withCallbacks attaches per-call async and progress callbacks to ctx.
+func withCallbacks(ctx context.Context, async AsyncCallback, progress ProgressCallback) context.Context {
+	if async == nil && progress == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, callbacksKey{}, toolCallbacks{async: async, progress: progress})
+}
+
+// asyncCallbackFrom returns the async callback for the current call, or fallback if none was provided.
+func asyncCallbackFrom(ctx context.Context, fallback AsyncCallback) AsyncCallback {
+	if cbs, ok := ctx.Value(callbacksKey{}).(toolCallbacks); ok && cbs.async != nil {
+		return cbs.async
+	}
+	return fallback
+}
+
+// progressCallbackFrom returns the progress callback for the current call, or fallback if none was provided.
+func progressCallbackFrom(ctx context.Context, fallback ProgressCallback) ProgressCallback {
+	if cbs, ok := ctx.Value(callbacksKey{}).(toolCallbacks); ok && cbs.progress != nil {
+		return cbs.progress
+	}
+	return fallback
+}
+
+// originFrom resolves the channel/chatID/sessionKey for a tool call, preferring
+// the turn context over the defaults configured through SetContext.
+func originFrom(ctx context.Context, channel, chatID, sessionKey string) (string, string, string) {
+	if tc, ok := TurnContextFrom(ctx); ok && tc.Channel != "" && tc.ChatID != "" {
+		return tc.Channel, tc.ChatID, tc.SessionKey
+	}
+	return channel, chatID, sessionKey
+}
@@ -26,6 +26,11 @@ func (t *EditFileTool) Name() string {
 	return "edit_file"
 }
 
+// Sequential keeps edits to the same file in call order.
+func (t *EditFileTool) Sequential() bool {
+	return true
+}
+
 func (t *EditFileTool) Description() string {
 	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
 }
@@ -114,6 +119,11 @@ func (t *AppendFileTool) Name() string {
 	return "append_file"
 }
 
+// Sequential keeps appends to the same file in call order.
+func (t *AppendFileTool) Sequential() bool {
+	return true
+}
+
 func (t *AppendFileTool) Description() string {
 	return "Append content to the end of a file"
 }
@@ -139,6 +139,11 @@ func (t *WriteFileTool) Name() string {
 	return "write_file"
 }
 
+// Sequential keeps writes to the same file in call order.
+func (t *WriteFileTool) Sequential() bool {
+	return true
+}
+
 func (t *WriteFileTool) Description() string {
 	return "Write content to a file"
 }
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/session"
+)
+
+// HistorySearchTool searches past conversations, including messages that
+// summarization has dropped from the session history.
+type HistorySearchTool struct {
+	index    *session.HistoryIndex
+	allChats bool // search the sessions of every chat, not only the current one
+}
+
+// NewHistorySearchTool creates a history search tool over index. Unless
+// allChats is set, searches only see the sessions of the chat the turn
+// belongs to.
+func NewHistorySearchTool(index *session.HistoryIndex, allChats bool) *HistorySearchTool {
+	return &HistorySearchTool{index: index, allChats: allChats}
+}
+
+func (t *HistorySearchTool) Name() string {
+	return "history_search"
+}
+
+func (t *HistorySearchTool) Description() string {
+	return "Full-text search over past conversations with the user, including messages no longer in your context. " +
+		"Use this to recall exact details from earlier chats (numbers, names, dates, decisions). " +
+		"Returns dated snippets; cite the date when you rely on one."
+}
+
+func (t *HistorySearchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Words to search for (e.g., 'passport number', 'flight tuesday')",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of results to return (1-20, default 5)",
+				"minimum":     1.0,
+				"maximum":     20.0,
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *HistorySearchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	query, ok := args["query"].(string)
+	query = strings.TrimSpace(query)
+	if !ok || query == "" {
+		return ErrorResult("query is required and must be a non-empty string")
+	}
+
+	limit := 5
+	if l, ok := args["limit"].(float64); ok {
+		li := int(l)
+		if li >= 1 && li <= 20 {
+			limit = li
+		}
+	}
+
+	var match func(session.HistoryEntry) bool
+	if !t.allChats {
+		channel, chatID, sessionKey := originFrom(ctx, "", "", "")
+		if channel == "" && sessionKey == "" {
+			return ErrorResult("history_search is only available in a conversation")
+		}
+		match = func(e session.HistoryEntry) bool {
+			return (sessionKey != "" && e.SessionKey == sessionKey) ||
+				(channel != "" && e.Channel == channel && e.ChatID == chatID)
+		}
+	}
+
+	hits, err := t.index.Search(query, match, limit)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("history search failed: %v", err))
+	}
+	return SilentResult(formatHistoryHits(query, hits, t.allChats))
+}
+
+func formatHistoryHits(query string, hits []session.HistoryHit, withSession bool) string {
+	if len(hits) == 0 {
+		return fmt.Sprintf("No past messages found for %q", query)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Found %d past messages for %q:\n", len(hits), query))
+	for i, hit := range hits {
+		date := "undated"
+		if !hit.Time.IsZero() {
+			date = hit.Time.Local().Format("2006-01-02 15:04")
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. [%s] %s", i+1, date, hit.Role))
+		if withSession {
+			sb.WriteString(fmt.Sprintf(" in %s", hit.SessionKey))
+		}
+		sb.WriteString(": " + hit.Snippet + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zhaopengme/mobaiclaw/pkg/session"
+)
+
+func TestHistorySearchTool_ScopesToCurrentChat(t *testing.T) {
+	dir := t.TempDir()
+	store := session.NewJSONStore(dir)
+	sm := session.NewSessionManagerWithStore(store, 0)
+	sm.SetIndex(session.NewHistoryIndex(dir, store))
+	for _, chat := range []string{"1", "2"} {
+		key := "agent:main:telegram:direct:" + chat
+		sm.AddMessage(key, "user", "my wifi password is secret-"+chat)
+		sm.SetChat(key, "telegram", chat)
+		if err := sm.Save(key); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	tool := NewHistorySearchTool(sm.Index(), false)
+	ctx := WithTurnContext(context.Background(), NewTurnContext("telegram", "1", "agent:main:telegram:direct:1"))
+	result := tool.Execute(ctx, map[string]interface{}{"query": "wifi password"})
+	if result.IsError || !strings.Contains(result.ForLLM, "secret-1") || strings.Contains(result.ForLLM, "secret-2") {
+		t.Fatalf("result = %+v", result)
+	}
+	if !strings.Contains(result.ForLLM, "] user: ") {
+		t.Errorf("hits should be dated and attributed: %s", result.ForLLM)
+	}
+
+	if result := tool.Execute(context.Background(), map[string]interface{}{"query": "wifi"}); !result.IsError {
+		t.Errorf("search without a conversation = %+v", result)
+	}
+
+	all := NewHistorySearchTool(sm.Index(), true)
+	result = all.Execute(ctx, map[string]interface{}{"query": "wifi", "limit": 10.0})
+	if !strings.Contains(result.ForLLM, "secret-2") || !strings.Contains(result.ForLLM, "in agent:main:telegram:direct:2") {
+		t.Errorf("all-chats result = %s", result.ForLLM)
+	}
+}
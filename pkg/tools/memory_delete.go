@@ -19,6 +19,11 @@ func (t *MemoryDeleteTool) Name() string {
 	return "memory_delete"
 }
 
+// Sequential keeps memory updates in call order.
+func (t *MemoryDeleteTool) Sequential() bool {
+	return true
+}
+
 func (t *MemoryDeleteTool) Description() string {
 	return "Delete a specific fact or preference from the core profile."
 }
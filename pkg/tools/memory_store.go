@@ -25,6 +25,11 @@ func (t *MemoryStoreTool) Name() string {
 	return "memory_store"
 }
 
+// Sequential keeps memory updates in call order.
+func (t *MemoryStoreTool) Sequential() bool {
+	return true
+}
+
 func (t *MemoryStoreTool) Description() string {
 	return "Store a permanent fact or user preference in the core profile. Use this to remember long-term information."
 }
@@ -22,6 +22,11 @@ func (t *MessageTool) Name() string {
 	return "message"
 }
 
+// Sequential keeps messages in the order the model sent them.
+func (t *MessageTool) Sequential() bool {
+	return true
+}
+
 func (t *MessageTool) Description() string {
 	return "Send a message to user on a chat channel. Use this when you want to communicate something."
 }
@@ -54,6 +59,8 @@ func (t *MessageTool) SetContext(channel, chatID, sessionKey string) {
 }
 
 // HasSentInRound returns true if the message tool sent a message during the current round.
+// It only tracks calls made without a TurnContext; concurrent turns should use
+// TurnContext.MessageSent instead.
 func (t *MessageTool) HasSentInRound() bool {
 	return t.sentInRound
 }
@@ -71,11 +78,12 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 	channel, _ := args["channel"].(string)
 	chatID, _ := args["chat_id"].(string)
 
+	defaultChannel, defaultChatID, _ := originFrom(ctx, t.defaultChannel, t.defaultChatID, "")
 	if channel == "" {
-		channel = t.defaultChannel
+		channel = defaultChannel
 	}
 	if chatID == "" {
-		chatID = t.defaultChatID
+		chatID = defaultChatID
 	}
 
 	if channel == "" || chatID == "" {
@@ -94,7 +102,11 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	}
 
-	t.sentInRound = true
+	if tc, ok := TurnContextFrom(ctx); ok {
+		tc.MarkMessageSent()
+	} else {
+		t.sentInRound = true
+	}
 	// Silent: user already received the message directly
 	return &ToolResult{
 		ForLLM: fmt.Sprintf("Message sent to %s:%s", channel, chatID),
@@ -257,3 +257,32 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected chat_id type to be 'string'")
 	}
 }
+
+func TestMessageTool_Execute_UsesTurnContext(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("default-channel", "default-chat", "")
+
+	var sentChannel, sentChatID string
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		sentChannel = channel
+		sentChatID = chatID
+		return nil
+	})
+
+	turn := NewTurnContext("turn-channel", "turn-chat", "session-1")
+	ctx := WithTurnContext(context.Background(), turn)
+
+	result := tool.Execute(ctx, map[string]interface{}{"content": "hi"})
+	if result.IsError {
+		t.Fatalf("Expected success, got error: %s", result.ForLLM)
+	}
+	if sentChannel != "turn-channel" || sentChatID != "turn-chat" {
+		t.Errorf("Expected turn context target, got %s:%s", sentChannel, sentChatID)
+	}
+	if !turn.MessageSent() {
+		t.Error("Expected turn to record that a message was sent")
+	}
+	if tool.HasSentInRound() {
+		t.Error("Expected shared tool state to stay untouched when a turn context is present")
+	}
+}
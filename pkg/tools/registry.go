@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -34,13 +35,24 @@ func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	return tool, ok
 }
 
+// IsSequential reports whether the named tool opted out of parallel execution.
+func (r *ToolRegistry) IsSequential(name string) bool {
+	tool, ok := r.Get(name)
+	if !ok {
+		return false
+	}
+	st, ok := tool.(SequentialTool)
+	return ok && st.Sequential()
+}
+
 func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string]interface{}) *ToolResult {
 	return r.ExecuteWithContext(ctx, name, args, "", "", "", nil, nil)
 }
 
-// ExecuteWithContext executes a tool with channel/chatID/sessionKey context and optional async callback.
-// If the tool implements AsyncTool and a non-nil callback is provided,
-// the callback will be set on the tool before execution.
+// ExecuteWithContext executes a tool with channel/chatID/sessionKey context and optional callbacks.
+// If ctx does not already carry a TurnContext, one is created from channel/chatID/sessionKey.
+// The async and progress callbacks are attached to ctx for AsyncTool and ProgressTool
+// implementations to pick up; the tool instance itself is never mutated.
 func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args map[string]interface{}, channel, chatID, sessionKey string, asyncCallback AsyncCallback, progressCallback ProgressCallback) *ToolResult {
 	logger.InfoCF("tool", "Tool execution started",
 		map[string]interface{}{
@@ -57,25 +69,12 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
-	// If tool implements ContextualTool, set context
-	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
-		contextualTool.SetContext(channel, chatID, sessionKey)
-	}
-
-	// If tool implements AsyncTool and callback is provided, set callback
-	if asyncTool, ok := tool.(AsyncTool); ok && asyncCallback != nil {
-		asyncTool.SetCallback(asyncCallback)
-		logger.DebugCF("tool", "Async callback injected",
-			map[string]interface{}{
-				"tool": name,
-			})
-	}
-
-	// If tool implements ProgressTool and callback is provided, set callback
-	if progTool, ok := tool.(ProgressTool); ok && progressCallback != nil {
-		progTool.SetProgressCallback(progressCallback)
-		logger.DebugCF("tool", "Progress callback injected", map[string]interface{}{"tool": name})
+	// Per-call state travels through ctx rather than being set on the shared
+	// tool instance, so the same tool can serve concurrent sessions.
+	if _, ok := TurnContextFrom(ctx); !ok && channel != "" && chatID != "" {
+		ctx = WithTurnContext(ctx, NewTurnContext(channel, chatID, sessionKey))
 	}
+	ctx = withCallbacks(ctx, asyncCallback, progressCallback)
 
 	start := time.Now()
 	result := tool.Execute(ctx, args)
@@ -118,8 +117,8 @@ func (r *ToolRegistry) GetDefinitions() []map[string]interface{} {
 	return definitions
 }
 
-// ToProviderDefs converts tool definitions to provider-compatible format.
-// This is the format expected by LLM provider APIs.
+// ToProviderDefs converts tool definitions to provider-compatible format,
+// sorted by name. This is the format expected by LLM provider APIs.
 func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -147,6 +146,11 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 			},
 		})
 	}
+	// Keep the request identical from call to call, for prompt caches and
+	// recorded cassettes
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Function.Name < definitions[j].Function.Name
+	})
 	return definitions
 }
 
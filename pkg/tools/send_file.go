@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SendFileCallback delivers a file attachment to a chat.
+type SendFileCallback func(channel, chatID, caption string, media []string) error
+
+// SendFileTool sends a workspace file to the user as an attachment.
+type SendFileTool struct {
+	workspace      string
+	restrict       bool
+	sendCallback   SendFileCallback
+	defaultChannel string
+	defaultChatID  string
+}
+
+func NewSendFileTool(workspace string, restrict bool) *SendFileTool {
+	return &SendFileTool{workspace: workspace, restrict: restrict}
+}
+
+func (t *SendFileTool) Name() string {
+	return "send_file"
+}
+
+// Sequential keeps attachments in the order the model sent them.
+func (t *SendFileTool) Sequential() bool {
+	return true
+}
+
+func (t *SendFileTool) Description() string {
+	return "Send a file from the workspace to the user as an attachment (images are shown inline where the channel supports it)."
+}
+
+func (t *SendFileTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file to send",
+			},
+			"caption": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: text to send along with the file",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *SendFileTool) SetContext(channel, chatID, sessionKey string) {
+	t.defaultChannel = channel
+	t.defaultChatID = chatID
+}
+
+func (t *SendFileTool) SetSendCallback(callback SendFileCallback) {
+	t.sendCallback = callback
+}
+
+func (t *SendFileTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+	caption, _ := args["caption"].(string)
+
+	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	info, err := os.Stat(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to access file: %v", err))
+	}
+	if info.IsDir() {
+		return ErrorResult(fmt.Sprintf("%s is a directory", path))
+	}
+
+	channel, chatID, _ := originFrom(ctx, t.defaultChannel, t.defaultChatID, "")
+	if channel == "" || chatID == "" {
+		return ErrorResult("No target channel/chat specified")
+	}
+
+	if t.sendCallback == nil {
+		return ErrorResult("File sending not configured")
+	}
+
+	if err := t.sendCallback(channel, chatID, caption, []string{resolvedPath}); err != nil {
+		return ErrorResult(fmt.Sprintf("sending file: %v", err)).WithError(err)
+	}
+
+	return SilentResult(fmt.Sprintf("File %s sent to %s:%s", filepath.Base(resolvedPath), channel, chatID))
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSendFileTool_SendsWorkspaceFile(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "report.pdf"), []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewSendFileTool(workspace, true)
+	tool.SetContext("telegram", "42", "")
+
+	var gotChannel, gotChatID, gotCaption string
+	var gotMedia []string
+	tool.SetSendCallback(func(channel, chatID, caption string, media []string) error {
+		gotChannel, gotChatID, gotCaption, gotMedia = channel, chatID, caption, media
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":    "report.pdf",
+		"caption": "here you go",
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if !result.Silent {
+		t.Error("expected silent result")
+	}
+	if gotChannel != "telegram" || gotChatID != "42" || gotCaption != "here you go" {
+		t.Errorf("unexpected target %s:%s caption %q", gotChannel, gotChatID, gotCaption)
+	}
+	if len(gotMedia) != 1 || gotMedia[0] != filepath.Join(workspace, "report.pdf") {
+		t.Errorf("unexpected media %v", gotMedia)
+	}
+}
+
+func TestSendFileTool_RejectsOutsideWorkspace(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewSendFileTool(t.TempDir(), true)
+	tool.SetContext("telegram", "42", "")
+	called := false
+	tool.SetSendCallback(func(channel, chatID, caption string, media []string) error {
+		called = true
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": outside})
+	if !result.IsError {
+		t.Fatal("expected error for path outside workspace")
+	}
+	if called {
+		t.Error("callback must not be called for rejected paths")
+	}
+}
+
+func TestSendFileTool_MissingFile(t *testing.T) {
+	tool := NewSendFileTool(t.TempDir(), true)
+	tool.SetContext("telegram", "42", "")
+	tool.SetSendCallback(func(channel, chatID, caption string, media []string) error { return nil })
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "missing.txt"})
+	if !result.IsError {
+		t.Fatal("expected error for missing file")
+	}
+}
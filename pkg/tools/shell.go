@@ -121,6 +121,11 @@ func (t *ExecTool) Name() string {
 	return "exec"
 }
 
+// Sequential runs commands one at a time since they may depend on each other.
+func (t *ExecTool) Sequential() bool {
+	return true
+}
+
 func (t *ExecTool) Description() string {
 	return "Execute a shell command and return its output. Use with caution."
 }
@@ -197,6 +202,8 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		return ErrorResult(fmt.Sprintf("failed to start command: %v", err))
 	}
 
+	progressCb := progressCallbackFrom(ctx, t.progressCb)
+
 	var stdoutBuf, stderrBuf bytes.Buffer
 	var recentLogs []string
 	var logsMu sync.Mutex
@@ -209,7 +216,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 			if len(lineBytes) > 0 {
 				buf.Write(lineBytes)
 
-				if t.progressCb != nil {
+				if progressCb != nil {
 					lineStr := string(bytes.TrimRight(lineBytes, "\r\n"))
 					logsMu.Lock()
 					recentLogs = append(recentLogs, lineStr)
@@ -218,7 +225,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 					}
 					logStr := strings.Join(recentLogs, "\n")
 					logsMu.Unlock()
-					t.progressCb(logStr)
+					progressCb(logStr)
 				}
 			}
 			if err != nil {
@@ -38,6 +38,11 @@ func (t *InstallSkillTool) Name() string {
 	return "install_skill"
 }
 
+// Sequential avoids concurrent installs into the skills directory.
+func (t *InstallSkillTool) Sequential() bool {
+	return true
+}
+
 func (t *InstallSkillTool) Description() string {
 	return "Install a skill from a registry by slug. Downloads and extracts the skill into the workspace. Use find_skills first to discover available skills."
 }
@@ -85,7 +85,8 @@ func (t *SpawnTool) Execute(ctx context.Context, args map[string]interface{}) *T
 	}
 
 	// Pass callback to manager for async completion notification
-	result, err := t.manager.Spawn(ctx, task, label, agentID, t.originChannel, t.originChatID, t.callback)
+	originChannel, originChatID, _ := originFrom(ctx, t.originChannel, t.originChatID, "")
+	result, err := t.manager.Spawn(ctx, task, label, agentID, originChannel, originChatID, asyncCallbackFrom(ctx, t.callback))
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to spawn subagent: %v", err))
 	}
@@ -20,6 +20,11 @@ func (t *SPITool) Name() string {
 	return "spi"
 }
 
+// Sequential serializes access to the bus.
+func (t *SPITool) Sequential() bool {
+	return true
+}
+
 func (t *SPITool) Description() string {
 	return "Interact with SPI bus devices for high-speed peripheral communication. Actions: list (find SPI devices), transfer (full-duplex send/receive), read (receive bytes). Linux only."
 }
@@ -17,9 +17,12 @@ type SubagentTask struct {
 	AgentID       string
 	OriginChannel string
 	OriginChatID  string
+	SessionKey    string
 	Status        string
 	Result        string
 	Created       int64
+
+	cancel context.CancelFunc
 }
 
 type SubagentManager struct {
@@ -83,6 +86,14 @@ func (sm *SubagentManager) Spawn(ctx context.Context, task, label, agentID, orig
 	taskID := fmt.Sprintf("subagent-%d", sm.nextID)
 	sm.nextID++
 
+	// The task outlives the turn that spawned it, so detach it from the turn's
+	// cancellation and give it its own cancel func (see CancelSession).
+	sessionKey := ""
+	if tc, ok := TurnContextFrom(ctx); ok {
+		sessionKey = tc.SessionKey
+	}
+	taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
 	subagentTask := &SubagentTask{
 		ID:            taskID,
 		Task:          task,
@@ -90,13 +101,15 @@ func (sm *SubagentManager) Spawn(ctx context.Context, task, label, agentID, orig
 		AgentID:       agentID,
 		OriginChannel: originChannel,
 		OriginChatID:  originChatID,
+		SessionKey:    sessionKey,
 		Status:        "running",
 		Created:       time.Now().UnixMilli(),
+		cancel:        cancel,
 	}
 	sm.tasks[taskID] = subagentTask
 
 	// Start task in background with context cancellation support
-	go sm.runTask(ctx, subagentTask, callback)
+	go sm.runTask(taskCtx, subagentTask, callback)
 
 	if label != "" {
 		return fmt.Sprintf("Spawned subagent '%s' for task: %s", label, task), nil
@@ -105,8 +118,11 @@ func (sm *SubagentManager) Spawn(ctx context.Context, task, label, agentID, orig
 }
 
 func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, callback AsyncCallback) {
-	task.Status = "running"
-	task.Created = time.Now().UnixMilli()
+	if task.cancel != nil {
+		defer task.cancel()
+	}
+	// Spawn already marked the task running, under sm.mu where CancelSession
+	// reads it.
 
 	// Build system prompt for subagent
 	systemPrompt := `You are a subagent. Complete the given task independently and report the result.
@@ -202,8 +218,8 @@ After completing the task, provide a clear summary of what was done.`
 		}
 	}
 
-	// Send announce message back to main agent
-	if sm.bus != nil {
+	// Send announce message back to main agent (cancelled tasks stay quiet)
+	if sm.bus != nil && task.Status != "cancelled" {
 		announceContent := fmt.Sprintf("Task '%s' completed.\n\nResult:\n%s", task.Label, task.Result)
 		sm.bus.PublishInbound(bus.InboundMessage{
 			Channel:  "system",
@@ -215,6 +231,23 @@ After completing the task, provide a clear summary of what was done.`
 	}
 }
 
+// CancelSession cancels all running subagent tasks spawned from the given session.
+// It returns the number of tasks that were cancelled.
+func (sm *SubagentManager) CancelSession(sessionKey string) int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	cancelled := 0
+	for _, task := range sm.tasks {
+		if task.SessionKey != sessionKey || task.Status != "running" || task.cancel == nil {
+			continue
+		}
+		task.cancel()
+		cancelled++
+	}
+	return cancelled
+}
+
 func (sm *SubagentManager) GetTask(taskID string) (*SubagentTask, bool) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
@@ -304,6 +337,8 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 		},
 	}
 
+	originChannel, originChatID, _ := originFrom(ctx, t.originChannel, t.originChatID, "")
+
 	// Use RunToolLoop to execute with tools (same as async SpawnTool)
 	sm := t.manager
 	sm.mu.RLock()
@@ -332,7 +367,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 		Tools:         tools,
 		MaxIterations: maxIter,
 		LLMOptions:    llmOptions,
-	}, messages, t.originChannel, t.originChatID, "")
+	}, messages, originChannel, originChatID, "")
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
 	}
@@ -0,0 +1,321 @@
+// Package usage records LLM token usage and cost per agent, session, model
+// and day, persisted in the agent workspace.
+package usage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dayFormat = "2006-01-02"
+
+// Record is the accumulated usage of one model in one session on one day.
+type Record struct {
+	Day              string  `json:"day"` // YYYY-MM-DD, local time; YYYY-MM before the current month
+	AgentID          string  `json:"agent_id"`
+	SessionKey       string  `json:"session_key"`
+	Model            string  `json:"model"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost,omitempty"` // USD, from the model's configured prices
+}
+
+func (r *Record) key() string {
+	return strings.Join([]string{r.Day, r.AgentID, r.SessionKey, r.Model}, "\x00")
+}
+
+// merge adds the usage of other to r.
+func (r *Record) merge(other *Record) {
+	r.Requests += other.Requests
+	r.PromptTokens += other.PromptTokens
+	r.CompletionTokens += other.CompletionTokens
+	r.TotalTokens += other.TotalTokens
+	r.Cost += other.Cost
+}
+
+// Totals sums a set of records.
+type Totals struct {
+	Requests         int
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	Cost             float64
+}
+
+func (t *Totals) add(r *Record) {
+	t.Requests += r.Requests
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.TotalTokens += r.TotalTokens
+	t.Cost += r.Cost
+}
+
+// Filter selects records. Empty fields match everything.
+type Filter struct {
+	AgentID    string
+	SessionKey string
+	Period     string // day ("2006-01-02") or month ("2006-01") prefix
+}
+
+func (f Filter) matches(r *Record) bool {
+	return (f.AgentID == "" || r.AgentID == f.AgentID) &&
+		(f.SessionKey == "" || r.SessionKey == f.SessionKey) &&
+		strings.HasPrefix(r.Day, f.Period)
+}
+
+// Today returns the period string for the current day.
+func Today(now time.Time) string {
+	return now.Format(dayFormat)
+}
+
+// ThisMonth returns the period string for the current month.
+func ThisMonth(now time.Time) string {
+	return now.Format("2006-01")
+}
+
+// Store accumulates usage records. Each LLM call is appended to
+// {workspace}/state/usage.jsonl, which is compacted into one line per record
+// every compactAfter calls and when the store is opened.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	records map[string]*Record
+	now     func() time.Time
+
+	// pending counts the lines appended since the log was last compacted.
+	pending int
+}
+
+// compactAfter is the number of appended calls after which the log is
+// rewritten with one line per record.
+const compactAfter = 1000
+
+// NewStore opens the usage store of a workspace.
+func NewStore(workspace string) *Store {
+	s := &Store{
+		path:    filepath.Join(workspace, "state", "usage.jsonl"),
+		records: make(map[string]*Record),
+		now:     time.Now,
+	}
+	s.load()
+	return s
+}
+
+// Add records the usage of a single LLM call.
+func (s *Store) Add(agentID, sessionKey, model string, promptTokens, completionTokens, totalTokens int, cost float64) error {
+	if totalTokens == 0 {
+		totalTokens = promptTokens + completionTokens
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call := Record{
+		Day:              Today(s.now()),
+		AgentID:          agentID,
+		SessionKey:       sessionKey,
+		Model:            model,
+		Requests:         1,
+		PromptTokens:     int64(promptTokens),
+		CompletionTokens: int64(completionTokens),
+		TotalTokens:      int64(totalTokens),
+		Cost:             cost,
+	}
+	total := call
+	s.addRecord(&total)
+
+	if s.pending >= compactAfter {
+		return s.compact()
+	}
+	return s.appendRecord(&call)
+}
+
+// Totals sums all records matching f.
+func (s *Store) Totals(f Filter) Totals {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var t Totals
+	for _, r := range s.records {
+		if f.matches(r) {
+			t.add(r)
+		}
+	}
+	return t
+}
+
+// ByModel sums the records matching f per model.
+func (s *Store) ByModel(f Filter) map[string]Totals {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]Totals)
+	for _, r := range s.records {
+		if f.matches(r) {
+			t := out[r.Model]
+			t.add(r)
+			out[r.Model] = t
+		}
+	}
+	return out
+}
+
+// Models returns the keys of a ByModel result in sorted order.
+func Models(byModel map[string]Totals) []string {
+	models := make([]string, 0, len(byModel))
+	for m := range byModel {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// Now returns the store's current time, used to derive day and month periods.
+func (s *Store) Now() time.Time {
+	return s.now()
+}
+
+// appendRecord appends one call to the log. Must be called with the lock held.
+func (s *Store) appendRecord(r *Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append usage: %w", err)
+	}
+	s.pending++
+	return nil
+}
+
+// compact rolls the days of past months up into one record per month and
+// rewrites the log with one line per record, using temp file + rename. Must
+// be called with the lock held.
+func (s *Store) compact() error {
+	thisMonth := ThisMonth(s.now())
+	for key, r := range s.records {
+		if len(r.Day) != len(dayFormat) || strings.HasPrefix(r.Day, thisMonth) {
+			continue
+		}
+		delete(s.records, key)
+		r.Day = r.Day[:len(thisMonth)]
+		s.addRecord(r)
+	}
+
+	records := make([]*Record, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.SessionKey != b.SessionKey {
+			return a.SessionKey < b.SessionKey
+		}
+		return a.Model < b.Model
+	})
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to marshal usage: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tempFile := s.path + ".tmp"
+	f, err := os.OpenFile(tempFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, s.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	s.pending = 0
+	return nil
+}
+
+// load reads the log, compacting it when it holds more lines than records.
+func (s *Store) load() {
+	legacyPath := filepath.Join(filepath.Dir(s.path), "usage.json")
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		s.loadLegacy(legacyPath)
+		return
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var r Record
+		// A crash may leave the last line incomplete
+		if len(line) == 0 || json.Unmarshal(line, &r) != nil {
+			continue
+		}
+		s.addRecord(&r)
+		s.pending++
+	}
+	if s.pending > len(s.records) {
+		s.compact()
+	}
+	s.pending = 0
+	os.Remove(legacyPath)
+}
+
+// loadLegacy imports the usage.json of earlier versions into the log.
+func (s *Store) loadLegacy(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var records []*Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return
+	}
+	for _, r := range records {
+		s.addRecord(r)
+	}
+	if err := s.compact(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// addRecord merges r into the records with the same key.
+func (s *Store) addRecord(r *Record) {
+	if existing, ok := s.records[r.key()]; ok {
+		existing.merge(r)
+	} else {
+		s.records[r.key()] = r
+	}
+}
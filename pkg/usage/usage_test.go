@@ -0,0 +1,114 @@
+package usage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_AccumulatesByPeriod(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+
+	s.now = func() time.Time { return time.Date(2026, 3, 31, 12, 0, 0, 0, time.Local) }
+	if err := s.Add("main", "s1", "gpt", 10, 5, 0, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Date(2026, 4, 1, 12, 0, 0, 0, time.Local) }
+	s.Add("main", "s1", "gpt", 10, 5, 15, 0.5)
+	s.Add("main", "s2", "claude", 100, 50, 150, 0)
+
+	if got := s.Totals(Filter{}); got.TotalTokens != 180 || got.Requests != 3 {
+		t.Errorf("all totals = %+v", got)
+	}
+	if got := s.Totals(Filter{Period: "2026-04"}); got.TotalTokens != 165 {
+		t.Errorf("april totals = %+v", got)
+	}
+	if got := s.Totals(Filter{SessionKey: "s1", Period: "2026-04-01"}); got.TotalTokens != 15 || got.Cost != 0.5 {
+		t.Errorf("session day totals = %+v", got)
+	}
+
+	byModel := s.ByModel(Filter{AgentID: "main"})
+	if models := Models(byModel); len(models) != 2 || models[0] != "claude" || models[1] != "gpt" {
+		t.Errorf("models = %v", models)
+	}
+	if byModel["gpt"].TotalTokens != 30 {
+		t.Errorf("gpt totals = %+v", byModel["gpt"])
+	}
+
+	reloaded := NewStore(workspace)
+	if got := reloaded.Totals(Filter{}); got.TotalTokens != 180 {
+		t.Errorf("reloaded totals = %+v", got)
+	}
+}
+
+func TestStore_AppendsCallsAndCompacts(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	s.now = func() time.Time { return time.Date(2026, 3, 31, 12, 0, 0, 0, time.Local) }
+	s.Add("main", "s1", "gpt", 10, 5, 15, 0)
+	s.now = func() time.Time { return time.Date(2026, 4, 1, 12, 0, 0, 0, time.Local) }
+	s.Add("main", "s1", "gpt", 10, 5, 15, 0)
+	s.Add("main", "s1", "gpt", 10, 5, 15, 0)
+
+	path := filepath.Join(workspace, "state", "usage.jsonl")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("usage log mode = %o, want 0600", perm)
+	}
+	if lines := countLines(t, path); lines != 3 {
+		t.Errorf("usage log has %d lines, want one per call", lines)
+	}
+
+	// Opening the store in May rolls March and April up into months
+	reloaded := &Store{
+		path:    path,
+		records: make(map[string]*Record),
+		now:     func() time.Time { return time.Date(2026, 5, 2, 12, 0, 0, 0, time.Local) },
+	}
+	reloaded.load()
+	if lines := countLines(t, path); lines != 2 {
+		t.Errorf("compacted usage log has %d lines, want one per month", lines)
+	}
+	if got := reloaded.Totals(Filter{Period: "2026-04"}); got.TotalTokens != 30 || got.Requests != 2 {
+		t.Errorf("april totals = %+v", got)
+	}
+	if got := reloaded.Totals(Filter{}); got.TotalTokens != 45 {
+		t.Errorf("all totals = %+v", got)
+	}
+}
+
+func TestStore_ImportsLegacyFile(t *testing.T) {
+	workspace := t.TempDir()
+	legacy := filepath.Join(workspace, "state", "usage.json")
+	os.MkdirAll(filepath.Dir(legacy), 0755)
+	data := `[{"day":"2026-04-01","agent_id":"main","session_key":"s1","model":"gpt","requests":2,"prompt_tokens":20,"completion_tokens":10,"total_tokens":30}]`
+	if err := os.WriteFile(legacy, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewStore(workspace)
+	if got := s.Totals(Filter{}); got.TotalTokens != 30 || got.Requests != 2 {
+		t.Errorf("imported totals = %+v", got)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy file still exists: %v", err)
+	}
+	if got := NewStore(workspace).Totals(Filter{}); got.TotalTokens != 30 {
+		t.Errorf("reloaded totals = %+v", got)
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
@@ -46,6 +46,33 @@ func SanitizeFilename(filename string) string {
 	return base
 }
 
+// MediaDir returns the temp directory that downloaded media is stored in.
+func MediaDir() string {
+	return filepath.Join(os.TempDir(), "mobaiclaw_media")
+}
+
+// RemoveMedia deletes downloaded media files, and the subdirectories of
+// MediaDir they leave empty. Paths outside MediaDir are left untouched so a
+// message can never cause arbitrary files to be removed.
+func RemoveMedia(paths []string) {
+	dir := MediaDir() + string(filepath.Separator)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if !strings.HasPrefix(path, dir) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.DebugCF("media", "Failed to remove media file", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+		}
+		if parent := filepath.Dir(path); parent+string(filepath.Separator) != dir {
+			os.Remove(parent) // fails while the directory still holds files
+		}
+	}
+}
+
 // DownloadOptions holds optional parameters for downloading files
 type DownloadOptions struct {
 	Timeout      time.Duration
@@ -64,7 +91,7 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 		opts.LoggerPrefix = "utils"
 	}
 
-	mediaDir := filepath.Join(os.TempDir(), "mobaiclaw_media")
+	mediaDir := MediaDir()
 	if err := os.MkdirAll(mediaDir, 0700); err != nil {
 		logger.ErrorCF(opts.LoggerPrefix, "Failed to create media directory", map[string]interface{}{
 			"error": err.Error(),
@@ -1,5 +1,7 @@
 package utils
 
+import "fmt"
+
 // Truncate returns a truncated version of s with at most maxLen runes.
 // Handles multi-byte Unicode characters properly.
 // If the string is truncated, "..." is appended to indicate truncation.
@@ -23,3 +25,14 @@ func DerefStr(s *string, fallback string) string {
 	}
 	return *s
 }
+
+// FormatBytes formats a byte count for display, e.g. "512 B" or "1.5 KB".
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}